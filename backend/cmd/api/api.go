@@ -2,19 +2,26 @@ package api
 
 import (
 	"context"
+	"crypto/sha256"
 	"errors"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
 	"typinggame-api/config"
 	"typinggame-api/internal/driver"
+	"typinggame-api/internal/filter"
 	"typinggame-api/internal/handler"
 	"typinggame-api/internal/models"
 	"typinggame-api/internal/repository"
+	"typinggame-api/internal/search"
 	"typinggame-api/internal/service"
+	"typinggame-api/internal/storage"
+	"typinggame-api/internal/worker"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -46,41 +53,163 @@ func StartServer() {
 	gameScoreRepo := repository.NewGameScoreRepository(db)
 	friendRepo := repository.NewFriendRepository(db)
 	messageRepo := repository.NewMessageRepository(db)
-	authService := service.NewAuthService(userRepo)
-	postService := service.NewPostService(postRepo, userRepo, historyRepo)
-	commentService := service.NewCommentService(commentRepo, postRepo, userRepo, historyRepo)
-	gameService := service.NewGameService(gameScoreRepo)
+	passageRepo := repository.NewPassageRepository(db)
+	entityRepo := repository.NewEntityRepository(db)
+	bookmarkRepo := repository.NewBookmarkRepository(db)
+	pollRepo := repository.NewPollRepository(db)
+	reportRepo := repository.NewReportRepository(db)
+	reactionRepo := repository.NewReactionRepository(db)
+	imported, err := reactionRepo.ImportPostReactions()
+	if err != nil {
+		logger.Fatal("Failed to import post reactions", zap.Error(err))
+	}
+	if imported > 0 {
+		logger.Info("Imported post reactions", zap.Int64("reactions", imported))
+	}
+	duplicates, err := postRepo.EnsurePlainRepostIndex()
+	if err != nil {
+		logger.Fatal("Failed to add the plain repost index", zap.Error(err))
+	}
+	if duplicates > 0 {
+		logger.Info("Deleted duplicate plain reposts", zap.Int64("posts", duplicates))
+	}
+	store, err := newStorage(config.Get())
+	if err != nil {
+		logger.Fatal("Failed to initialize file storage", zap.Error(err))
+	}
+	uploadConfig := config.Get().Upload
+	attachmentService := service.NewAttachmentService(store, service.AttachmentLimits{
+		MaxBytes:      uploadConfig.MaxImageBytes,
+		MaxPerPost:    uploadConfig.MaxAttachments,
+		ThumbnailSize: uploadConfig.ThumbnailSize,
+	}, logger)
+	contentPolicy := service.NewContentPolicy(friendRepo)
+	searchService := service.NewSearchService(newSearchIndex(config.Get(), db), postRepo, commentRepo, userRepo, contentPolicy)
+	if config.Get().Search.Backend == "memory" {
+		logger.Info("Building in-memory search index...")
+		count, err := searchService.Reindex()
+		if err != nil {
+			logger.Fatal("Failed to build search index", zap.Error(err))
+		}
+		logger.Info("Search index built", zap.Int("documents", count))
+	}
+	contentFilter, err := newContentFilter(config.Get())
+	if err != nil {
+		logger.Fatal("Failed to load content filter", zap.Error(err))
+	}
+	reviewQueue := service.NewReviewQueue(reportRepo)
+	reactionRegistry := service.NewReactionRegistry(config.Get().Reactions.Names, config.Get().Reactions.AllowEmoji)
+	reactionService := service.NewReactionService(reactionRepo, contentPolicy, reactionRegistry)
+	authService := service.NewAuthService(userRepo, searchService, logger)
+	entityService := service.NewEntityService(entityRepo, userRepo, postRepo, contentPolicy)
+	postService := service.NewPostService(postRepo, userRepo, historyRepo, contentPolicy, attachmentService, entityService, searchService, contentFilter, reviewQueue, reactionService, logger)
+	commentService := service.NewCommentService(commentRepo, postRepo, userRepo, historyRepo, contentPolicy, entityService, searchService, contentFilter, reviewQueue, reactionService, logger)
+	bookmarkService := service.NewBookmarkService(bookmarkRepo, postRepo, contentPolicy)
+	pollService := service.NewPollService(pollRepo, postRepo, contentPolicy)
+	trashService := service.NewTrashService(postRepo, commentRepo, attachmentService, searchService, config.Get().Trash.Retention, logger)
+	moderationService := service.NewModerationService(reportRepo, userRepo, postRepo, commentRepo, messageRepo, postService, commentService, contentPolicy)
+	leaderboardHub := service.NewLeaderboardHub(gameScoreRepo, friendRepo)
+	gameService := service.NewGameService(gameScoreRepo, userRepo, friendRepo, leaderboardHub)
+	passageService := service.NewPassageService(passageRepo, gameScoreRepo, userRepo, leaderboardHub)
+	certificateKey, generatedKey, err := service.LoadCertificateKey(config.Get().Certificate.SigningKey)
+	if err != nil {
+		logger.Fatal("Failed to load certificate signing key", zap.Error(err))
+	}
+	if generatedKey {
+		logger.Warn("CERT_SIGNING_KEY is not set, using a temporary key; certificates will not verify after restart")
+	}
+	certificateService := service.NewCertificateService(gameScoreRepo, certificateKey)
 	friendService := service.NewFriendService(friendRepo)
-	messageService := service.NewMessageService(messageRepo, friendRepo)
+	messageService := service.NewMessageService(messageRepo, friendRepo, contentFilter, reviewQueue, reactionService)
 	authHandler := handler.NewAuthHandler(authService)
-	userHandler := handler.NewUserHandler(userRepo)
-	postHandler := handler.NewPostHandler(postService)
+	userHandler := handler.NewUserHandler(userRepo, contentPolicy, searchService, logger)
+	postHandler := handler.NewPostHandler(postService, attachmentService, bookmarkService, pollService)
 	commentHandler := handler.NewCommentHandler(commentService)
-	gameHandler := handler.NewGameHandler(gameService)
+	gameHandler := handler.NewGameHandler(gameService, leaderboardHub)
 	friendHandler := handler.NewFriendHandler(friendService)
 	messageHandler := handler.NewMessageHandler(messageService)
+	certificateHandler := handler.NewCertificateHandler(certificateService)
+	passageHandler := handler.NewPassageHandler(passageService)
+	entityHandler := handler.NewEntityHandler(entityService)
+	bookmarkHandler := handler.NewBookmarkHandler(bookmarkService, postService, attachmentService, pollService)
+	searchHandler := handler.NewSearchHandler(searchService, postService, attachmentService, bookmarkService, pollService)
+	pollHandler := handler.NewPollHandler(pollService)
+	moderationHandler := handler.NewModerationHandler(moderationService)
+	trashHandler := handler.NewTrashHandler(trashService)
+	reactionHandler := handler.NewReactionHandler(reactionService)
 
 	handlers := &handler.Handlers{
-		AuthHandler:    authHandler,
-		UserHandler:    userHandler,
-		PostHandler:    postHandler,
-		CommentHandler: commentHandler,
-		GameHandler:    gameHandler,
-		FriendHandler:  friendHandler,
-		MessageHandler: messageHandler,
+		AuthHandler:        authHandler,
+		UserHandler:        userHandler,
+		PostHandler:        postHandler,
+		CommentHandler:     commentHandler,
+		GameHandler:        gameHandler,
+		FriendHandler:      friendHandler,
+		MessageHandler:     messageHandler,
+		CertificateHandler: certificateHandler,
+		PassageHandler:     passageHandler,
+		EntityHandler:      entityHandler,
+		SearchHandler:      searchHandler,
+		BookmarkHandler:    bookmarkHandler,
+		PollHandler:        pollHandler,
+		ModerationHandler:  moderationHandler,
+		TrashHandler:       trashHandler,
+		ReactionHandler:    reactionHandler,
 	}
 
 	e := echo.New()
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
+	e.Use(middleware.BodyLimit(bodyLimit(config.Get())))
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins:     []string{"http://localhost:3000", "http://127.0.0.1:3000"},
 		AllowMethods:     []string{echo.GET, echo.HEAD, echo.PUT, echo.PATCH, echo.POST, echo.DELETE},
-		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
+		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "If-Match", "If-None-Match"},
+		ExposeHeaders:    []string{"ETag"},
 		AllowCredentials: true,
 	}))
 
 	handler.InitializeRoutes(e, handlers)
+	if config.Get().Storage.Driver == "local" {
+		mediaHandler := handler.NewMediaHandler(config.Get().Storage.LocalDir, mediaSigner(config.Get()))
+		e.GET("/media/*", mediaHandler.ServeFile)
+	}
+
+	// Background workers share one context that is cancelled on shutdown
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	var bgWorkers sync.WaitGroup
+	startWorker := func(run func()) {
+		bgWorkers.Add(1)
+		go func() {
+			defer bgWorkers.Done()
+			run()
+		}()
+	}
+	startWorker(func() { leaderboardHub.Run(bgCtx) })
+	startWorker(func() {
+		worker.Every(bgCtx, config.Get().Scheduler.PublishInterval, logger, "publish-scheduled-posts", func() error {
+			_, err := postService.PublishDue(time.Now())
+			return err
+		})
+	})
+	startWorker(func() {
+		worker.Every(bgCtx, config.Get().Scheduler.PollCloseInterval, logger, "close-polls", func() error {
+			_, err := pollService.CloseDue(time.Now())
+			return err
+		})
+	})
+	startWorker(func() {
+		worker.Every(bgCtx, config.Get().Scheduler.HotScoreInterval, logger, "refresh-hot-scores", func() error {
+			_, err := postService.RefreshHotScores(time.Now())
+			return err
+		})
+	})
+	startWorker(func() {
+		worker.Every(bgCtx, config.Get().Scheduler.TrashPurgeInterval, logger, "purge-trash", func() error {
+			_, err := trashService.Purge(time.Now())
+			return err
+		})
+	})
 
 	port := ":" + config.Get().Server.Port
 	hs := &http.Server{
@@ -103,6 +232,11 @@ func StartServer() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
+	// Stop workers first: this also ends open leaderboard streams, which would
+	// otherwise hold Shutdown until its timeout
+	stopBackground()
+	bgWorkers.Wait()
+
 	shutdownCtx, shutdownRelease := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownRelease()
 
@@ -114,11 +248,86 @@ func StartServer() {
 	logger.Info("Graceful shutdown complete.")
 }
 
+// newStorage picks the file storage backend named by STORAGE_DRIVER.
+func newStorage(cfg config.Config) (storage.Storage, error) {
+	if cfg.Storage.Driver == "s3" {
+		return storage.NewS3Storage(storage.S3Config{
+			Endpoint:  cfg.Storage.S3Endpoint,
+			Region:    cfg.Storage.S3Region,
+			Bucket:    cfg.Storage.S3Bucket,
+			AccessKey: cfg.Storage.S3AccessKey,
+			SecretKey: cfg.Storage.S3SecretKey,
+			URLTTL:    cfg.Storage.URLTTL,
+		})
+	}
+
+	publicURL := cfg.Storage.PublicURL
+	if publicURL == "" {
+		publicURL = "http://localhost:" + cfg.Server.Port + "/media"
+	}
+	return storage.NewLocalStorage(cfg.Storage.LocalDir, publicURL, mediaSigner(cfg))
+}
+
+// mediaSigner signs links to locally stored files with a key derived from
+// the JWT secret, so links stay valid across restarts and servers.
+func mediaSigner(cfg config.Config) *storage.URLSigner {
+	secret := sha256.Sum256([]byte("media:" + cfg.JWT.Secret))
+	return storage.NewURLSigner(secret[:], cfg.Storage.URLTTL)
+}
+
+// bodyLimit fits a post with as many of the largest attachments as allowed,
+// plus room for the other form fields.
+func bodyLimit(cfg config.Config) string {
+	limit := int64(cfg.Upload.MaxAttachments)*cfg.Upload.MaxImageBytes + 1<<20
+	return strconv.FormatInt(limit, 10) + "B"
+}
+
+// newSearchIndex picks the search index named by SEARCH_BACKEND.
+func newSearchIndex(cfg config.Config, db *gorm.DB) search.Index {
+	if cfg.Search.Backend == "memory" {
+		return search.NewMemoryIndex()
+	}
+	return search.NewMySQLIndex(db)
+}
+
+// newContentFilter builds the filter chain every post, comment and message
+// goes through: length limits first, then the word blocklist, then the spam
+// heuristics.
+func newContentFilter(cfg config.Config) (filter.ContentFilter, error) {
+	var words []string
+	for _, language := range cfg.Filter.BlocklistLanguages {
+		list, err := filter.BuiltinWords(language)
+		if err != nil {
+			return nil, err
+		}
+		words = append(words, list...)
+	}
+	for _, path := range cfg.Filter.BlocklistFiles {
+		list, err := filter.LoadWords(path)
+		if err != nil {
+			return nil, err
+		}
+		words = append(words, list...)
+	}
+	action, _ := filter.ParseVerdict(cfg.Filter.BlocklistAction)
+
+	return filter.Chain{
+		filter.NewLengthFilter(map[filter.Kind]int{
+			filter.Post:    cfg.Filter.MaxPostLength,
+			filter.Comment: cfg.Filter.MaxCommentLength,
+			filter.Message: cfg.Filter.MaxMessageLength,
+		}),
+		filter.NewBlocklist(words, action),
+		filter.NewSpamFilter(cfg.Filter.MaxLinks, cfg.Filter.MaxRepeats),
+	}, nil
+}
+
 func autoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	err := db.AutoMigrate(
 		&models.User{},
 		&models.Post{},
-		&models.PostReaction{},
+		&models.PostAttachment{},
+		&models.Reaction{},
 		&models.Comment{},
 		&models.EditHistory{},
 		&models.GameScore{},
@@ -126,5 +335,25 @@ func autoMigrate(db *gorm.DB) error {
 		&models.BlockedUser{},
 		&models.Conversation{},
 		&models.Message{},
+		&models.Passage{},
+		&models.HashtagUse{},
+		&models.Mention{},
+		&models.SearchDocument{},
+		&models.Bookmark{},
+		&models.BookmarkCollection{},
+		&models.Poll{},
+		&models.PollOption{},
+		&models.PollVote{},
+		&models.Report{},
+		&models.ModerationAction{},
 	)
+	if err != nil {
+		return err
+	}
+	// Reactors used to be paged by updated_at; idx_reactions_target_created
+	// replaces this index
+	if db.Migrator().HasIndex(&models.Reaction{}, "idx_reactions_target_updated") {
+		return db.Migrator().DropIndex(&models.Reaction{}, "idx_reactions_target_updated")
+	}
+	return nil
 }
@@ -0,0 +1,63 @@
+// Command import-passages loads quotes and paragraphs for the passage typing
+// mode from a JSON or CSV file.
+//
+//	go run ./cmd/import-passages -file quotes.csv
+//
+// JSON files hold an array of {"text", "source", "language"} objects. CSV
+// files need a header row with a text column and optional source and
+// language columns. Passages that are already in the database are skipped.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"typinggame-api/config"
+	"typinggame-api/internal/driver"
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+	"typinggame-api/internal/service"
+)
+
+func main() {
+	file := flag.String("file", "", "path to a .json or .csv file")
+	format := flag.String("format", "", "json or csv (default: from the file extension)")
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatal("-file is required")
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*file), ".")
+	}
+
+	if err := config.LoadConfig(); err != nil {
+		log.Fatal(err)
+	}
+	db := driver.NewDatabase()
+	if err := db.AutoMigrate(&models.Passage{}); err != nil {
+		log.Fatalf("migrate passages: %v", err)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	passageService := service.NewPassageService(
+		repository.NewPassageRepository(db),
+		repository.NewGameScoreRepository(db),
+		repository.NewUserRepository(db),
+		nil,
+	)
+
+	result, err := passageService.Import(f, *format)
+	if err != nil {
+		log.Fatalf("import failed: %v", err)
+	}
+	log.Printf("Imported %d passages, skipped %d", result.Imported, result.Skipped)
+}
@@ -0,0 +1,42 @@
+// Command reindex-search rebuilds the MySQL search index from the posts,
+// comments and users tables. Run it once after upgrading to fill the index
+// for existing content, or any time the index looks out of date.
+//
+//	go run ./cmd/reindex-search
+package main
+
+import (
+	"log"
+
+	"typinggame-api/config"
+	"typinggame-api/internal/driver"
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+	"typinggame-api/internal/search"
+	"typinggame-api/internal/service"
+)
+
+func main() {
+	if err := config.LoadConfig(); err != nil {
+		log.Fatal(err)
+	}
+	db := driver.NewDatabase()
+	if err := db.AutoMigrate(&models.SearchDocument{}); err != nil {
+		log.Fatalf("migrate search documents: %v", err)
+	}
+
+	friendRepo := repository.NewFriendRepository(db)
+	searchService := service.NewSearchService(
+		search.NewMySQLIndex(db),
+		repository.NewPostRepository(db),
+		repository.NewCommentRepository(db),
+		repository.NewUserRepository(db),
+		service.NewContentPolicy(friendRepo),
+	)
+
+	count, err := searchService.Reindex()
+	if err != nil {
+		log.Fatalf("reindex failed after %d documents: %v", count, err)
+	}
+	log.Printf("Indexed %d documents", count)
+}
@@ -0,0 +1,49 @@
+// Command set-role changes a user's role. Use it to appoint the first admin,
+// who can then manage moderators through the API.
+//
+//	go run ./cmd/set-role -email admin@example.com -role admin
+package main
+
+import (
+	"flag"
+	"log"
+
+	"typinggame-api/config"
+	"typinggame-api/internal/driver"
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+)
+
+func main() {
+	email := flag.String("email", "", "email of the user")
+	role := flag.String("role", "", "user, moderator or admin")
+	flag.Parse()
+
+	if *email == "" {
+		log.Fatal("-email is required")
+	}
+	switch *role {
+	case models.UserRoleUser, models.UserRoleModerator, models.UserRoleAdmin:
+	default:
+		log.Fatal("-role must be user, moderator or admin")
+	}
+
+	if err := config.LoadConfig(); err != nil {
+		log.Fatal(err)
+	}
+	db := driver.NewDatabase()
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		log.Fatalf("migrate users: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	user, err := userRepo.FindByEmail(*email)
+	if err != nil {
+		log.Fatalf("find user %s: %v", *email, err)
+	}
+	user.Role = *role
+	if err := userRepo.Update(user); err != nil {
+		log.Fatalf("update user: %v", err)
+	}
+	log.Printf("%s is now %s", user.Email, user.Role)
+}
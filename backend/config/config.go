@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
@@ -26,10 +27,96 @@ type jwt struct {
 	Secret string `envconfig:"JWT_SECRET" default:"your-secret-key-change-in-production"`
 }
 
+type game struct {
+	MaxBackdate  time.Duration `envconfig:"GAME_MAX_BACKDATE" default:"72h"`
+	MaxBatchSize int           `envconfig:"GAME_MAX_BATCH_SIZE" default:"50"`
+}
+
+type certificate struct {
+	// Base64 encoded Ed25519 seed (32 bytes) or private key (64 bytes)
+	SigningKey string `envconfig:"CERT_SIGNING_KEY" default:""`
+}
+
+type storage struct {
+	Driver string `envconfig:"STORAGE_DRIVER" default:"local" validate:"oneof=local s3"`
+	// Base URL the API serves local files from; empty uses this server
+	PublicURL string `envconfig:"STORAGE_PUBLIC_URL" default:""`
+	LocalDir  string `envconfig:"STORAGE_LOCAL_DIR" default:"uploads"`
+	// Links to files work for at least this long, and at most twice as long
+	URLTTL time.Duration `envconfig:"STORAGE_URL_TTL" default:"15m" validate:"min=1m,max=72h"`
+	// S3 compatible services, including MinIO
+	S3Endpoint  string `envconfig:"STORAGE_S3_ENDPOINT" default:"http://localhost:9000"`
+	S3Region    string `envconfig:"STORAGE_S3_REGION" default:"us-east-1"`
+	S3Bucket    string `envconfig:"STORAGE_S3_BUCKET" default:"typinggame"`
+	S3AccessKey string `envconfig:"STORAGE_S3_ACCESS_KEY" default:""`
+	S3SecretKey string `envconfig:"STORAGE_S3_SECRET_KEY" default:""`
+}
+
+type upload struct {
+	MaxImageBytes  int64 `envconfig:"UPLOAD_MAX_IMAGE_BYTES" default:"10485760"`
+	MaxAttachments int   `envconfig:"UPLOAD_MAX_ATTACHMENTS" default:"4"`
+	ThumbnailSize  int   `envconfig:"UPLOAD_THUMBNAIL_SIZE" default:"320"`
+}
+
+type search struct {
+	// mysql keeps the index in a FULLTEXT table; memory rebuilds it in
+	// process on every start and is meant for tests and small setups
+	Backend string `envconfig:"SEARCH_BACKEND" default:"mysql" validate:"oneof=mysql memory"`
+}
+
+type scheduler struct {
+	// How often the server looks for scheduled posts that are due
+	PublishInterval time.Duration `envconfig:"SCHEDULER_PUBLISH_INTERVAL" default:"30s"`
+	// How often polls past their closing time are marked closed
+	PollCloseInterval time.Duration `envconfig:"SCHEDULER_POLL_CLOSE_INTERVAL" default:"1m"`
+	// How often hot scores are recomputed for the hot feed
+	HotScoreInterval time.Duration `envconfig:"SCHEDULER_HOT_SCORE_INTERVAL" default:"5m"`
+	// How often the trash is checked for items past their retention
+	TrashPurgeInterval time.Duration `envconfig:"SCHEDULER_TRASH_PURGE_INTERVAL" default:"1h"`
+}
+
+type trash struct {
+	// How long deleted posts and comments can be restored before they are
+	// deleted for good
+	Retention time.Duration `envconfig:"TRASH_RETENTION" default:"720h"`
+}
+
+type filter struct {
+	// Built-in word lists to load; any of en, th and ja
+	BlocklistLanguages []string `envconfig:"FILTER_BLOCKLIST_LANGUAGES" default:"en,th,ja" validate:"dive,oneof=en th ja"`
+	// Extra word list files, one word per line
+	BlocklistFiles []string `envconfig:"FILTER_BLOCKLIST_FILES" default:""`
+	// What happens to text with a blocked word
+	BlocklistAction string `envconfig:"FILTER_BLOCKLIST_ACTION" default:"mask" validate:"oneof=mask hold reject"`
+	// Text over these limits is held for review as likely spam
+	MaxLinks   int `envconfig:"FILTER_MAX_LINKS" default:"3"`
+	MaxRepeats int `envconfig:"FILTER_MAX_REPEATS" default:"10"`
+	// Longest text accepted, in characters
+	MaxPostLength    int `envconfig:"FILTER_MAX_POST_LENGTH" default:"5000"`
+	MaxCommentLength int `envconfig:"FILTER_MAX_COMMENT_LENGTH" default:"2000"`
+	MaxMessageLength int `envconfig:"FILTER_MAX_MESSAGE_LENGTH" default:"2000"`
+}
+
+type reactions struct {
+	// Named reactions clients may use, in the order they are offered
+	Names []string `envconfig:"REACTIONS" default:"like,love,haha,wow,sad,angry" validate:"min=1,dive,required,max=32"`
+	// Also accept any single emoji as a reaction
+	AllowEmoji bool `envconfig:"REACTIONS_ALLOW_EMOJI" default:"true"`
+}
+
 type Config struct {
-	Server   server
-	Database database
-	JWT      jwt
+	Server      server
+	Database    database
+	JWT         jwt
+	Game        game
+	Certificate certificate
+	Storage     storage
+	Upload      upload
+	Search      search
+	Scheduler   scheduler
+	Filter      filter
+	Trash       trash
+	Reactions   reactions
 }
 
 var cfg Config
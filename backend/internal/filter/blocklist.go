@@ -0,0 +1,141 @@
+package filter
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"typinggame-api/internal/search"
+)
+
+//go:embed lists/*.txt
+var builtinLists embed.FS
+
+// Languages that have a built-in word list.
+var Languages = []string{"en", "th", "ja"}
+
+// BuiltinWords returns the built-in word list for a language.
+func BuiltinWords(language string) ([]string, error) {
+	f, err := builtinLists.Open("lists/" + language + ".txt")
+	if err != nil {
+		return nil, fmt.Errorf("no built-in word list for %q", language)
+	}
+	defer f.Close()
+	return ParseWords(f)
+}
+
+// LoadWords reads a word list file in the same format as the built-in ones.
+func LoadWords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseWords(f)
+}
+
+// ParseWords reads one entry per line. Blank lines and lines starting with #
+// are skipped.
+func ParseWords(r io.Reader) ([]string, error) {
+	var words []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words, scanner.Err()
+}
+
+// blockedWord is one folded blocklist entry. Words in scripts that separate
+// words with spaces only match whole words (or word starts when prefix is
+// set); Thai, Japanese and Chinese words match anywhere.
+type blockedWord struct {
+	runes  []rune
+	prefix bool
+	whole  bool
+}
+
+type blocklist struct {
+	words   []blockedWord
+	verdict Verdict
+}
+
+// NewBlocklist flags text containing any of words with verdict. Matching
+// ignores case and full-width forms. With Mask the matched characters are
+// replaced by asterisks.
+func NewBlocklist(words []string, verdict Verdict) ContentFilter {
+	b := &blocklist{verdict: verdict}
+	for _, word := range words {
+		prefix := strings.HasSuffix(word, "*")
+		runes := []rune(search.Fold(strings.TrimSuffix(word, "*")))
+		if len(runes) == 0 {
+			continue
+		}
+		b.words = append(b.words, blockedWord{runes: runes, prefix: prefix, whole: !unspaced(runes[0])})
+	}
+	return b
+}
+
+// unspaced reports whether r belongs to a script written without spaces
+// between words.
+func unspaced(r rune) bool {
+	return unicode.In(r, unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar,
+		unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r)
+}
+
+func (b *blocklist) Check(input Input) Result {
+	folded := []rune(search.Fold(input.Text))
+	var masked []rune
+
+	for _, word := range b.words {
+		n := len(word.runes)
+		for i := 0; i+n <= len(folded); i++ {
+			if !word.matchesAt(folded, i) {
+				continue
+			}
+			if masked == nil {
+				masked = []rune(input.Text)
+			}
+			for j := i; j < i+n; j++ {
+				masked[j] = '*'
+			}
+			i += n - 1
+		}
+	}
+
+	if masked == nil {
+		return Result{Verdict: Allow, Text: input.Text}
+	}
+	result := Result{Verdict: b.verdict, Text: input.Text, Reasons: []string{"มีคำที่ไม่เหมาะสม"}}
+	if b.verdict == Mask {
+		result.Text = string(masked)
+	}
+	return result
+}
+
+func (w *blockedWord) matchesAt(text []rune, i int) bool {
+	end := i + len(w.runes)
+	for j, r := range w.runes {
+		if text[i+j] != r {
+			return false
+		}
+	}
+	if !w.whole {
+		return true
+	}
+	if i > 0 && isWordRune(text[i-1]) {
+		return false
+	}
+	return w.prefix || end == len(text) || !isWordRune(text[end])
+}
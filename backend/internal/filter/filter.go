@@ -0,0 +1,93 @@
+// Package filter screens user text before it is stored. Filters are chained;
+// each one can let the text through, mask parts of it, hold it for a
+// moderator or reject it outright.
+package filter
+
+import "strings"
+
+// Verdict is a filter's decision. Verdicts are ordered by severity, so the
+// strictest decision of a chain is the largest.
+type Verdict int
+
+const (
+	Allow Verdict = iota
+	Mask
+	Hold
+	Reject
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Mask:
+		return "mask"
+	case Hold:
+		return "hold"
+	case Reject:
+		return "reject"
+	}
+	return "allow"
+}
+
+// ParseVerdict reads a verdict written as by String.
+func ParseVerdict(s string) (Verdict, bool) {
+	for _, v := range []Verdict{Allow, Mask, Hold, Reject} {
+		if v.String() == s {
+			return v, true
+		}
+	}
+	return Allow, false
+}
+
+// Kind is what sort of content is being screened, since limits differ.
+type Kind string
+
+const (
+	Post    Kind = "post"
+	Comment Kind = "comment"
+	Message Kind = "message"
+)
+
+type Input struct {
+	Kind     Kind
+	AuthorID string
+	Text     string
+}
+
+// Result is a filter's decision. Text is the input text with any masking
+// applied; Reasons explain every verdict other than Allow.
+type Result struct {
+	Verdict Verdict
+	Text    string
+	Reasons []string
+}
+
+// Reason joins the reasons into one line.
+func (r Result) Reason() string {
+	return strings.Join(r.Reasons, ", ")
+}
+
+type ContentFilter interface {
+	Check(input Input) Result
+}
+
+// Chain runs filters in order. Each filter sees the text as masked by the
+// ones before it, the strictest verdict wins, and a rejection stops the
+// chain.
+type Chain []ContentFilter
+
+func (c Chain) Check(input Input) Result {
+	result := Result{Verdict: Allow, Text: input.Text}
+	for _, f := range c {
+		input.Text = result.Text
+		next := f.Check(input)
+		result.Text = next.Text
+		result.Reasons = append(result.Reasons, next.Reasons...)
+		if next.Verdict > result.Verdict {
+			result.Verdict = next.Verdict
+		}
+		if result.Verdict == Reject {
+			break
+		}
+	}
+	return result
+}
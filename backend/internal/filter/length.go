@@ -0,0 +1,24 @@
+package filter
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+type lengthFilter struct {
+	limits map[Kind]int
+}
+
+// NewLengthFilter rejects text longer than the limit for its kind, counted in
+// characters. Kinds without a limit, or with a limit of 0, are not checked.
+func NewLengthFilter(limits map[Kind]int) ContentFilter {
+	return &lengthFilter{limits: limits}
+}
+
+func (f *lengthFilter) Check(input Input) Result {
+	limit := f.limits[input.Kind]
+	if limit > 0 && utf8.RuneCountInString(input.Text) > limit {
+		return Result{Verdict: Reject, Text: input.Text, Reasons: []string{fmt.Sprintf("ข้อความยาวเกิน %d ตัวอักษร", limit)}}
+	}
+	return Result{Verdict: Allow, Text: input.Text}
+}
@@ -0,0 +1,60 @@
+package filter
+
+import "regexp"
+
+var linkPattern = regexp.MustCompile(`(?i)(?:https?://|www\.)\S+`)
+
+const (
+	// maxRepeatedChunk is the longest stretch of text checked for repetition
+	maxRepeatedChunk = 20
+	// minRepeatedSpan keeps short bursts such as "55555" (Thai for haha) from
+	// counting as spam
+	minRepeatedSpan = 50
+)
+
+type spamFilter struct {
+	maxLinks   int
+	maxRepeats int
+}
+
+// NewSpamFilter holds text with more than maxLinks links, or in which the
+// same chunk of up to 20 characters appears maxRepeats times in a row. The
+// repetition check works the same with or without spaces between words.
+// A limit of 0 turns that check off.
+func NewSpamFilter(maxLinks, maxRepeats int) ContentFilter {
+	return &spamFilter{maxLinks: maxLinks, maxRepeats: maxRepeats}
+}
+
+func (f *spamFilter) Check(input Input) Result {
+	var reasons []string
+	if f.maxLinks > 0 && len(linkPattern.FindAllStringIndex(input.Text, f.maxLinks+1)) > f.maxLinks {
+		reasons = append(reasons, "มีลิงก์มากเกินไป")
+	}
+	if f.maxRepeats > 0 && repetitive([]rune(input.Text), f.maxRepeats) {
+		reasons = append(reasons, "มีข้อความซ้ำกันมากเกินไป")
+	}
+	if len(reasons) == 0 {
+		return Result{Verdict: Allow, Text: input.Text}
+	}
+	return Result{Verdict: Hold, Text: input.Text, Reasons: reasons}
+}
+
+// repetitive reports whether some chunk of text repeats at least times times
+// back to back, covering at least minRepeatedSpan characters.
+func repetitive(text []rune, times int) bool {
+	for period := 1; period <= maxRepeatedChunk; period++ {
+		need := max(period*times, minRepeatedSpan)
+		run := 0
+		for i := period; i < len(text); i++ {
+			if text[i] != text[i-period] {
+				run = 0
+				continue
+			}
+			run++
+			if run+period >= need {
+				return true
+			}
+		}
+	}
+	return false
+}
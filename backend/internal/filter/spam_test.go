@@ -0,0 +1,33 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRepetitive(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		times int
+		want  bool
+	}{
+		{"short laugh", "555555555", 3, false},
+		{"long run of one character", strings.Repeat("5", 60), 3, true},
+		{"repeated word with spaces", strings.Repeat("buy now ", 8), 5, true},
+		{"repeated thai without spaces", strings.Repeat("สแปม", 15), 5, true},
+		{"repeated japanese", strings.Repeat("買って", 20), 5, true},
+		{"repeats under the span", strings.Repeat("buy now ", 5), 5, false},
+		{"fewer repeats than times", strings.Repeat("buy now ", 8), 10, false},
+		{"chunk longer than checked", strings.Repeat("abcdefghijklmnopqrstuvwxy", 4), 2, false},
+		{"ordinary text", "The quick brown fox jumps over the lazy dog while the typing game keeps score of every word.", 3, false},
+		{"empty", "", 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := repetitive([]rune(tt.text), tt.times); got != tt.want {
+				t.Errorf("repetitive(%q, %d) = %v, want %v", tt.text, tt.times, got, tt.want)
+			}
+		})
+	}
+}
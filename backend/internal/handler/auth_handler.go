@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/go-playground/validator/v10"
@@ -20,6 +21,8 @@ type RegisterRequest struct {
 	Name     string `json:"name" validate:"required,min=2"`
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=6"`
+	// GuestToken, when set, moves the guest's game history into the new account
+	GuestToken string `json:"guestToken"`
 }
 
 type LoginRequest struct {
@@ -37,7 +40,7 @@ func (h *AuthHandler) Register(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
-	user, err := h.authService.Register(req.Name, req.Email, req.Password)
+	user, err := h.authService.Register(req.Name, req.Email, req.Password, req.GuestToken)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
@@ -56,6 +59,9 @@ func (h *AuthHandler) Login(c echo.Context) error {
 	}
 
 	token, user, err := h.authService.Login(req.Email, req.Password)
+	if errors.Is(err, service.ErrUserSuspended) {
+		return c.JSON(http.StatusForbidden, map[string]string{"message": err.Error()})
+	}
 	if err != nil {
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": err.Error()})
 	}
@@ -66,3 +72,15 @@ func (h *AuthHandler) Login(c echo.Context) error {
 	})
 }
 
+
+func (h *AuthHandler) StartGuestSession(c echo.Context) error {
+	token, user, err := h.authService.StartGuestSession()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"token": token,
+		"user":  user,
+	})
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+	"typinggame-api/internal/service"
+)
+
+type BookmarkHandler struct {
+	bookmarkService service.BookmarkService
+	renderer        *postRenderer
+}
+
+func NewBookmarkHandler(bookmarkService service.BookmarkService, postService service.PostService, attachmentService service.AttachmentService, pollService service.PollService) *BookmarkHandler {
+	return &BookmarkHandler{
+		bookmarkService: bookmarkService,
+		renderer:        newPostRenderer(postService, attachmentService, bookmarkService, pollService),
+	}
+}
+
+type BookmarkRequest struct {
+	CollectionID string `json:"collectionId"`
+}
+
+type CollectionRequest struct {
+	Name string `json:"name"`
+}
+
+func (h *BookmarkHandler) writeError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrPostNotFound), errors.Is(err, service.ErrCollectionNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	case errors.Is(err, service.ErrCollectionExists):
+		return c.JSON(http.StatusConflict, map[string]string{"message": err.Error()})
+	case errors.Is(err, service.ErrInvalidCollectionName), errors.Is(err, repository.ErrInvalidCursor):
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+}
+
+// AddBookmark saves a post. Sending it again with another collectionId moves
+// the bookmark.
+func (h *BookmarkHandler) AddBookmark(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	postID := c.Param("id")
+
+	var req BookmarkRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "ข้อมูลไม่ถูกต้อง"})
+	}
+
+	bookmark, err := h.bookmarkService.Bookmark(postID, userID, req.CollectionID)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, bookmark)
+}
+
+func (h *BookmarkHandler) RemoveBookmark(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	postID := c.Param("id")
+
+	if err := h.bookmarkService.RemoveBookmark(postID, userID); err != nil {
+		return h.writeError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "ลบบุ๊กมาร์กสำเร็จ"})
+}
+
+// GetBookmarks lists saved posts, most recently saved first. ?collection=
+// limits the list to one collection.
+func (h *BookmarkHandler) GetBookmarks(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	cursor, limit := pageParams(c)
+
+	page, err := h.bookmarkService.GetBookmarks(userID, c.QueryParam("collection"), cursor, limit)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+
+	posts := make([]models.Post, 0, len(page.Bookmarks))
+	for _, bookmark := range page.Bookmarks {
+		posts = append(posts, bookmark.Post)
+	}
+	items, err := h.renderer.items(posts, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+	for i, bookmark := range page.Bookmarks {
+		items[i]["bookmarkedAt"] = bookmark.CreatedAt
+		items[i]["collectionId"] = bookmark.CollectionID
+	}
+
+	return c.JSON(http.StatusOK, pageResponse(items, page.NextCursor))
+}
+
+func (h *BookmarkHandler) GetCollections(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	collections, err := h.bookmarkService.GetCollections(userID)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	if collections == nil {
+		collections = []models.BookmarkCollection{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"collections": collections})
+}
+
+func (h *BookmarkHandler) CreateCollection(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	var req CollectionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "ข้อมูลไม่ถูกต้อง"})
+	}
+
+	collection, err := h.bookmarkService.CreateCollection(userID, req.Name)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, collection)
+}
+
+func (h *BookmarkHandler) RenameCollection(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	collectionID := c.Param("id")
+
+	var req CollectionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "ข้อมูลไม่ถูกต้อง"})
+	}
+
+	collection, err := h.bookmarkService.RenameCollection(collectionID, userID, req.Name)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, collection)
+}
+
+// DeleteCollection removes a collection; its bookmarks are kept.
+func (h *BookmarkHandler) DeleteCollection(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	collectionID := c.Param("id")
+
+	if err := h.bookmarkService.DeleteCollection(collectionID, userID); err != nil {
+		return h.writeError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "ลบคอลเลกชันสำเร็จ"})
+}
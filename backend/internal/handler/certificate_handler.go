@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"typinggame-api/internal/service"
+)
+
+type CertificateHandler struct {
+	certificateService service.CertificateService
+}
+
+func NewCertificateHandler(certificateService service.CertificateService) *CertificateHandler {
+	return &CertificateHandler{certificateService: certificateService}
+}
+
+func (h *CertificateHandler) IssueCertificate(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	scoreID := c.Param("id")
+
+	signed, err := h.certificateService.IssueCertificate(scoreID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrScoreNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+		case errors.Is(err, service.ErrNotScoreOwner), errors.Is(err, service.ErrScoreFlagged):
+			return c.JSON(http.StatusForbidden, map[string]string{"message": err.Error()})
+		default:
+			return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		}
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"certificate": signed.Certificate,
+		"payload":     signed.Payload,
+		"signature":   signed.Signature,
+		"publicKey":   signed.PublicKey,
+		"algorithm":   "Ed25519",
+	})
+}
+
+// VerifyCertificate is public so anyone handed a certificate (an employer, a
+// teacher) can check it without an account.
+func (h *CertificateHandler) VerifyCertificate(c echo.Context) error {
+	payload := c.QueryParam("payload")
+	signature := c.QueryParam("signature")
+
+	if payload == "" || signature == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "query parameters 'payload' and 'signature' are required"})
+	}
+
+	result, err := h.certificateService.VerifyCertificate(payload, signature)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	response := map[string]interface{}{
+		"valid":     result.Valid,
+		"publicKey": h.certificateService.PublicKey(),
+	}
+	if result.Reason != "" {
+		response["reason"] = result.Reason
+	}
+	if result.Certificate != nil {
+		response["certificate"] = result.Certificate
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
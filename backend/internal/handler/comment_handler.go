@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
 	"typinggame-api/internal/service"
 )
 
@@ -16,6 +20,108 @@ func NewCommentHandler(commentService service.CommentService) *CommentHandler {
 	return &CommentHandler{commentService: commentService}
 }
 
+// commentResponse is the shape every comment endpoint returns.
+func commentResponse(comment *models.Comment) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         comment.ID,
+		"content":    comment.Content,
+		"postId":     comment.PostID,
+		"parentId":   comment.ParentID,
+		"depth":      comment.Depth,
+		"replies":    comment.Replies,
+		"authorId":   comment.AuthorID,
+		"authorName": comment.Author.Name,
+		"entities":   entitiesResponse(comment.Entities),
+		"hidden":     comment.Hidden,
+		"createdAt":  comment.CreatedAt,
+		"updatedAt":  comment.UpdatedAt,
+		"version":    comment.Version,
+	}
+}
+
+// threadResponse is a comment as it appears in a thread, with its
+// reactions. Deleted and hidden comments that have replies show as
+// placeholders, with "deleted": true and nothing of their content, author
+// or reactions.
+func threadResponse(comment *models.Comment, summary *repository.ReactionSummary) map[string]interface{} {
+	if !comment.IsPlaceholder() {
+		response := reactionFields(commentResponse(comment), summary)
+		response["deleted"] = false
+		return response
+	}
+	return map[string]interface{}{
+		"id":         comment.ID,
+		"content":    "",
+		"postId":     comment.PostID,
+		"parentId":   comment.ParentID,
+		"depth":      comment.Depth,
+		"replies":    comment.Replies,
+		"authorId":   nil,
+		"authorName": nil,
+		"entities":   entitiesResponse(nil),
+		"deleted":    true,
+		"createdAt":  comment.CreatedAt,
+	}
+}
+
+// threadTree nests a whole thread, given oldest first, under "children".
+// Replies whose parent isn't in comments, such as replies to a blocked
+// user, are left out.
+func threadTree(comments []models.Comment, summaries map[string]*repository.ReactionSummary) []map[string]interface{} {
+	nodes := make(map[string]map[string]interface{}, len(comments))
+	children := make(map[string][]map[string]interface{}, len(comments))
+	for i := range comments {
+		nodes[comments[i].ID] = threadResponse(&comments[i], summaries[comments[i].ID])
+	}
+	roots := []map[string]interface{}{}
+	for _, comment := range comments {
+		node := nodes[comment.ID]
+		switch {
+		case comment.ParentID == nil:
+			roots = append(roots, node)
+		case nodes[*comment.ParentID] != nil:
+			children[*comment.ParentID] = append(children[*comment.ParentID], node)
+		}
+	}
+	for id, node := range nodes {
+		if children[id] == nil {
+			node["children"] = []map[string]interface{}{}
+		} else {
+			node["children"] = children[id]
+		}
+	}
+	return roots
+}
+
+func threadPage(page *service.CommentPage, summaries map[string]*repository.ReactionSummary) map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(page.Comments))
+	for i := range page.Comments {
+		items = append(items, threadResponse(&page.Comments[i], summaries[page.Comments[i].ID]))
+	}
+	return pageResponse(items, page.NextCursor)
+}
+
+// writeComment writes a comment with its ETag.
+func writeComment(c echo.Context, status int, comment *models.Comment) error {
+	return writeTagged(c, status, commentResponse(comment), comment.Version)
+}
+
+// GetComment returns one comment, for revalidating it with If-None-Match
+// before an edit.
+func (h *CommentHandler) GetComment(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	comment, err := h.commentService.GetComment(c.Param("commentId"), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrCommentNotFound) || errors.Is(err, service.ErrPostNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return writeComment(c, http.StatusOK, comment)
+}
+
 type CreateCommentRequest struct {
 	Content string `json:"content" validate:"required"`
 }
@@ -35,46 +141,97 @@ func (h *CommentHandler) CreateComment(c echo.Context) error {
 
 	comment, err := h.commentService.CreateComment(req.Content, postID, userID)
 	if err != nil {
+		if errors.Is(err, service.ErrPostNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+		}
+		if errors.Is(err, service.ErrContentRejected) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
 
-	// Format response
-	response := map[string]interface{}{
-		"id":        comment.ID,
-		"content":   comment.Content,
-		"postId":    comment.PostID,
-		"authorId":  comment.AuthorID,
-		"authorName": comment.Author.Name,
-		"createdAt": comment.CreatedAt,
-		"updatedAt": comment.UpdatedAt,
-	}
-
-	return c.JSON(http.StatusCreated, response)
+	return writeComment(c, heldStatus(http.StatusCreated, comment.Hidden), comment)
 }
 
+// GetComments pages through the comments on a post, with reply counts, or
+// returns the whole thread nested with ?view=tree.
 func (h *CommentHandler) GetComments(c echo.Context) error {
+	userID := c.Get("user_id").(string)
 	postID := c.Param("id")
 
-	comments, err := h.commentService.GetCommentsByPostID(postID)
+	if c.QueryParam("view") == "tree" {
+		comments, err := h.commentService.GetThread(postID, userID)
+		if err != nil {
+			return h.threadError(c, err)
+		}
+		summaries, err := h.commentService.GetReactionSummaries(comments, userID)
+		if err != nil {
+			return h.threadError(c, err)
+		}
+		return writeTagged(c, http.StatusOK, threadTree(comments, summaries), 0)
+	}
+
+	cursor, limit := pageParams(c)
+	page, err := h.commentService.GetComments(postID, userID, cursor, limit)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return h.threadError(c, err)
 	}
+	return h.writeThreadPage(c, page, userID)
+}
 
-	// Format response
-	var response []map[string]interface{}
-	for _, comment := range comments {
-		response = append(response, map[string]interface{}{
-			"id":        comment.ID,
-			"content":   comment.Content,
-			"postId":    comment.PostID,
-			"authorId":  comment.AuthorID,
-			"authorName": comment.Author.Name,
-			"createdAt": comment.CreatedAt,
-			"updatedAt": comment.UpdatedAt,
-		})
+// GetReplies pages through the direct replies of a comment.
+func (h *CommentHandler) GetReplies(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	cursor, limit := pageParams(c)
+
+	page, err := h.commentService.GetReplies(c.Param("commentId"), userID, cursor, limit)
+	if err != nil {
+		return h.threadError(c, err)
+	}
+	return h.writeThreadPage(c, page, userID)
+}
+
+func (h *CommentHandler) writeThreadPage(c echo.Context, page *service.CommentPage, viewerID string) error {
+	summaries, err := h.commentService.GetReactionSummaries(page.Comments, viewerID)
+	if err != nil {
+		return h.threadError(c, err)
+	}
+	return writeTagged(c, http.StatusOK, threadPage(page, summaries), 0)
+}
+
+func (h *CommentHandler) threadError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrPostNotFound), errors.Is(err, service.ErrCommentNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	case errors.Is(err, repository.ErrInvalidCursor):
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+}
+
+// ReplyToComment answers a comment. Replies nest up to a fixed depth; past
+// it they are added next to the comment they answer.
+func (h *CommentHandler) ReplyToComment(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	var req CreateCommentRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "ข้อมูลไม่ถูกต้อง"})
 	}
 
-	return c.JSON(http.StatusOK, response)
+	if err := validator.New().Struct(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	comment, err := h.commentService.ReplyToComment(req.Content, c.Param("commentId"), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrContentRejected) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+		}
+		return h.threadError(c, err)
+	}
+
+	return writeComment(c, heldStatus(http.StatusCreated, comment.Hidden), comment)
 }
 
 func (h *CommentHandler) DeleteComment(c echo.Context) error {
@@ -88,6 +245,21 @@ func (h *CommentHandler) DeleteComment(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]string{"message": "ลบคอมเมนต์สำเร็จ"})
 }
 
+func (h *CommentHandler) RestoreComment(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	commentID := c.Param("commentId")
+
+	comment, err := h.commentService.RestoreComment(commentID, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrTrashItemNotFound) || errors.Is(err, service.ErrPostNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, commentResponse(comment))
+}
+
 type UpdateCommentRequest struct {
 	Content string `json:"content" validate:"required"`
 }
@@ -105,8 +277,14 @@ func (h *CommentHandler) UpdateComment(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
-	comment, err := h.commentService.UpdateComment(commentID, userID, req.Content)
+	comment, err := h.commentService.UpdateComment(commentID, userID, req.Content, ifMatchVersion(c))
 	if err != nil {
+		if errors.Is(err, service.ErrContentRejected) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+		}
+		if errors.Is(err, service.ErrStaleVersion) {
+			return h.writeStale(c, commentID, userID, err)
+		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
 
@@ -114,39 +292,82 @@ func (h *CommentHandler) UpdateComment(c echo.Context) error {
 		return c.JSON(http.StatusForbidden, map[string]string{"message": "ไม่มีสิทธิ์แก้ไขคอมเมนต์นี้"})
 	}
 
-	// Format response
-	response := map[string]interface{}{
-		"id":        comment.ID,
-		"content":   comment.Content,
-		"postId":    comment.PostID,
-		"authorId":  comment.AuthorID,
-		"authorName": comment.Author.Name,
-		"createdAt": comment.CreatedAt,
-		"updatedAt": comment.UpdatedAt,
-	}
+	return writeComment(c, heldStatus(http.StatusOK, comment.Hidden), comment)
+}
 
-	return c.JSON(http.StatusOK, response)
+// writeStale answers an edit made against an old version with 412 and the
+// comment as it is now, tagged with its current ETag.
+func (h *CommentHandler) writeStale(c echo.Context, commentID, viewerID string, err error) error {
+	comment, findErr := h.commentService.GetComment(commentID, viewerID)
+	if findErr != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": findErr.Error()})
+	}
+	current := commentResponse(comment)
+	data, findErr := json.Marshal(current)
+	if findErr != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": findErr.Error()})
+	}
+	c.Response().Header().Set("ETag", etag(comment.Version, data))
+	return c.JSON(http.StatusPreconditionFailed, map[string]interface{}{
+		"message": err.Error(),
+		"current": current,
+	})
 }
 
 func (h *CommentHandler) GetEditHistory(c echo.Context) error {
+	userID := c.Get("user_id").(string)
 	commentID := c.Param("commentId")
 
-	histories, err := h.commentService.GetEditHistory(commentID)
+	history, err := h.commentService.GetEditHistory(commentID, userID)
+	if err != nil {
+		return editHistoryError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, editHistoryResponse(history))
+}
+
+
+func (h *CommentHandler) ReactToComment(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	commentID := c.Param("commentId")
+
+	reaction, err := bindReaction(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := h.commentService.ReactToComment(commentID, userID, reaction); err != nil {
+		return reactionError(c, err, service.ErrCommentNotFound, service.ErrPostNotFound)
+	}
+
+	summary, err := h.commentService.GetReactionSummary(commentID, userID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
 
-	// Format response
-	var response []map[string]interface{}
-	for _, history := range histories {
-		response = append(response, map[string]interface{}{
-			"id":        history.ID,
-			"oldContent": history.OldContent,
-			"newContent": history.NewContent,
-			"createdAt": history.CreatedAt,
-		})
+	return c.JSON(http.StatusOK, reactionFields(map[string]interface{}{"message": "สำเร็จ"}, summary))
+}
+
+func (h *CommentHandler) GetReactions(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	summary, err := h.commentService.GetReactionSummary(c.Param("commentId"), userID)
+	if err != nil {
+		return reactionError(c, err, service.ErrCommentNotFound, service.ErrPostNotFound)
 	}
 
-	return c.JSON(http.StatusOK, response)
+	return c.JSON(http.StatusOK, reactionFields(map[string]interface{}{}, summary))
 }
 
+// GetReactors pages through who reacted to a comment, optionally with one
+// ?reaction= only.
+func (h *CommentHandler) GetReactors(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	cursor, limit := pageParams(c)
+
+	page, err := h.commentService.GetReactors(c.Param("commentId"), userID, c.QueryParam("reaction"), cursor, limit)
+	if err != nil {
+		return reactionError(c, err, service.ErrCommentNotFound, service.ErrPostNotFound)
+	}
+	return c.JSON(http.StatusOK, reactorPage(page))
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+	"typinggame-api/internal/service"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	defaultTrendingLimit  = 10
+	maxTrendingLimit      = 50
+)
+
+type EntityHandler struct {
+	entityService service.EntityService
+}
+
+func NewEntityHandler(entityService service.EntityService) *EntityHandler {
+	return &EntityHandler{entityService: entityService}
+}
+
+// entitiesResponse never returns null, so clients can always iterate.
+func entitiesResponse(entities models.TextEntities) models.TextEntities {
+	if entities == nil {
+		return models.TextEntities{}
+	}
+	return entities
+}
+
+// GetTrending returns the most used hashtags the viewer can see used.
+// ?window= takes a duration such
+// as 6h (default 24h, at most 7 days) and ?limit= caps the list.
+func (h *EntityHandler) GetTrending(c echo.Context) error {
+	window := defaultTrendingWindow
+	if windowParam := c.QueryParam("window"); windowParam != "" {
+		parsed, err := time.ParseDuration(windowParam)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": "window ไม่ถูกต้อง"})
+		}
+		window = parsed
+	}
+
+	limit := defaultTrendingLimit
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 {
+			limit = min(l, maxTrendingLimit)
+		}
+	}
+
+	tags, err := h.entityService.GetTrending(c.Get("user_id").(string), window, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+	if tags == nil {
+		tags = []repository.TagCount{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"tags": tags})
+}
+
+// GetMentions lists posts and comments that mention the caller.
+func (h *EntityHandler) GetMentions(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	cursor, limit := pageParams(c)
+
+	page, err := h.entityService.GetMentions(userID, cursor, limit)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	items := make([]map[string]interface{}, 0, len(page.Mentions))
+	for _, mention := range page.Mentions {
+		items = append(items, map[string]interface{}{
+			"id":         mention.ID,
+			"sourceType": mention.SourceType,
+			"sourceId":   mention.SourceID,
+			"postId":     mention.PostID,
+			"authorId":   mention.AuthorID,
+			"authorName": mention.Author.Name,
+			"createdAt":  mention.CreatedAt,
+		})
+	}
+
+	return c.JSON(http.StatusOK, pageResponse(items, page.NextCursor))
+}
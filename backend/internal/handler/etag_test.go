@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestNoneMatch(t *testing.T) {
+	const tag = `"3-0011223344556677"`
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"missing", "", false},
+		{"same tag", tag, true},
+		{"weak tag", "W/" + tag, true},
+		{"in a list", `"2-aaaaaaaaaaaaaaaa", ` + tag, true},
+		{"wildcard", "*", true},
+		{"other tag", `"3-ffffffffffffffff"`, false},
+		{"unquoted", "3-0011223344556677", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := noneMatch(tt.header, tag); got != tt.want {
+				t.Errorf("noneMatch(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"missing", "", 0},
+		{"wildcard", "*", 0},
+		{"issued tag", etag(7, []byte(`{"id":"p1"}`)), 7},
+		{"weak tag", `W/"4-0011223344556677"`, 4},
+		{"first of a list", `"5-aa", "6-bb"`, 5},
+		{"surrounding spaces", `  "2-aa"  `, 2},
+		{"unquoted", "3-aa", 3},
+		{"not a version", `"abc-aa"`, -1},
+		{"unversioned list tag", `"0-aa"`, -1},
+		{"negative", `"-1-aa"`, -1},
+	}
+	e := echo.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("If-Match", tt.header)
+			}
+			c := e.NewContext(req, httptest.NewRecorder())
+			if got := ifMatchVersion(c); got != tt.want {
+				t.Errorf("ifMatchVersion(%q) = %d, want %d", tt.header, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,12 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
@@ -9,17 +14,23 @@ import (
 )
 
 type GameHandler struct {
-	gameService service.GameService
+	gameService    service.GameService
+	leaderboardHub service.LeaderboardHub
 }
 
-func NewGameHandler(gameService service.GameService) *GameHandler {
-	return &GameHandler{gameService: gameService}
+func NewGameHandler(gameService service.GameService, leaderboardHub service.LeaderboardHub) *GameHandler {
+	return &GameHandler{
+		gameService:    gameService,
+		leaderboardHub: leaderboardHub,
+	}
 }
 
 type SaveScoreRequest struct {
-	Score      int    `json:"score" validate:"required,min=0"`
-	WordsTyped int    `json:"wordsTyped" validate:"required,min=0"`
-	Difficulty string `json:"difficulty" validate:"required"`
+	Score      int     `json:"score" validate:"required,min=0"`
+	WordsTyped int     `json:"wordsTyped" validate:"required,min=0"`
+	Difficulty string  `json:"difficulty" validate:"required"`
+	WPM        float64 `json:"wpm" validate:"min=0"`
+	Accuracy   float64 `json:"accuracy" validate:"min=0,max=100"`
 }
 
 func (h *GameHandler) SaveScore(c echo.Context) error {
@@ -34,8 +45,17 @@ func (h *GameHandler) SaveScore(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
-	score, err := h.gameService.SaveScore(userID, req.Score, req.WordsTyped, req.Difficulty)
+	score, err := h.gameService.SaveScore(userID, service.ScoreSubmission{
+		Score:      req.Score,
+		WordsTyped: req.WordsTyped,
+		Difficulty: req.Difficulty,
+		WPM:        req.WPM,
+		Accuracy:   req.Accuracy,
+	})
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidScore) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
 
@@ -44,42 +64,122 @@ func (h *GameHandler) SaveScore(c echo.Context) error {
 		"score":      score.Score,
 		"wordsTyped": score.WordsTyped,
 		"difficulty": score.Difficulty,
+		"wpm":        score.WPM,
+		"accuracy":   score.Accuracy,
+		"playedAt":   score.PlayedAt,
 		"createdAt":  score.CreatedAt,
 	})
 }
 
-func (h *GameHandler) GetTopScores(c echo.Context) error {
-	limit := 10
-	if limitParam := c.QueryParam("limit"); limitParam != "" {
-		// Parse limit if provided
+type SyncScoreItem struct {
+	ClientID   string    `json:"clientId"`
+	Score      int       `json:"score"`
+	WordsTyped int       `json:"wordsTyped"`
+	Difficulty string    `json:"difficulty"`
+	WPM        float64   `json:"wpm"`
+	Accuracy   float64   `json:"accuracy"`
+	PlayedAt   time.Time `json:"playedAt"`
+}
+
+type SyncScoresRequest struct {
+	Scores []SyncScoreItem `json:"scores" validate:"required,min=1"`
+}
+
+// SyncScores stores runs recorded while the client was offline. Each item is
+// validated on its own so one bad run doesn't block the rest of the batch.
+func (h *GameHandler) SyncScores(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	var req SyncScoresRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "ข้อมูลไม่ถูกต้อง"})
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	submissions := make([]service.ScoreSubmission, 0, len(req.Scores))
+	for _, item := range req.Scores {
+		submissions = append(submissions, service.ScoreSubmission{
+			ClientID:   item.ClientID,
+			Score:      item.Score,
+			WordsTyped: item.WordsTyped,
+			Difficulty: item.Difficulty,
+			WPM:        item.WPM,
+			Accuracy:   item.Accuracy,
+			PlayedAt:   item.PlayedAt,
+		})
 	}
 
-	scores, err := h.gameService.GetTopScores(limit)
+	results, err := h.gameService.SaveScoresBatch(userID, submissions)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
 	var response []map[string]interface{}
-	for _, score := range scores {
-		response = append(response, map[string]interface{}{
-			"id":         score.ID,
-			"userId":     score.UserID,
-			"userName":   score.User.Name,
-			"score":      score.Score,
-			"wordsTyped": score.WordsTyped,
-			"difficulty": score.Difficulty,
-			"createdAt":  score.CreatedAt,
-		})
+	for _, result := range results {
+		item := map[string]interface{}{
+			"clientId": result.ClientID,
+			"status":   result.Status,
+		}
+		if result.Score != nil {
+			item["id"] = result.Score.ID
+			item["score"] = result.Score.Score
+			item["wordsTyped"] = result.Score.WordsTyped
+			item["difficulty"] = result.Score.Difficulty
+			item["wpm"] = result.Score.WPM
+			item["accuracy"] = result.Score.Accuracy
+			item["playedAt"] = result.Score.PlayedAt
+		}
+		if result.Message != "" {
+			item["message"] = result.Message
+		}
+		response = append(response, item)
 	}
 
-	return c.JSON(http.StatusOK, response)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"results": response,
+	})
+}
+
+// leaderboardFilter reads ?period=all|day|week|month, ?scope=friends and ?limit=.
+func leaderboardFilter(c echo.Context, difficulty string) (service.LeaderboardFilter, error) {
+	filter := service.LeaderboardFilter{
+		Difficulty: difficulty,
+		Period:     c.QueryParam("period"),
+		Friends:    c.QueryParam("scope") == "friends",
+		Limit:      10,
+	}
+
+	if !service.ValidLeaderboardPeriod(filter.Period) {
+		return filter, errors.New("period must be one of all, day, week, month")
+	}
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
+			filter.Limit = l
+		}
+	}
+	return filter, nil
+}
+
+func (h *GameHandler) GetTopScores(c echo.Context) error {
+	return h.getLeaderboard(c, "")
 }
 
 func (h *GameHandler) GetTopScoresByDifficulty(c echo.Context) error {
-	difficulty := c.Param("difficulty")
-	limit := 10
+	return h.getLeaderboard(c, c.Param("difficulty"))
+}
+
+func (h *GameHandler) getLeaderboard(c echo.Context, difficulty string) error {
+	userID := c.Get("user_id").(string)
 
-	scores, err := h.gameService.GetTopScoresByDifficulty(difficulty, limit)
+	filter, err := leaderboardFilter(c, difficulty)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	scores, err := h.gameService.GetLeaderboard(userID, filter)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
@@ -93,6 +193,8 @@ func (h *GameHandler) GetTopScoresByDifficulty(c echo.Context) error {
 			"score":      score.Score,
 			"wordsTyped": score.WordsTyped,
 			"difficulty": score.Difficulty,
+			"wpm":        score.WPM,
+			"accuracy":   score.Accuracy,
 			"createdAt":  score.CreatedAt,
 		})
 	}
@@ -100,6 +202,72 @@ func (h *GameHandler) GetTopScoresByDifficulty(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
+// StreamLeaderboard pushes leaderboard changes as server-sent events. The
+// first event is the current board, then a "rank_change" event whenever the
+// top entries change. Takes the same query parameters as GetTopScores plus
+// ?difficulty=.
+func (h *GameHandler) StreamLeaderboard(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	filter, err := leaderboardFilter(c, c.QueryParam("difficulty"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	sub, err := h.leaderboardHub.Subscribe(userID, filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+	defer h.leaderboardHub.Unsubscribe(sub)
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	if err := writeEvent(res, "snapshot", map[string]interface{}{"entries": sub.Snapshot}); err != nil {
+		return nil
+	}
+
+	heartbeat := time.NewTicker(25 * time.Second)
+	defer heartbeat.Stop()
+
+	ctx := c.Request().Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-sub.Events:
+			if !ok {
+				// Server is shutting down
+				return nil
+			}
+			if err := writeEvent(res, "rank_change", event); err != nil {
+				return nil
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": ping\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
+
+func writeEvent(res *echo.Response, name string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", name, payload); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
+
 func (h *GameHandler) GetUserBestScore(c echo.Context) error {
 	userID := c.Get("user_id").(string)
 
@@ -115,7 +283,8 @@ func (h *GameHandler) GetUserBestScore(c echo.Context) error {
 		"score":      score.Score,
 		"wordsTyped": score.WordsTyped,
 		"difficulty": score.Difficulty,
+		"wpm":        score.WPM,
+		"accuracy":   score.Accuracy,
 		"createdAt":  score.CreatedAt,
 	})
 }
-
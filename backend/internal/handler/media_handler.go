@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+	"path/filepath"
+
+	"github.com/labstack/echo/v4"
+	"typinggame-api/internal/storage"
+)
+
+// MediaHandler serves files kept by local storage. Only links issued by the
+// storage, which are given to viewers allowed to see the post, are served.
+type MediaHandler struct {
+	dir    string
+	signer *storage.URLSigner
+}
+
+func NewMediaHandler(dir string, signer *storage.URLSigner) *MediaHandler {
+	return &MediaHandler{dir: dir, signer: signer}
+}
+
+func (h *MediaHandler) ServeFile(c echo.Context) error {
+	key := c.Param("*")
+	if !h.signer.Verify(key, c.QueryParam("expires"), c.QueryParam("signature")) {
+		return c.JSON(http.StatusForbidden, map[string]string{"message": "ลิงก์ไม่ถูกต้องหรือหมดอายุ"})
+	}
+	// Keep shared caches from serving the file to people without a link
+	c.Response().Header().Set("Cache-Control", "private")
+	return c.File(filepath.Join(h.dir, filepath.FromSlash(key)))
+}
@@ -105,7 +105,7 @@ func (h *MessageHandler) SendMessage(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
+	return c.JSON(heldStatus(http.StatusOK, message.Hidden), map[string]interface{}{
 		"id":        message.ID,
 		"senderId":  message.SenderID,
 		"sender": map[string]interface{}{
@@ -114,6 +114,7 @@ func (h *MessageHandler) SendMessage(c echo.Context) error {
 		},
 		"content":   message.Content,
 		"isRead":    message.IsRead,
+		"hidden":    message.Hidden,
 		"createdAt": message.CreatedAt,
 	})
 }
@@ -148,9 +149,14 @@ func (h *MessageHandler) GetMessages(c echo.Context) error {
 		messages[i], messages[j] = messages[j], messages[i]
 	}
 
+	summaries, err := h.messageService.GetReactionSummaries(messages, c.Get("user_id").(string))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
 	var response []map[string]interface{}
 	for _, msg := range messages {
-		response = append(response, map[string]interface{}{
+		response = append(response, reactionFields(map[string]interface{}{
 			"id":        msg.ID,
 			"senderId":  msg.SenderID,
 			"sender": map[string]interface{}{
@@ -160,7 +166,7 @@ func (h *MessageHandler) GetMessages(c echo.Context) error {
 			"content":   msg.Content,
 			"isRead":    msg.IsRead,
 			"createdAt": msg.CreatedAt,
-		})
+		}, summaries[msg.ID]))
 	}
 
 	return c.JSON(http.StatusOK, response)
@@ -177,3 +183,48 @@ func (h *MessageHandler) MarkAsRead(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]string{"message": "marked as read"})
 }
 
+
+func (h *MessageHandler) ReactToMessage(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	messageID := c.Param("id")
+
+	reaction, err := bindReaction(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	if err := h.messageService.ReactToMessage(messageID, userID, reaction); err != nil {
+		return reactionError(c, err, service.ErrMessageNotFound)
+	}
+
+	summary, err := h.messageService.GetReactionSummary(messageID, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, reactionFields(map[string]interface{}{"message": "สำเร็จ"}, summary))
+}
+
+func (h *MessageHandler) GetReactions(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	summary, err := h.messageService.GetReactionSummary(c.Param("id"), userID)
+	if err != nil {
+		return reactionError(c, err, service.ErrMessageNotFound)
+	}
+
+	return c.JSON(http.StatusOK, reactionFields(map[string]interface{}{}, summary))
+}
+
+// GetReactors pages through who reacted to a message, optionally with one
+// ?reaction= only.
+func (h *MessageHandler) GetReactors(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	cursor, limit := pageParams(c)
+
+	page, err := h.messageService.GetReactors(c.Param("id"), userID, c.QueryParam("reaction"), cursor, limit)
+	if err != nil {
+		return reactionError(c, err, service.ErrMessageNotFound)
+	}
+	return c.JSON(http.StatusOK, reactorPage(page))
+}
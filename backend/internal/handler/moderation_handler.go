@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+	"typinggame-api/internal/service"
+)
+
+type ModerationHandler struct {
+	moderationService service.ModerationService
+}
+
+func NewModerationHandler(moderationService service.ModerationService) *ModerationHandler {
+	return &ModerationHandler{moderationService: moderationService}
+}
+
+type ReportRequest struct {
+	TargetType string `json:"targetType"`
+	TargetID   string `json:"targetId"`
+	Reason     string `json:"reason"`
+}
+
+type AssignRequest struct {
+	AssigneeID string `json:"assigneeId"`
+}
+
+type ModerationActionRequest struct {
+	Action         string     `json:"action"`
+	Reason         string     `json:"reason"`
+	SuspendedUntil *time.Time `json:"suspendedUntil"`
+}
+
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+func (h *ModerationHandler) writeError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrReportNotFound),
+		errors.Is(err, service.ErrReportTargetNotFound),
+		errors.Is(err, service.ErrUserNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	case errors.Is(err, service.ErrNotModerator), errors.Is(err, service.ErrNotAdmin):
+		return c.JSON(http.StatusForbidden, map[string]string{"message": err.Error()})
+	case errors.Is(err, service.ErrAlreadyReported), errors.Is(err, service.ErrReportClosed):
+		return c.JSON(http.StatusConflict, map[string]string{"message": err.Error()})
+	case errors.Is(err, service.ErrInvalidReportTarget),
+		errors.Is(err, service.ErrCannotReportSelf),
+		errors.Is(err, service.ErrInvalidReason),
+		errors.Is(err, service.ErrInvalidAssignee),
+		errors.Is(err, service.ErrInvalidModerationAction),
+		errors.Is(err, service.ErrInvalidSuspension),
+		errors.Is(err, service.ErrInvalidRole),
+		errors.Is(err, repository.ErrInvalidCursor):
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+}
+
+// RejectSuspended turns suspended users away from every route it guards,
+// including requests made with tokens issued before the suspension.
+func (h *ModerationHandler) RejectSuspended(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		until, err := h.moderationService.SuspendedUntil(c.Get("user_id").(string))
+		if errors.Is(err, service.ErrUserNotFound) {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"message": "token ไม่ถูกต้อง"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		}
+		if until != nil {
+			return c.JSON(http.StatusForbidden, map[string]interface{}{
+				"message":        service.ErrUserSuspended.Error(),
+				"suspendedUntil": until,
+			})
+		}
+		return next(c)
+	}
+}
+
+// CreateReport files a report about a post, comment, message or user.
+func (h *ModerationHandler) CreateReport(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	var req ReportRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "ข้อมูลไม่ถูกต้อง"})
+	}
+
+	report, err := h.moderationService.Report(userID, service.ReportInput{
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Reason:     req.Reason,
+	})
+	if err != nil {
+		return h.writeError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, report)
+}
+
+// GetMyWarnings lists the caller's warnings and suspensions. Neither the
+// moderator nor the report behind them is included.
+func (h *ModerationHandler) GetMyWarnings(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	warnings, err := h.moderationService.GetWarnings(userID)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+
+	items := make([]map[string]interface{}, 0, len(warnings))
+	for _, warning := range warnings {
+		items = append(items, map[string]interface{}{
+			"id":             warning.ID,
+			"action":         warning.Action,
+			"targetType":     warning.TargetType,
+			"targetId":       warning.TargetID,
+			"reason":         warning.Reason,
+			"suspendedUntil": warning.SuspendedUntil,
+			"createdAt":      warning.CreatedAt,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"warnings": items})
+}
+
+// GetQueue lists reports oldest first. ?status= takes a comma separated list
+// (default open,in_review); ?assignee=me|unassigned narrows it further.
+func (h *ModerationHandler) GetQueue(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	cursor, limit := pageParams(c)
+
+	filter := service.QueueFilter{Assignee: c.QueryParam("assignee")}
+	if status := c.QueryParam("status"); status != "" {
+		filter.Statuses = strings.Split(status, ",")
+	}
+
+	page, err := h.moderationService.GetQueue(userID, filter, cursor, limit)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusOK, pageResponse(page.Reports, page.NextCursor))
+}
+
+// GetReport returns a report with the current state of its target and the
+// actions taken on it.
+func (h *ModerationHandler) GetReport(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	detail, err := h.moderationService.GetReport(userID, c.Param("id"))
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	if detail.Actions == nil {
+		detail.Actions = []models.ModerationAction{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"report": detail.Report,
+		"target": map[string]interface{}{
+			"available": detail.Target.Available,
+			"content":   detail.Target.Content,
+			"hidden":    detail.Target.Hidden,
+		},
+		"actions": detail.Actions,
+	})
+}
+
+func (h *ModerationHandler) AssignReport(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	var req AssignRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "ข้อมูลไม่ถูกต้อง"})
+	}
+
+	report, err := h.moderationService.Assign(userID, c.Param("id"), req.AssigneeID)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// ActOnReport hides, deletes, warns, suspends or dismisses, and closes the
+// report.
+func (h *ModerationHandler) ActOnReport(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	var req ModerationActionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "ข้อมูลไม่ถูกต้อง"})
+	}
+
+	action, err := h.moderationService.Act(userID, c.Param("id"), service.ActionInput{
+		Action:         req.Action,
+		Reason:         req.Reason,
+		SuspendedUntil: req.SuspendedUntil,
+	})
+	if err != nil {
+		return h.writeError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, action)
+}
+
+// GetActions pages through the moderation log, newest first. ?userId= limits
+// it to actions against one user.
+func (h *ModerationHandler) GetActions(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	cursor, limit := pageParams(c)
+
+	page, err := h.moderationService.GetActions(userID, c.QueryParam("userId"), cursor, limit)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+	return c.JSON(http.StatusOK, pageResponse(page.Actions, page.NextCursor))
+}
+
+// SetRole lets admins make users moderators or admins, or take that away.
+func (h *ModerationHandler) SetRole(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+
+	var req RoleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "ข้อมูลไม่ถูกต้อง"})
+	}
+
+	user, err := h.moderationService.SetRole(userID, c.Param("id"), req.Role)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
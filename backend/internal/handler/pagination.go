@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageParams reads ?cursor= and ?limit= for cursor paginated listings.
+func pageParams(c echo.Context) (cursor string, limit int) {
+	limit = defaultPageSize
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 {
+			limit = l
+		}
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	return c.QueryParam("cursor"), limit
+}
+
+// pageResponse is the envelope shared by every paginated listing. Clients
+// pass nextCursor back as ?cursor= until hasMore is false.
+func pageResponse[T any](items []T, nextCursor string) map[string]interface{} {
+	if items == nil {
+		items = []T{}
+	}
+	var cursor interface{}
+	if nextCursor != "" {
+		cursor = nextCursor
+	}
+	return map[string]interface{}{
+		"items":      items,
+		"nextCursor": cursor,
+		"hasMore":    nextCursor != "",
+	}
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/service"
+)
+
+type PassageHandler struct {
+	passageService service.PassageService
+}
+
+func NewPassageHandler(passageService service.PassageService) *PassageHandler {
+	return &PassageHandler{passageService: passageService}
+}
+
+func passageResponse(passage *models.Passage) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           passage.ID,
+		"text":         passage.Text,
+		"source":       passage.Source,
+		"language":     passage.Language,
+		"lengthBucket": passage.LengthBucket,
+		"charCount":    passage.CharCount,
+	}
+}
+
+func (h *PassageHandler) GetRandomPassage(c echo.Context) error {
+	language := c.QueryParam("language")
+	length := c.QueryParam("length")
+
+	passage, err := h.passageService.GetRandomPassage(language, length)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, passageResponse(passage))
+}
+
+func (h *PassageHandler) GetPassage(c echo.Context) error {
+	passage, err := h.passageService.GetPassage(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, passageResponse(passage))
+}
+
+type SubmitPassageRunRequest struct {
+	TypedText string `json:"typedText" validate:"required"`
+	ElapsedMs int64  `json:"elapsedMs" validate:"required,min=1"`
+}
+
+func (h *PassageHandler) SubmitRun(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	passageID := c.Param("id")
+
+	var req SubmitPassageRunRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "ข้อมูลไม่ถูกต้อง"})
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	score, err := h.passageService.SubmitRun(userID, passageID, service.PassageRun{
+		TypedText: req.TypedText,
+		Elapsed:   time.Duration(req.ElapsedMs) * time.Millisecond,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrPassageNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"id":         score.ID,
+		"passageId":  passageID,
+		"characters": score.Score,
+		"wpm":        score.WPM,
+		"accuracy":   score.Accuracy,
+		"createdAt":  score.CreatedAt,
+	})
+}
+
+func (h *PassageHandler) GetPassageLeaderboard(c echo.Context) error {
+	passageID := c.Param("id")
+	limit := 10
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	scores, err := h.passageService.GetPassageLeaderboard(passageID, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrPassageNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	var response []map[string]interface{}
+	for i, score := range scores {
+		response = append(response, map[string]interface{}{
+			"rank":       i + 1,
+			"id":         score.ID,
+			"userId":     score.UserID,
+			"userName":   score.User.Name,
+			"characters": score.Score,
+			"wpm":        score.WPM,
+			"accuracy":   score.Accuracy,
+			"createdAt":  score.CreatedAt,
+		})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
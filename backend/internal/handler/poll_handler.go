@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"typinggame-api/internal/service"
+)
+
+type PollHandler struct {
+	pollService service.PollService
+}
+
+func NewPollHandler(pollService service.PollService) *PollHandler {
+	return &PollHandler{pollService: pollService}
+}
+
+type VoteRequest struct {
+	OptionIDs []string `json:"optionIds"`
+}
+
+// Vote casts the caller's vote and returns the poll with its tallies.
+func (h *PollHandler) Vote(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	postID := c.Param("id")
+
+	var req VoteRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "ข้อมูลไม่ถูกต้อง"})
+	}
+
+	poll, err := h.pollService.Vote(postID, userID, req.OptionIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPostNotFound), errors.Is(err, service.ErrPollNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+		case errors.Is(err, service.ErrPollClosed), errors.Is(err, service.ErrAlreadyVoted):
+			return c.JSON(http.StatusConflict, map[string]string{"message": err.Error()})
+		case errors.Is(err, service.ErrInvalidPollVote):
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, pollResponse(poll))
+}
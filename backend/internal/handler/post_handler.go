@@ -1,23 +1,50 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
 	"typinggame-api/internal/service"
+	"typinggame-api/internal/textdiff"
 )
 
 type PostHandler struct {
-	postService service.PostService
+	postService       service.PostService
+	attachmentService service.AttachmentService
+	renderer          *postRenderer
 }
 
-func NewPostHandler(postService service.PostService) *PostHandler {
-	return &PostHandler{postService: postService}
+func NewPostHandler(postService service.PostService, attachmentService service.AttachmentService, bookmarkService service.BookmarkService, pollService service.PollService) *PostHandler {
+	return &PostHandler{
+		postService:       postService,
+		attachmentService: attachmentService,
+		renderer:          newPostRenderer(postService, attachmentService, bookmarkService, pollService),
+	}
 }
 
+// CreatePostRequest is sent as JSON, or as multipart/form-data when images
+// are attached under the "attachments" field. Status draft keeps the post
+// private to its author; scheduled publishes it at publishAt (RFC 3339).
+// Polls can only be sent as JSON.
 type CreatePostRequest struct {
-	Content string `json:"content" validate:"required"`
+	Content    string       `json:"content" form:"content"`
+	Visibility string       `json:"visibility" form:"visibility" validate:"omitempty,oneof=public friends only_me"`
+	Status     string       `json:"status" form:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt  *time.Time   `json:"publishAt" form:"publishAt"`
+	Poll       *PollRequest `json:"poll"`
+}
+
+type PollRequest struct {
+	Options               []string   `json:"options"`
+	MultipleChoice        bool       `json:"multipleChoice"`
+	HideResultsUntilVoted bool       `json:"hideResultsUntilVoted"`
+	ClosesAt              *time.Time `json:"closesAt"`
 }
 
 func (h *PostHandler) CreatePost(c echo.Context) error {
@@ -32,180 +59,302 @@ func (h *PostHandler) CreatePost(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
-	post, err := h.postService.CreatePost(req.Content, userID)
+	uploads, err := readAttachments(c, h.attachmentService.Limits())
+	if err != nil {
+		return h.writeError(c, err)
+	}
+
+	input := service.CreatePostInput{
+		Content:     req.Content,
+		Visibility:  req.Visibility,
+		Status:      req.Status,
+		PublishAt:   req.PublishAt,
+		Attachments: uploads,
+	}
+	if req.Poll != nil {
+		input.Poll = &service.PollInput{
+			Options:               req.Poll.Options,
+			MultipleChoice:        req.Poll.MultipleChoice,
+			HideResultsUntilVoted: req.Poll.HideResultsUntilVoted,
+			ClosesAt:              req.Poll.ClosesAt,
+		}
+	}
+
+	post, err := h.postService.CreatePost(userID, input)
+	if err != nil {
+		return h.writeError(c, err)
+	}
+
+	return h.writePost(c, heldStatus(http.StatusCreated, post.Hidden), post, userID)
+}
+
+// writePage sends one page of a feed in the shared pagination envelope.
+func (h *PostHandler) writePage(c echo.Context, page *service.PostPage, viewerID string) error {
+	items, err := h.renderer.items(page.Posts, viewerID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+	return writeTagged(c, http.StatusOK, pageResponse(items, page.NextCursor), 0)
+}
+
+// writePost sends a single post in the same shape as feed items.
+func (h *PostHandler) writePost(c echo.Context, status int, post *models.Post, viewerID string) error {
+	items, err := h.renderer.items([]models.Post{*post}, viewerID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
+	return writeTagged(c, status, items[0], post.Version)
+}
 
-	// Format response
-	response := map[string]interface{}{
-		"id":        post.ID,
-		"content":   post.Content,
-		"author":    post.AuthorID,
-		"authorName": post.Author.Name,
-		"createdAt": post.CreatedAt,
-		"updatedAt": post.UpdatedAt,
-		"likes":     post.Likes,
-		"comments":  post.Comments,
+// writeStale answers an edit made against an old version with 412 and the
+// post as it is now, tagged with its current ETag.
+func (h *PostHandler) writeStale(c echo.Context, postID, viewerID string, err error) error {
+	post, findErr := h.postService.GetPostByID(postID, viewerID)
+	if findErr != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": findErr.Error()})
+	}
+	items, findErr := h.renderer.items([]models.Post{*post}, viewerID)
+	if findErr != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": findErr.Error()})
+	}
+	data, findErr := json.Marshal(items[0])
+	if findErr != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": findErr.Error()})
 	}
+	c.Response().Header().Set("ETag", etag(post.Version, data))
+	return c.JSON(http.StatusPreconditionFailed, map[string]interface{}{
+		"message": err.Error(),
+		"current": json.RawMessage(data),
+	})
+}
 
-	return c.JSON(http.StatusCreated, response)
+// heldStatus answers 202 Accepted instead of status for content that was
+// saved hidden, such as content the content filter held for review.
+func heldStatus(status int, hidden bool) int {
+	if hidden {
+		return http.StatusAccepted
+	}
+	return status
 }
 
+// GetAllPosts lists posts newest first, or ranked with ?sort=top|hot over
+// ?window=day|week.
 func (h *PostHandler) GetAllPosts(c echo.Context) error {
-	posts, err := h.postService.GetAllPosts()
+	userID := c.Get("user_id").(string)
+	cursor, limit := pageParams(c)
+
+	var page *service.PostPage
+	var err error
+	if sort := c.QueryParam("sort"); sort == "" || sort == "new" {
+		page, err = h.postService.GetAllPosts(userID, cursor, limit)
+	} else {
+		page, err = h.postService.GetRankedPosts(userID, sort, c.QueryParam("window"), cursor, limit)
+	}
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return h.pageError(c, err)
 	}
 
-	// Format response
-	var response []map[string]interface{}
-	for _, post := range posts {
-		// Get reactions count for each post
-		reactions, _ := h.postService.GetReactionsCount(post.ID)
-		
-		response = append(response, map[string]interface{}{
-			"id":        post.ID,
-			"content":   post.Content,
-			"author":    post.AuthorID,
-			"authorName": post.Author.Name,
-			"createdAt": post.CreatedAt,
-			"likes":     post.Likes,
-			"comments":  post.Comments,
-			"reactions": reactions,
-		})
-	}
-
-	return c.JSON(http.StatusOK, response)
+	return h.writePage(c, page, userID)
 }
 
-type ReactRequest struct {
-	Reaction string `json:"reaction" validate:"required,oneof=like love haha wow sad angry"`
+// GetDrafts returns the caller's drafts and scheduled posts.
+func (h *PostHandler) GetDrafts(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	cursor, limit := pageParams(c)
+
+	page, err := h.postService.GetDrafts(userID, cursor, limit)
+	if err != nil {
+		return h.pageError(c, err)
+	}
+
+	return h.writePage(c, page, userID)
+}
+
+// GetHomeFeed returns posts by the caller and their accepted friends.
+func (h *PostHandler) GetHomeFeed(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	cursor, limit := pageParams(c)
+
+	page, err := h.postService.GetHomeFeed(userID, cursor, limit)
+	if err != nil {
+		return h.pageError(c, err)
+	}
+
+	return h.writePage(c, page, userID)
+}
+
+func (h *PostHandler) pageError(c echo.Context, err error) error {
+	if errors.Is(err, repository.ErrInvalidCursor) ||
+		errors.Is(err, service.ErrInvalidSort) ||
+		errors.Is(err, service.ErrInvalidWindow) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+	if errors.Is(err, service.ErrUserNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 }
 
 func (h *PostHandler) ReactToPost(c echo.Context) error {
 	userID := c.Get("user_id").(string)
 	postID := c.Param("id")
 
-	var req ReactRequest
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"message": "ข้อมูลไม่ถูกต้อง"})
+	reaction, err := bindReaction(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
-	if err := validator.New().Struct(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"message": "reaction ไม่ถูกต้อง"})
+	if err := h.postService.ReactToPost(postID, userID, reaction); err != nil {
+		return reactionError(c, err, service.ErrPostNotFound)
 	}
 
-	if err := h.postService.ReactToPost(postID, userID, req.Reaction); err != nil {
+	// Get updated reactions count
+	summary, err := h.postService.GetReactionSummary(postID, userID)
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
 
-	// Get updated reactions count
-	counts, _ := h.postService.GetReactionsCount(postID)
-	userReaction, _ := h.postService.GetUserReaction(postID, userID)
-
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message":      "สำเร็จ",
-		"reactions":    counts,
-		"userReaction": userReaction,
-	})
+	return c.JSON(http.StatusOK, reactionFields(map[string]interface{}{"message": "สำเร็จ"}, summary))
 }
 
 func (h *PostHandler) GetReactions(c echo.Context) error {
 	userID := c.Get("user_id").(string)
 	postID := c.Param("id")
 
-	counts, err := h.postService.GetReactionsCount(postID)
+	if _, err := h.postService.GetPostByID(postID, userID); err != nil {
+		if errors.Is(err, service.ErrPostNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	summary, err := h.postService.GetReactionSummary(postID, userID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
 
-	userReaction, _ := h.postService.GetUserReaction(postID, userID)
+	return c.JSON(http.StatusOK, reactionFields(map[string]interface{}{}, summary))
+}
+
+// GetReactors pages through who reacted to a post, optionally with one
+// ?reaction= only.
+func (h *PostHandler) GetReactors(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	cursor, limit := pageParams(c)
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"reactions":    counts,
-		"userReaction": userReaction,
-	})
+	page, err := h.postService.GetReactors(c.Param("id"), userID, c.QueryParam("reaction"), cursor, limit)
+	if err != nil {
+		return reactionError(c, err, service.ErrPostNotFound)
+	}
+	return c.JSON(http.StatusOK, reactorPage(page))
+}
+
+// GetHashtagPosts lists posts tagged with :tag, which may include the #.
+func (h *PostHandler) GetHashtagPosts(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	cursor, limit := pageParams(c)
+
+	page, err := h.postService.GetHashtagFeed(c.Param("tag"), userID, cursor, limit)
+	if err != nil {
+		return h.pageError(c, err)
+	}
+
+	return h.writePage(c, page, userID)
 }
 
 func (h *PostHandler) GetMyPosts(c echo.Context) error {
 	userID := c.Get("user_id").(string)
+	cursor, limit := pageParams(c)
 
-	posts, err := h.postService.GetPostsByUserID(userID)
+	page, err := h.postService.GetPostsByUserID(userID, userID, cursor, limit)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return h.pageError(c, err)
 	}
 
-	// Format response
-	var response []map[string]interface{}
-	for _, post := range posts {
-		reactions, _ := h.postService.GetReactionsCount(post.ID)
-		
-		response = append(response, map[string]interface{}{
-			"id":        post.ID,
-			"content":   post.Content,
-			"author":    post.AuthorID,
-			"authorName": post.Author.Name,
-			"createdAt": post.CreatedAt,
-			"updatedAt": post.UpdatedAt,
-			"likes":     post.Likes,
-			"comments":  post.Comments,
-			"reactions": reactions,
-		})
-	}
-
-	return c.JSON(http.StatusOK, response)
+	return h.writePage(c, page, userID)
 }
 
 func (h *PostHandler) GetUserPosts(c echo.Context) error {
+	viewerID := c.Get("user_id").(string)
 	userID := c.Param("id")
+	cursor, limit := pageParams(c)
 
-	posts, err := h.postService.GetPostsByUserID(userID)
+	page, err := h.postService.GetPostsByUserID(userID, viewerID, cursor, limit)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return h.pageError(c, err)
 	}
 
-	// Format response
-	var response []map[string]interface{}
-	for _, post := range posts {
-		reactions, _ := h.postService.GetReactionsCount(post.ID)
-		
-		response = append(response, map[string]interface{}{
-			"id":        post.ID,
-			"content":   post.Content,
-			"author":    post.AuthorID,
-			"authorName": post.Author.Name,
-			"createdAt": post.CreatedAt,
-			"updatedAt": post.UpdatedAt,
-			"likes":     post.Likes,
-			"comments":  post.Comments,
-			"reactions": reactions,
-		})
-	}
-
-	return c.JSON(http.StatusOK, response)
+	return h.writePage(c, page, viewerID)
 }
 
 func (h *PostHandler) GetEditHistory(c echo.Context) error {
+	userID := c.Get("user_id").(string)
 	postID := c.Param("id")
 
-	histories, err := h.postService.GetEditHistory(postID)
+	history, err := h.postService.GetEditHistory(postID, userID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return editHistoryError(c, err)
 	}
 
-	// Format response
-	var response []map[string]interface{}
-	for _, history := range histories {
-		response = append(response, map[string]interface{}{
-			"id":        history.ID,
-			"oldContent": history.OldContent,
-			"newContent": history.NewContent,
-			"createdAt": history.CreatedAt,
-		})
+	return c.JSON(http.StatusOK, editHistoryResponse(history))
+}
+
+func editHistoryError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrPostNotFound), errors.Is(err, service.ErrCommentNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+	case errors.Is(err, service.ErrEditHistoryHidden):
+		return c.JSON(http.StatusForbidden, map[string]string{"message": err.Error()})
 	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+}
 
-	return c.JSON(http.StatusOK, response)
+// editHistoryResponse renders history entries newest first. Each carries
+// the diff from its old to its new content, split into words, or into
+// character clusters for Thai and Japanese. When the author only shares
+// timestamps the content and diff are left out.
+func editHistoryResponse(history *service.EditHistory) []map[string]interface{} {
+	response := make([]map[string]interface{}, 0, len(history.Entries))
+	for _, entry := range history.Entries {
+		item := map[string]interface{}{
+			"id":        entry.ID,
+			"version":   entry.Version,
+			"editorId":  entry.EditorID,
+			"revertOf":  entry.RevertOf,
+			"createdAt": entry.CreatedAt,
+		}
+		if !history.TimestampsOnly {
+			item["oldContent"] = entry.OldContent
+			item["newContent"] = entry.NewContent
+			item["diff"] = textdiff.Diff(entry.OldContent, entry.NewContent)
+		}
+		response = append(response, item)
+	}
+	return response
+}
+
+// RevertPost restores the content the post had before the edit
+// :historyId.
+func (h *PostHandler) RevertPost(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	postID := c.Param("id")
+
+	post, err := h.postService.RevertPost(postID, c.Param("historyId"), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrHistoryNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+		}
+		if errors.Is(err, service.ErrStaleVersion) {
+			return h.writeStale(c, postID, userID, err)
+		}
+		return h.writeError(c, err)
+	}
+
+	if post == nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"message": "ไม่มีสิทธิ์แก้ไขโพสต์นี้"})
+	}
+
+	return h.writePost(c, heldStatus(http.StatusOK, post.Hidden), post, userID)
 }
 
 func (h *PostHandler) DeletePost(c echo.Context) error {
@@ -219,8 +368,73 @@ func (h *PostHandler) DeletePost(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]string{"message": "ลบโพสต์สำเร็จ"})
 }
 
+func (h *PostHandler) RestorePost(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	postID := c.Param("id")
+
+	post, err := h.postService.RestorePost(postID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTrashItemNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+		case errors.Is(err, service.ErrAlreadyReposted):
+			return c.JSON(http.StatusConflict, map[string]string{"message": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return h.writePost(c, http.StatusOK, post, userID)
+}
+
+// RepostRequest shares a post. Leaving content empty makes a plain repost;
+// with content it becomes a quote post.
+type RepostRequest struct {
+	Content    string `json:"content"`
+	Visibility string `json:"visibility" validate:"omitempty,oneof=public friends only_me"`
+}
+
+func (h *PostHandler) Repost(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	postID := c.Param("id")
+
+	var req RepostRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": "ข้อมูลไม่ถูกต้อง"})
+	}
+
+	if err := validator.New().Struct(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
+	post, err := h.postService.Repost(postID, userID, service.RepostInput{
+		Content:    req.Content,
+		Visibility: req.Visibility,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPostNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+		case errors.Is(err, service.ErrAlreadyReposted):
+			return c.JSON(http.StatusConflict, map[string]string{"message": err.Error()})
+		case errors.Is(err, service.ErrCannotRepost):
+			return c.JSON(http.StatusForbidden, map[string]string{"message": err.Error()})
+		case errors.Is(err, service.ErrContentRejected):
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	return h.writePost(c, heldStatus(http.StatusCreated, post.Hidden), post, userID)
+}
+
+// UpdatePostRequest replaces the content. New images can be attached the same
+// way as on create; removeAttachmentIds drops existing ones.
 type UpdatePostRequest struct {
-	Content string `json:"content" validate:"required"`
+	Content             string     `json:"content" form:"content"`
+	Visibility          string     `json:"visibility" form:"visibility" validate:"omitempty,oneof=public friends only_me"`
+	Status              string     `json:"status" form:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt           *time.Time `json:"publishAt" form:"publishAt"`
+	RemoveAttachmentIDs []string   `json:"removeAttachmentIds" form:"removeAttachmentIds"`
 }
 
 func (h *PostHandler) UpdatePost(c echo.Context) error {
@@ -236,58 +450,46 @@ func (h *PostHandler) UpdatePost(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 	}
 
-	post, err := h.postService.UpdatePost(postID, userID, req.Content)
+	uploads, err := readAttachments(c, h.attachmentService.Limits())
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return h.writeError(c, err)
 	}
 
-	if post == nil {
-		return c.JSON(http.StatusForbidden, map[string]string{"message": "ไม่มีสิทธิ์แก้ไขโพสต์นี้"})
+	post, err := h.postService.UpdatePost(postID, userID, service.UpdatePostInput{
+		Content:             req.Content,
+		Visibility:          req.Visibility,
+		Status:              req.Status,
+		PublishAt:           req.PublishAt,
+		Attachments:         uploads,
+		RemoveAttachmentIDs: req.RemoveAttachmentIDs,
+		Version:             ifMatchVersion(c),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrStaleVersion) {
+			return h.writeStale(c, postID, userID, err)
+		}
+		return h.writeError(c, err)
 	}
 
-	// Get reactions count
-	reactions, _ := h.postService.GetReactionsCount(post.ID)
-
-	// Format response
-	response := map[string]interface{}{
-		"id":        post.ID,
-		"content":   post.Content,
-		"author":    post.AuthorID,
-		"authorName": post.Author.Name,
-		"createdAt": post.CreatedAt,
-		"updatedAt": post.UpdatedAt,
-		"likes":     post.Likes,
-		"comments":  post.Comments,
-		"reactions": reactions,
+	if post == nil {
+		return c.JSON(http.StatusForbidden, map[string]string{"message": "ไม่มีสิทธิ์แก้ไขโพสต์นี้"})
 	}
 
-	return c.JSON(http.StatusOK, response)
+	return h.writePost(c, heldStatus(http.StatusOK, post.Hidden), post, userID)
 }
 
 func (h *PostHandler) GetPost(c echo.Context) error {
+	userID := c.Get("user_id").(string)
 	postID := c.Param("id")
 
-	post, err := h.postService.GetPostByID(postID)
+	post, err := h.postService.GetPostByID(postID, userID)
 	if err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{"message": "ไม่พบโพสต์"})
-	}
-
-	// Get reactions count
-	reactions, _ := h.postService.GetReactionsCount(post.ID)
-
-	// Format response
-	response := map[string]interface{}{
-		"id":        post.ID,
-		"content":   post.Content,
-		"author":    post.AuthorID,
-		"authorName": post.Author.Name,
-		"createdAt": post.CreatedAt,
-		"updatedAt": post.UpdatedAt,
-		"likes":     post.Likes,
-		"comments":  post.Comments,
-		"reactions": reactions,
+		if errors.Is(err, service.ErrPostNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"message": "ไม่พบโพสต์"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
 	}
 
-	return c.JSON(http.StatusOK, response)
+	return h.writePost(c, http.StatusOK, post, userID)
 }
 
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+	"typinggame-api/internal/service"
+)
+
+// postRenderer builds the post shape shared by every endpoint that returns
+// posts, for one viewer.
+type postRenderer struct {
+	postService       service.PostService
+	attachmentService service.AttachmentService
+	bookmarkService   service.BookmarkService
+	pollService       service.PollService
+}
+
+func newPostRenderer(postService service.PostService, attachmentService service.AttachmentService, bookmarkService service.BookmarkService, pollService service.PollService) *postRenderer {
+	return &postRenderer{
+		postService:       postService,
+		attachmentService: attachmentService,
+		bookmarkService:   bookmarkService,
+		pollService:       pollService,
+	}
+}
+
+// viewerState is everything about a list of posts that depends on who is
+// looking, keyed by post ID.
+type viewerState struct {
+	summaries  map[string]*repository.ReactionSummary
+	bookmarked map[string]bool
+	polls      map[string]*service.PollView
+}
+
+// items renders posts for viewerID. Reactions, bookmarks and polls of the
+// posts and of the posts they share are loaded once for the whole list. A
+// shared post is embedded under repostOf, or replaced by an {id, unavailable}
+// placeholder once it is deleted or hidden from the viewer.
+func (r *postRenderer) items(posts []models.Post, viewerID string) ([]map[string]interface{}, error) {
+	originals, err := r.postService.GetRepostOriginals(posts, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	withOriginals := append(make([]models.Post, 0, len(posts)+len(originals)), posts...)
+	for _, original := range originals {
+		withOriginals = append(withOriginals, *original)
+	}
+
+	var state viewerState
+	if state.summaries, err = r.postService.GetReactionSummaries(withOriginals, viewerID); err != nil {
+		return nil, err
+	}
+	if state.bookmarked, err = r.bookmarkService.GetBookmarkedPostIDs(viewerID, withOriginals); err != nil {
+		return nil, err
+	}
+	if state.polls, err = r.pollService.GetPolls(viewerID, withOriginals); err != nil {
+		return nil, err
+	}
+
+	items := make([]map[string]interface{}, 0, len(posts))
+	for i := range posts {
+		item := r.response(&posts[i], &state)
+		if id := posts[i].RepostOfID; id != nil {
+			if original, ok := originals[*id]; ok {
+				item["repostOf"] = r.response(original, &state)
+			} else {
+				item["repostOf"] = map[string]interface{}{"id": *id, "unavailable": true}
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// response is the shape of one post.
+func (r *postRenderer) response(post *models.Post, state *viewerState) map[string]interface{} {
+	summary := state.summaries[post.ID]
+	if summary == nil {
+		summary = &repository.ReactionSummary{Counts: map[string]int64{}}
+	}
+
+	attachments := make([]map[string]interface{}, 0, len(post.Attachments))
+	for _, attachment := range post.Attachments {
+		attachments = append(attachments, map[string]interface{}{
+			"id":           attachment.ID,
+			"url":          r.attachmentService.URL(attachment.StorageKey),
+			"thumbnailUrl": r.attachmentService.URL(attachment.ThumbnailKey),
+			"contentType":  attachment.ContentType,
+			"size":         attachment.Size,
+			"width":        attachment.Width,
+			"height":       attachment.Height,
+		})
+	}
+
+	var poll map[string]interface{}
+	if view := state.polls[post.ID]; view != nil {
+		poll = pollResponse(view)
+	}
+
+	return map[string]interface{}{
+		"id":           post.ID,
+		"content":      post.Content,
+		"author":       post.AuthorID,
+		"authorName":   post.Author.Name,
+		"visibility":   post.Visibility,
+		"status":       post.Status,
+		"publishAt":    post.PublishAt,
+		"hidden":       post.Hidden,
+		"createdAt":    post.CreatedAt,
+		"updatedAt":    post.UpdatedAt,
+		"version":      post.Version,
+		"likes":        summary.Total,
+		"comments":     post.Comments,
+		"reposts":      post.Reposts,
+		"repostOfId":   post.RepostOfID,
+		"repostOf":     nil,
+		"reactions":    summary.Counts,
+		"userReaction": summary.UserReaction,
+		"isBookmarked": state.bookmarked[post.ID],
+		"attachments":  attachments,
+		"poll":         poll,
+		"entities":     entitiesResponse(post.Entities),
+	}
+}
+
+// pollResponse is the shape of a poll. Tallies are null while the results
+// are hidden from the viewer.
+func pollResponse(view *service.PollView) map[string]interface{} {
+	options := make([]map[string]interface{}, 0, len(view.Poll.Options))
+	for _, option := range view.Poll.Options {
+		var votes interface{}
+		if view.ResultsVisible {
+			votes = option.Votes
+		}
+		options = append(options, map[string]interface{}{
+			"id":    option.ID,
+			"text":  option.Text,
+			"votes": votes,
+		})
+	}
+
+	var voters interface{}
+	if view.ResultsVisible {
+		voters = view.Poll.Voters
+	}
+
+	return map[string]interface{}{
+		"id":                    view.Poll.ID,
+		"multipleChoice":        view.Poll.MultipleChoice,
+		"hideResultsUntilVoted": view.Poll.HideResultsUntilVoted,
+		"closesAt":              view.Poll.ClosesAt,
+		"closed":                view.Closed,
+		"voters":                voters,
+		"options":               options,
+		"userVotes":             view.UserVotes,
+		"resultsVisible":        view.ResultsVisible,
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"typinggame-api/internal/repository"
+	"typinggame-api/internal/service"
+)
+
+type ReactionHandler struct {
+	reactionService service.ReactionService
+}
+
+func NewReactionHandler(reactionService service.ReactionService) *ReactionHandler {
+	return &ReactionHandler{reactionService: reactionService}
+}
+
+// GetReactions lists the reactions clients can offer. With allowEmoji any
+// single emoji is accepted as well.
+func (h *ReactionHandler) GetReactions(c echo.Context) error {
+	registry := h.reactionService.Registry()
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"reactions":  registry.Names(),
+		"allowEmoji": registry.AllowsEmoji(),
+	})
+}
+
+// ReactRequest is the body of every POST .../reactions. Whether the reaction
+// is one the server accepts is up to the reaction registry.
+type ReactRequest struct {
+	Reaction string `json:"reaction" validate:"required,max=64"`
+}
+
+// bindReaction reads a ReactRequest. Its errors are fit to return to the
+// client with a 400.
+func bindReaction(c echo.Context) (string, error) {
+	var req ReactRequest
+	if err := c.Bind(&req); err != nil {
+		return "", errors.New("ข้อมูลไม่ถูกต้อง")
+	}
+	if err := validator.New().Struct(&req); err != nil {
+		return "", service.ErrInvalidReaction
+	}
+	return req.Reaction, nil
+}
+
+// reactionFields adds a target's reaction counts and the viewer's own
+// reaction to its response. A nil summary counts as no reactions.
+func reactionFields(response map[string]interface{}, summary *repository.ReactionSummary) map[string]interface{} {
+	if summary == nil {
+		summary = &repository.ReactionSummary{Counts: map[string]int64{}}
+	}
+	response["reactions"] = summary.Counts
+	response["userReaction"] = summary.UserReaction
+	return response
+}
+
+// reactorPage renders who reacted with what, most recent first.
+func reactorPage(page *service.ReactorPage) map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(page.Reactions))
+	for _, reaction := range page.Reactions {
+		items = append(items, map[string]interface{}{
+			"userId":    reaction.UserID,
+			"name":      reaction.User.Name,
+			"reaction":  reaction.Reaction,
+			"reactedAt": reaction.UpdatedAt,
+		})
+	}
+	return pageResponse(items, page.NextCursor)
+}
+
+// reactionError maps the errors shared by every reaction endpoint. notFound
+// lists the errors that mean the target can't be seen.
+func reactionError(c echo.Context, err error, notFound ...error) error {
+	for _, target := range notFound {
+		if errors.Is(err, target) {
+			return c.JSON(http.StatusNotFound, map[string]string{"message": err.Error()})
+		}
+	}
+	switch {
+	case errors.Is(err, service.ErrInvalidReaction), errors.Is(err, repository.ErrInvalidCursor):
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+}
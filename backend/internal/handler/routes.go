@@ -6,45 +6,106 @@ import (
 )
 
 type Handlers struct {
-	AuthHandler    *AuthHandler
-	UserHandler    *UserHandler
-	PostHandler    *PostHandler
-	CommentHandler *CommentHandler
-	GameHandler    *GameHandler
-	FriendHandler  *FriendHandler
-	MessageHandler *MessageHandler
+	AuthHandler        *AuthHandler
+	UserHandler        *UserHandler
+	PostHandler        *PostHandler
+	CommentHandler     *CommentHandler
+	GameHandler        *GameHandler
+	FriendHandler      *FriendHandler
+	MessageHandler     *MessageHandler
+	CertificateHandler *CertificateHandler
+	PassageHandler     *PassageHandler
+	EntityHandler      *EntityHandler
+	SearchHandler      *SearchHandler
+	BookmarkHandler    *BookmarkHandler
+	PollHandler        *PollHandler
+	ModerationHandler  *ModerationHandler
+	TrashHandler       *TrashHandler
+	ReactionHandler    *ReactionHandler
 }
 
 func InitializeRoutes(e *echo.Echo, h *Handlers) {
 	api := e.Group("/api")
 	api.POST("/auth/register", h.AuthHandler.Register)
 	api.POST("/auth/login", h.AuthHandler.Login)
+	api.POST("/auth/guest", h.AuthHandler.StartGuestSession)
+	api.GET("/certificates/verify", h.CertificateHandler.VerifyCertificate)
 
-	protected := api.Group("", middleware.AuthMiddleware())
-	protected.GET("/user/me", h.UserHandler.GetMe)
+	// Game routes are open to guest sessions as well as registered users
+	game := api.Group("/game", middleware.GuestAuthMiddleware())
+	game.POST("/scores", h.GameHandler.SaveScore)
+	game.POST("/scores/batch", h.GameHandler.SyncScores)
+	game.GET("/leaderboard", h.GameHandler.GetTopScores)
+	game.GET("/leaderboard/stream", h.GameHandler.StreamLeaderboard)
+	game.GET("/leaderboard/:difficulty", h.GameHandler.GetTopScoresByDifficulty)
+	game.GET("/my-best", h.GameHandler.GetUserBestScore)
+	game.GET("/passages/random", h.PassageHandler.GetRandomPassage)
+	game.GET("/passages/:id", h.PassageHandler.GetPassage)
+	game.POST("/passages/:id/scores", h.PassageHandler.SubmitRun)
+	game.GET("/passages/:id/leaderboard", h.PassageHandler.GetPassageLeaderboard)
+
+	// Suspended users can still see their own account and why they were
+	// suspended
+	account := api.Group("", middleware.AuthMiddleware())
+	account.GET("/user/me", h.UserHandler.GetMe)
+	account.GET("/user/me/warnings", h.ModerationHandler.GetMyWarnings)
+
+	protected := api.Group("", middleware.AuthMiddleware(), h.ModerationHandler.RejectSuspended)
 	protected.PUT("/user/me", h.UserHandler.UpdateMe)
 	protected.GET("/user/:id", h.UserHandler.GetUser)
 	protected.GET("/posts", h.PostHandler.GetAllPosts)
+	protected.GET("/feed/home", h.PostHandler.GetHomeFeed)
 	protected.POST("/posts", h.PostHandler.CreatePost)
 	protected.GET("/posts/my", h.PostHandler.GetMyPosts)
+	protected.GET("/posts/drafts", h.PostHandler.GetDrafts)
 	protected.GET("/posts/user/:id", h.PostHandler.GetUserPosts)
 	protected.GET("/posts/:id", h.PostHandler.GetPost)
 	protected.PUT("/posts/:id", h.PostHandler.UpdatePost)
 	protected.GET("/posts/:id/history", h.PostHandler.GetEditHistory)
+	protected.POST("/posts/:id/revert/:historyId", h.PostHandler.RevertPost)
 	protected.DELETE("/posts/:id", h.PostHandler.DeletePost)
+	protected.POST("/posts/:id/restore", h.PostHandler.RestorePost)
+	protected.POST("/posts/:id/repost", h.PostHandler.Repost)
+	protected.POST("/posts/:id/bookmark", h.BookmarkHandler.AddBookmark)
+	protected.DELETE("/posts/:id/bookmark", h.BookmarkHandler.RemoveBookmark)
+	protected.POST("/posts/:id/poll/vote", h.PollHandler.Vote)
 	protected.GET("/posts/:id/comments", h.CommentHandler.GetComments)
 	protected.POST("/posts/:id/comments", h.CommentHandler.CreateComment)
 	protected.GET("/posts/:id/reactions", h.PostHandler.GetReactions)
 	protected.POST("/posts/:id/reactions", h.PostHandler.ReactToPost)
+	protected.GET("/posts/:id/reactions/users", h.PostHandler.GetReactors)
+	protected.GET("/comments/:commentId", h.CommentHandler.GetComment)
 	protected.PUT("/comments/:commentId", h.CommentHandler.UpdateComment)
 	protected.GET("/comments/:commentId/history", h.CommentHandler.GetEditHistory)
+	protected.GET("/comments/:commentId/replies", h.CommentHandler.GetReplies)
+	protected.POST("/comments/:commentId/replies", h.CommentHandler.ReplyToComment)
+	protected.GET("/comments/:commentId/reactions", h.CommentHandler.GetReactions)
+	protected.POST("/comments/:commentId/reactions", h.CommentHandler.ReactToComment)
+	protected.GET("/comments/:commentId/reactions/users", h.CommentHandler.GetReactors)
 	protected.DELETE("/comments/:commentId", h.CommentHandler.DeleteComment)
-	
-	protected.POST("/game/scores", h.GameHandler.SaveScore)
-	protected.GET("/game/leaderboard", h.GameHandler.GetTopScores)
-	protected.GET("/game/leaderboard/:difficulty", h.GameHandler.GetTopScoresByDifficulty)
-	protected.GET("/game/my-best", h.GameHandler.GetUserBestScore)
-	
+	protected.POST("/comments/:commentId/restore", h.CommentHandler.RestoreComment)
+	protected.GET("/trash", h.TrashHandler.GetTrash)
+	protected.GET("/reactions", h.ReactionHandler.GetReactions)
+	protected.GET("/hashtags/trending", h.EntityHandler.GetTrending)
+	protected.GET("/hashtags/:tag/posts", h.PostHandler.GetHashtagPosts)
+	protected.GET("/mentions", h.EntityHandler.GetMentions)
+	protected.GET("/search", h.SearchHandler.Search)
+	protected.GET("/bookmarks", h.BookmarkHandler.GetBookmarks)
+	protected.GET("/bookmarks/collections", h.BookmarkHandler.GetCollections)
+	protected.POST("/bookmarks/collections", h.BookmarkHandler.CreateCollection)
+	protected.PUT("/bookmarks/collections/:id", h.BookmarkHandler.RenameCollection)
+	protected.DELETE("/bookmarks/collections/:id", h.BookmarkHandler.DeleteCollection)
+
+	protected.POST("/reports", h.ModerationHandler.CreateReport)
+	protected.GET("/moderation/reports", h.ModerationHandler.GetQueue)
+	protected.GET("/moderation/reports/:id", h.ModerationHandler.GetReport)
+	protected.POST("/moderation/reports/:id/assign", h.ModerationHandler.AssignReport)
+	protected.POST("/moderation/reports/:id/actions", h.ModerationHandler.ActOnReport)
+	protected.GET("/moderation/actions", h.ModerationHandler.GetActions)
+	protected.PUT("/moderation/users/:id/role", h.ModerationHandler.SetRole)
+
+	protected.POST("/game/scores/:id/certificate", h.CertificateHandler.IssueCertificate)
+
 	protected.GET("/users/search", h.FriendHandler.SearchUsers)
 	protected.POST("/friends/:id", h.FriendHandler.SendFriendRequest)
 	protected.POST("/friends/accept/:id", h.FriendHandler.AcceptFriendRequest)
@@ -56,11 +117,13 @@ func InitializeRoutes(e *echo.Echo, h *Handlers) {
 	protected.POST("/friends/block/:id", h.FriendHandler.BlockUser)
 	protected.POST("/friends/unblock/:id", h.FriendHandler.UnblockUser)
 	protected.GET("/friends/blocked", h.FriendHandler.GetBlockedUsers)
-	
+
 	protected.GET("/conversations", h.MessageHandler.GetConversations)
 	protected.POST("/conversations/start/:id", h.MessageHandler.StartConversation)
 	protected.GET("/conversations/:id/messages", h.MessageHandler.GetMessages)
 	protected.POST("/conversations/:id/messages", h.MessageHandler.SendMessage)
 	protected.POST("/conversations/:id/read", h.MessageHandler.MarkAsRead)
+	protected.GET("/messages/:id/reactions", h.MessageHandler.GetReactions)
+	protected.POST("/messages/:id/reactions", h.MessageHandler.ReactToMessage)
+	protected.GET("/messages/:id/reactions/users", h.MessageHandler.GetReactors)
 }
-
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+	"typinggame-api/internal/search"
+	"typinggame-api/internal/service"
+)
+
+type SearchHandler struct {
+	searchService service.SearchService
+	renderer      *postRenderer
+}
+
+func NewSearchHandler(searchService service.SearchService, postService service.PostService, attachmentService service.AttachmentService, bookmarkService service.BookmarkService, pollService service.PollService) *SearchHandler {
+	return &SearchHandler{
+		searchService: searchService,
+		renderer:      newPostRenderer(postService, attachmentService, bookmarkService, pollService),
+	}
+}
+
+// Search finds posts, comments or users matching ?q=, best match first.
+// ?type= picks which (default posts). Every item carries its relevance
+// score and highlights: code point ranges of the matched text in the
+// content, or in the name for users.
+func (h *SearchHandler) Search(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	cursor, limit := pageParams(c)
+	query := strings.TrimSpace(c.QueryParam("q"))
+	docType := strings.TrimSuffix(c.QueryParam("type"), "s")
+	if docType == "" {
+		docType = string(search.Post)
+	}
+
+	page, err := h.searchService.Search(userID, query, docType, cursor, limit)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) ||
+			errors.Is(err, service.ErrInvalidSearchType) ||
+			errors.Is(err, service.ErrSearchQueryTooShort) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	var posts []models.Post
+	for _, result := range page.Results {
+		if result.Post != nil {
+			posts = append(posts, *result.Post)
+		}
+	}
+	postResults, err := h.renderer.items(posts, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	items := make([]map[string]interface{}, 0, len(page.Results))
+	for _, result := range page.Results {
+		var item map[string]interface{}
+		switch {
+		case result.Post != nil:
+			item, postResults = postResults[0], postResults[1:]
+		case result.Comment != nil:
+			item = commentResponse(result.Comment)
+		case result.User != nil:
+			item = map[string]interface{}{
+				"id":        result.User.ID,
+				"name":      result.User.Name,
+				"createdAt": result.User.CreatedAt,
+			}
+		}
+		item["score"] = result.Score
+		item["highlights"] = result.Highlights
+		items = append(items, item)
+	}
+
+	return c.JSON(http.StatusOK, pageResponse(items, page.NextCursor))
+}
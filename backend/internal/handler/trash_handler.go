@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"typinggame-api/internal/repository"
+	"typinggame-api/internal/service"
+)
+
+type TrashHandler struct {
+	trashService service.TrashService
+}
+
+func NewTrashHandler(trashService service.TrashService) *TrashHandler {
+	return &TrashHandler{trashService: trashService}
+}
+
+// GetTrash lists the caller's deleted posts and comments, most recently
+// deleted first, with when each one will be purged.
+func (h *TrashHandler) GetTrash(c echo.Context) error {
+	userID := c.Get("user_id").(string)
+	cursor, limit := pageParams(c)
+
+	page, err := h.trashService.GetTrash(userID, cursor, limit)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+
+	items := make([]map[string]interface{}, 0, len(page.Items))
+	for _, item := range page.Items {
+		var response map[string]interface{}
+		if item.Post != nil {
+			response = map[string]interface{}{
+				"type":       "post",
+				"id":         item.Post.ID,
+				"content":    item.Post.Content,
+				"visibility": item.Post.Visibility,
+				"status":     item.Post.Status,
+				"repostOfId": item.Post.RepostOfID,
+				"createdAt":  item.Post.CreatedAt,
+			}
+		} else {
+			response = map[string]interface{}{
+				"type":      "comment",
+				"id":        item.Comment.ID,
+				"content":   item.Comment.Content,
+				"postId":    item.Comment.PostID,
+				"createdAt": item.Comment.CreatedAt,
+			}
+		}
+		response["deletedAt"] = item.DeletedAt
+		response["purgeAt"] = item.PurgeAt
+		items = append(items, response)
+	}
+
+	return c.JSON(http.StatusOK, pageResponse(items, page.NextCursor))
+}
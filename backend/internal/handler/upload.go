@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"typinggame-api/internal/service"
+)
+
+// attachmentsField is the multipart field files are uploaded under.
+const attachmentsField = "attachments"
+
+func isMultipart(c echo.Context) bool {
+	return strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm)
+}
+
+// readAttachments loads the uploaded files of a multipart request. Too many
+// files, or files over the size limit, are refused from the form headers
+// before anything is read into memory.
+func readAttachments(c echo.Context, limits service.AttachmentLimits) ([]service.AttachmentUpload, error) {
+	if !isMultipart(c) {
+		return nil, nil
+	}
+	form, err := c.MultipartForm()
+	if errors.Is(err, echo.ErrStatusRequestEntityTooLarge) {
+		// A body without a declared length ran past the body limit
+		return nil, service.ErrAttachmentTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+	headers := form.File[attachmentsField]
+	if len(headers) > limits.MaxPerPost {
+		return nil, service.ErrTooManyAttachments
+	}
+
+	maxBytes := limits.MaxBytes
+	var uploads []service.AttachmentUpload
+	for _, header := range headers {
+		if header.Size > maxBytes {
+			return nil, service.ErrAttachmentTooLarge
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > maxBytes {
+			return nil, service.ErrAttachmentTooLarge
+		}
+		uploads = append(uploads, service.AttachmentUpload{Filename: header.Filename, Data: data})
+	}
+	return uploads, nil
+}
+
+// writeError sends a post create or update error with a matching status code.
+func (h *PostHandler) writeError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrAttachmentTooLarge):
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"message": err.Error()})
+	case errors.Is(err, service.ErrTooManyAttachments),
+		errors.Is(err, service.ErrUnsupportedAttachment),
+		errors.Is(err, service.ErrEmptyPost),
+		errors.Is(err, service.ErrInvalidPostStatus),
+		errors.Is(err, service.ErrInvalidPublishAt),
+		errors.Is(err, service.ErrInvalidPollOptions),
+		errors.Is(err, service.ErrInvalidPollCloseTime),
+		errors.Is(err, service.ErrContentRejected):
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	case errors.Is(err, service.ErrAlreadyPublished), errors.Is(err, service.ErrAlreadyReposted):
+		return c.JSON(http.StatusConflict, map[string]string{"message": err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+}
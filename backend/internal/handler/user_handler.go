@@ -3,16 +3,22 @@ package handler
 import (
 	"net/http"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"typinggame-api/internal/repository"
+	"typinggame-api/internal/service"
+	"go.uber.org/zap"
 )
 
 type UserHandler struct {
-	userRepo repository.UserRepository
+	userRepo      repository.UserRepository
+	policy        service.ContentPolicy
+	searchService service.SearchService
+	logger        *zap.Logger
 }
 
-func NewUserHandler(userRepo repository.UserRepository) *UserHandler {
-	return &UserHandler{userRepo: userRepo}
+func NewUserHandler(userRepo repository.UserRepository, policy service.ContentPolicy, searchService service.SearchService, logger *zap.Logger) *UserHandler {
+	return &UserHandler{userRepo: userRepo, policy: policy, searchService: searchService, logger: logger}
 }
 
 func (h *UserHandler) GetMe(c echo.Context) error {
@@ -27,7 +33,8 @@ func (h *UserHandler) GetMe(c echo.Context) error {
 }
 
 type UpdateUserRequest struct {
-	Name string `json:"name"`
+	Name                  string `json:"name"`
+	EditHistoryVisibility string `json:"editHistoryVisibility" validate:"omitempty,oneof=full timestamps hidden"`
 }
 
 func (h *UserHandler) UpdateMe(c echo.Context) error {
@@ -43,20 +50,41 @@ func (h *UserHandler) UpdateMe(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "ข้อมูลไม่ถูกต้อง"})
 	}
 
+	if err := validator.New().Struct(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
+	}
+
 	if req.Name != "" {
 		user.Name = req.Name
 	}
+	if req.EditHistoryVisibility != "" {
+		user.EditHistoryVisibility = req.EditHistoryVisibility
+	}
 
 	if err := h.userRepo.Update(user); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "เกิดข้อผิดพลาดในการอัปเดต"})
 	}
 
+	if err := h.searchService.IndexUser(user); err != nil {
+		h.logger.Error("Failed to index user for search", zap.String("user_id", user.ID), zap.Error(err))
+	}
+
 	return c.JSON(http.StatusOK, user)
 }
 
 func (h *UserHandler) GetUser(c echo.Context) error {
+	viewerID := c.Get("user_id").(string)
 	userID := c.Param("id")
 
+	// Blocked users look the same as users that don't exist
+	visible, err := h.policy.CanViewUser(viewerID, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+	}
+	if !visible {
+		return c.JSON(http.StatusNotFound, map[string]string{"message": "ไม่พบผู้ใช้"})
+	}
+
 	user, err := h.userRepo.FindByID(userID)
 	if err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"message": "ไม่พบผู้ใช้"})
@@ -0,0 +1,105 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// exifSegment builds an APP1 segment holding a TIFF header whose first IFD
+// has one orientation entry.
+func exifSegment(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8) // first IFD right after the header
+	order.PutUint16(tiff[8:], 1) // one entry
+	order.PutUint16(tiff[10:], exifOrientationTag)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1) // one value
+	order.PutUint16(tiff[18:], orientation)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// encodeJPEG encodes a w×h image whose top left pixel is red, with the
+// given segments inserted right after the start of image marker.
+func encodeJPEG(t *testing.T, w, h int, segments ...[]byte) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	out := append([]byte{}, data[:2]...)
+	for _, segment := range segments {
+		out = append(out, segment...)
+	}
+	return append(out, data[2:]...)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	comment := []byte{0xFF, 0xFE, 0x00, 0x07, 'h', 'e', 'l', 'l', 'o'}
+	xmp := append([]byte{0xFF, 0xE1, 0x00, 0x1F}, []byte("http://ns.adobe.com/xap/1.0/\x00")...)
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no metadata", encodeJPEG(t, 2, 2), 1},
+		{"big endian", encodeJPEG(t, 2, 2, exifSegment(binary.BigEndian, 6)), 6},
+		{"little endian", encodeJPEG(t, 2, 2, exifSegment(binary.LittleEndian, 8)), 8},
+		{"after other segments", encodeJPEG(t, 2, 2, comment, xmp, exifSegment(binary.BigEndian, 3)), 3},
+		{"out of range value", encodeJPEG(t, 2, 2, exifSegment(binary.BigEndian, 9)), 1},
+		{"truncated segment", encodeJPEG(t, 2, 2, exifSegment(binary.BigEndian, 6)[:20]), 1},
+		{"not a jpeg", []byte("GIF89a"), 1},
+		{"empty", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTIFFOrientation(t *testing.T) {
+	valid := exifSegment(binary.BigEndian, 5)[10:]
+	tests := []struct {
+		name string
+		tiff []byte
+		want int
+	}{
+		{"valid", valid, 5},
+		{"unknown byte order", append([]byte("XX"), valid[2:]...), 1},
+		{"IFD past the end", append(append([]byte{}, valid[:4]...), 0, 0, 0xFF, 0xFF), 1},
+		{"entry cut short", valid[:15], 1},
+		{"too short", valid[:4], 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tiffOrientation(tt.tiff); got != tt.want {
+				t.Errorf("tiffOrientation = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package media
+
+// gifFrameCount walks the block structure of a GIF without decoding any
+// pixels and returns how many frames it has. ok is false when the file
+// doesn't follow the format.
+func gifFrameCount(data []byte) (frames int, ok bool) {
+	// Header and logical screen descriptor
+	if len(data) < 13 {
+		return 0, false
+	}
+	pos := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << ((flags & 0x07) + 1)
+	}
+
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // extension: label, then data sub-blocks
+			pos, ok = skipSubBlocks(data, pos+2)
+		case 0x2C: // image descriptor, optional local color table, LZW code size, data
+			if pos+10 > len(data) {
+				return frames, false
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << ((flags & 0x07) + 1)
+			}
+			pos, ok = skipSubBlocks(data, pos+1)
+			frames++
+		case 0x3B: // trailer
+			return frames, true
+		default:
+			return frames, false
+		}
+		if !ok {
+			return frames, false
+		}
+	}
+	return frames, false
+}
+
+// skipSubBlocks returns the position after the sub-blocks starting at pos,
+// which end with an empty block.
+func skipSubBlocks(data []byte, pos int) (int, bool) {
+	for pos < len(data) {
+		size := int(data[pos])
+		pos++
+		if size == 0 {
+			return pos, true
+		}
+		pos += size
+	}
+	return pos, false
+}
@@ -0,0 +1,89 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"testing"
+)
+
+func encodeGIF(t *testing.T, frames, width, height int, localPalette bool) []byte {
+	t.Helper()
+	g := &gif.GIF{LoopCount: 0}
+	for i := 0; i < frames; i++ {
+		// A frame with its own palette gets a local color table
+		p := color.Palette(palette.Plan9)
+		if localPalette && i%2 == 1 {
+			p = color.Palette{color.Black, color.White}
+		}
+		frame := image.NewPaletted(image.Rect(0, 0, width, height), p)
+		frame.SetColorIndex(i%width, 0, 1)
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGIFFrameCount(t *testing.T) {
+	looping := encodeGIF(t, 3, 8, 8, false) // EncodeAll adds a NETSCAPE loop extension
+	tests := []struct {
+		name       string
+		data       []byte
+		wantFrames int
+		wantOK     bool
+	}{
+		{"single frame", encodeGIF(t, 1, 4, 4, false), 1, true},
+		{"animation with extensions", looping, 3, true},
+		{"local color tables", encodeGIF(t, 5, 16, 16, true), 5, true},
+		{"truncated", looping[:len(looping)-5], 0, false},
+		{"no trailer", looping[:len(looping)-1], 0, false},
+		{"header only", looping[:10], 0, false},
+		{"unknown block", append(append([]byte{}, looping[:len(looping)-1]...), 0x99), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames, ok := gifFrameCount(tt.data)
+			// The count of a broken file is never used
+			if ok != tt.wantOK || (ok && frames != tt.wantFrames) {
+				t.Errorf("gifFrameCount = (%d, %v), want (%d, %v)", frames, ok, tt.wantFrames, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestProcessImageLimitsAnimations(t *testing.T) {
+	tests := []struct {
+		name    string
+		frames  int
+		size    int
+		wantErr error
+	}{
+		{"small animation", 4, 32, nil},
+		{"many large frames", 30, 2000, ErrImageTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := ProcessImage(encodeGIF(t, tt.frames, tt.size, tt.size, false), 64)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ProcessImage error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			g, err := gif.DecodeAll(bytes.NewReader(img.Data))
+			if err != nil {
+				t.Fatalf("output is not a GIF: %v", err)
+			}
+			if len(g.Image) != tt.frames {
+				t.Errorf("output has %d frames, want %d", len(g.Image), tt.frames)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	// Guards against small files that decode into huge bitmaps
+	maxPixels = 40_000_000
+	// The same for all frames of an animation together; every frame is
+	// decoded at once
+	maxAnimationPixels = 100_000_000
+	jpegQuality        = 88
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrImageTooLarge   = errors.New("image dimensions are too large")
+)
+
+// Image is an upload after processing: re-encoded without metadata, with a
+// thumbnail no larger than the requested size.
+type Image struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+
+	Thumbnail            []byte
+	ThumbnailContentType string
+	ThumbnailExt         string
+}
+
+// ProcessImage sniffs the real type of data, ignoring whatever the client
+// claimed, and re-encodes it. Re-encoding drops EXIF and every other metadata
+// block; JPEG orientation is applied to the pixels first so photos still
+// display the right way up.
+func ProcessImage(data []byte, thumbnailSize int) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	if contentType == "image/gif" {
+		frames, ok := gifFrameCount(data)
+		if !ok || frames == 0 {
+			return nil, ErrUnsupportedType
+		}
+		if frames*cfg.Width*cfg.Height > maxAnimationPixels {
+			return nil, ErrImageTooLarge
+		}
+		return processGIF(data, thumbnailSize)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	result := &Image{ContentType: contentType}
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		result.Ext = ".jpg"
+	} else {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		result.Ext = ".png"
+	}
+	result.Data = buf.Bytes()
+	result.Width = img.Bounds().Dx()
+	result.Height = img.Bounds().Dy()
+
+	if err := result.setThumbnail(img, thumbnailSize, contentType == "image/png"); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// processGIF keeps every frame so animations survive, but drops comments and
+// application extensions.
+func processGIF(data []byte, thumbnailSize int) (*Image, error) {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil || len(g.Image) == 0 {
+		return nil, ErrUnsupportedType
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, &gif.GIF{
+		Image:     g.Image,
+		Delay:     g.Delay,
+		LoopCount: g.LoopCount,
+		Disposal:  g.Disposal,
+		Config:    g.Config,
+	}); err != nil {
+		return nil, err
+	}
+
+	result := &Image{
+		Data:        buf.Bytes(),
+		ContentType: "image/gif",
+		Ext:         ".gif",
+		Width:       g.Config.Width,
+		Height:      g.Config.Height,
+	}
+	if err := result.setThumbnail(g.Image[0], thumbnailSize, true); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// setThumbnail uses PNG when the source may have transparency.
+func (i *Image) setThumbnail(img image.Image, size int, transparent bool) error {
+	thumb := fit(img, size)
+
+	var buf bytes.Buffer
+	if transparent {
+		if err := png.Encode(&buf, thumb); err != nil {
+			return err
+		}
+		i.ThumbnailContentType, i.ThumbnailExt = "image/png", ".png"
+	} else {
+		if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return err
+		}
+		i.ThumbnailContentType, i.ThumbnailExt = "image/jpeg", ".jpg"
+	}
+	i.Thumbnail = buf.Bytes()
+	return nil
+}
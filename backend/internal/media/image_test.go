@@ -0,0 +1,140 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withPNGSize rewrites the dimensions in a PNG header, leaving the pixel
+// data alone, the way a decompression bomb claims a huge bitmap.
+func withPNGSize(data []byte, w, h uint32) []byte {
+	out := append([]byte{}, data...)
+	// Signature (8), chunk length (4), "IHDR" (4), then width and height
+	binary.BigEndian.PutUint32(out[16:], w)
+	binary.BigEndian.PutUint32(out[20:], h)
+	binary.BigEndian.PutUint32(out[29:], crc32.ChecksumIEEE(out[12:29]))
+	return out
+}
+
+// jpegMarkers lists the markers of the segments before the image data.
+func jpegMarkers(data []byte) []byte {
+	var markers []byte
+	for pos := 2; pos+4 <= len(data) && data[pos] == 0xFF; {
+		marker := data[pos+1]
+		if marker == 0xDA {
+			break
+		}
+		markers = append(markers, marker)
+		pos += 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+	}
+	return markers
+}
+
+func TestProcessImageTypes(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          []byte
+		wantType      string
+		wantExt       string
+		wantThumbType string
+		wantErr       error
+	}{
+		{"jpeg", encodeJPEG(t, 8, 8), "image/jpeg", ".jpg", "image/jpeg", nil},
+		{"png", encodePNG(t, 8, 8), "image/png", ".png", "image/png", nil},
+		{"gif", encodeGIF(t, 2, 8, 8, false), "image/gif", ".gif", "image/png", nil},
+		{"html", []byte("<html><body>hi</body></html>"), "", "", "", ErrUnsupportedType},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), "", "", "", ErrUnsupportedType},
+		{"bmp", append([]byte("BM"), make([]byte, 60)...), "", "", "", ErrUnsupportedType},
+		{"webp", append([]byte("RIFF\x00\x00\x00\x00WEBPVP8 "), make([]byte, 32)...), "", "", "", ErrUnsupportedType},
+		{"png signature only", encodePNG(t, 8, 8)[:12], "", "", "", ErrUnsupportedType},
+		{"empty", nil, "", "", "", ErrUnsupportedType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := ProcessImage(tt.data, 4)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ProcessImage error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if img.ContentType != tt.wantType || img.Ext != tt.wantExt || img.ThumbnailContentType != tt.wantThumbType {
+				t.Errorf("got %s %s thumbnail %s, want %s %s thumbnail %s",
+					img.ContentType, img.Ext, img.ThumbnailContentType, tt.wantType, tt.wantExt, tt.wantThumbType)
+			}
+			thumb, _, err := image.DecodeConfig(bytes.NewReader(img.Thumbnail))
+			if err != nil || thumb.Width != 4 || thumb.Height != 4 {
+				t.Errorf("thumbnail is %dx%d (%v), want 4x4", thumb.Width, thumb.Height, err)
+			}
+		})
+	}
+}
+
+func TestProcessImageLimitsPixels(t *testing.T) {
+	small := encodePNG(t, 1, 1)
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"at the limit", withPNGSize(small, 8000, 5000), ErrUnsupportedType}, // allowed, but the pixel data doesn't match
+		{"over the limit", withPNGSize(small, 8000, 5001), ErrImageTooLarge},
+		{"huge", withPNGSize(small, 1<<20, 1<<20), ErrImageTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ProcessImage(tt.data, 4); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ProcessImage error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessImageStripsMetadata(t *testing.T) {
+	comment := []byte{0xFF, 0xFE, 0x00, 0x07, 'h', 'e', 'l', 'l', 'o'}
+	tests := []struct {
+		name                  string
+		data                  []byte
+		wantWidth, wantHeight int
+	}{
+		{"no exif", encodeJPEG(t, 6, 4), 6, 4},
+		{"upright exif", encodeJPEG(t, 6, 4, exifSegment(binary.BigEndian, 1)), 6, 4},
+		{"rotated exif and comment", encodeJPEG(t, 6, 4, comment, exifSegment(binary.LittleEndian, 6)), 4, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := ProcessImage(tt.data, 320)
+			if err != nil {
+				t.Fatalf("ProcessImage: %v", err)
+			}
+			for _, marker := range jpegMarkers(img.Data) {
+				if marker == 0xE1 || marker == 0xFE {
+					t.Errorf("output keeps segment %#x", marker)
+				}
+			}
+			if bytes.Contains(img.Data, []byte("Exif\x00\x00")) {
+				t.Error("output contains an EXIF header")
+			}
+			if jpegOrientation(img.Data) != 1 {
+				t.Error("output still carries an orientation")
+			}
+			if img.Width != tt.wantWidth || img.Height != tt.wantHeight {
+				t.Errorf("size = %dx%d, want %dx%d", img.Width, img.Height, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
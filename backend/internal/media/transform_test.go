@@ -0,0 +1,67 @@
+package media
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestApplyOrientation(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	tests := []struct {
+		orientation int
+		wantSize    image.Point
+		wantRed     image.Point // where the top left source pixel ends up
+	}{
+		{1, image.Pt(3, 2), image.Pt(0, 0)},
+		{2, image.Pt(3, 2), image.Pt(2, 0)},
+		{3, image.Pt(3, 2), image.Pt(2, 1)},
+		{4, image.Pt(3, 2), image.Pt(0, 1)},
+		{5, image.Pt(2, 3), image.Pt(0, 0)},
+		{6, image.Pt(2, 3), image.Pt(1, 0)},
+		{7, image.Pt(2, 3), image.Pt(1, 2)},
+		{8, image.Pt(2, 3), image.Pt(0, 2)},
+		{0, image.Pt(3, 2), image.Pt(0, 0)},
+		{9, image.Pt(3, 2), image.Pt(0, 0)},
+	}
+	for _, tt := range tests {
+		// A 3×2 source that doesn't start at the origin
+		src := image.NewNRGBA(image.Rect(10, 20, 13, 22))
+		src.SetNRGBA(10, 20, red)
+
+		got := applyOrientation(src, tt.orientation)
+		b := got.Bounds()
+		if b.Size() != tt.wantSize {
+			t.Errorf("orientation %d: size = %v, want %v", tt.orientation, b.Size(), tt.wantSize)
+			continue
+		}
+		at := color.NRGBAModel.Convert(got.At(b.Min.X+tt.wantRed.X, b.Min.Y+tt.wantRed.Y))
+		if at != red {
+			t.Errorf("orientation %d: pixel at %v = %v, want red", tt.orientation, tt.wantRed, at)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name  string
+		w, h  int
+		size  int
+		wantW int
+		wantH int
+	}{
+		{"landscape", 640, 480, 320, 320, 240},
+		{"portrait", 480, 640, 320, 240, 320},
+		{"already small", 100, 50, 320, 100, 50},
+		{"thin strip keeps one pixel", 1000, 1, 320, 320, 1},
+		{"no size", 640, 480, 0, 640, 480},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fit(image.NewNRGBA(image.Rect(0, 0, tt.w, tt.h)), tt.size).Bounds()
+			if got.Dx() != tt.wantW || got.Dy() != tt.wantH {
+				t.Errorf("fit(%d×%d, %d) = %d×%d, want %d×%d", tt.w, tt.h, tt.size, got.Dx(), got.Dy(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
@@ -9,7 +9,18 @@ import (
 	"typinggame-api/config"
 )
 
+// AuthMiddleware only lets registered users through. Guest tokens are rejected.
 func AuthMiddleware() echo.MiddlewareFunc {
+	return authenticate(false)
+}
+
+// GuestAuthMiddleware accepts both registered users and guest sessions. It is
+// used for the game routes so visitors can play before signing up.
+func GuestAuthMiddleware() echo.MiddlewareFunc {
+	return authenticate(true)
+}
+
+func authenticate(allowGuest bool) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			authHeader := c.Request().Header.Get("Authorization")
@@ -43,9 +54,14 @@ func AuthMiddleware() echo.MiddlewareFunc {
 				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "ไม่พบ user_id ใน token"})
 			}
 
+			isGuest, _ := claims["guest"].(bool)
+			if isGuest && !allowGuest {
+				return c.JSON(http.StatusForbidden, map[string]string{"message": "กรุณาสมัครสมาชิกเพื่อใช้งานส่วนนี้"})
+			}
+
 			c.Set("user_id", userID)
+			c.Set("is_guest", isGuest)
 			return next(c)
 		}
 	}
 }
-
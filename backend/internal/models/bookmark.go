@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Bookmark is a post a user saved for later, optionally filed in one of
+// their collections.
+type Bookmark struct {
+	ID           string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	UserID       string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_bookmarks_user_post,priority:1;index:idx_bookmarks_user_created,priority:1" json:"userId"`
+	PostID       string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_bookmarks_user_post,priority:2" json:"postId"`
+	Post         Post      `gorm:"foreignKey:PostID" json:"-"`
+	CollectionID *string   `gorm:"type:varchar(36);index" json:"collectionId"`
+	CreatedAt    time.Time `gorm:"index:idx_bookmarks_user_created,priority:2" json:"createdAt"`
+}
+
+// BookmarkCollection is a named folder of bookmarks.
+type BookmarkCollection struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	UserID    string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_bookmark_collections_user_name,priority:1" json:"userId"`
+	Name      string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_bookmark_collections_user_name,priority:2" json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
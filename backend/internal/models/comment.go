@@ -11,11 +11,23 @@ type Comment struct {
 	Content   string    `gorm:"type:text;not null" json:"content"`
 	PostID    string    `gorm:"type:varchar(36);not null;index" json:"postId"`
 	Post      Post      `gorm:"foreignKey:PostID" json:"-"`
+	ParentID  *string   `gorm:"type:varchar(36);index" json:"parentId"` // nil for comments on the post itself
+	Depth     int       `gorm:"not null;default:0" json:"depth"`        // 0 for comments on the post, 1 for their replies, and so on
+	Replies   int       `gorm:"not null;default:0" json:"replies"`      // direct replies shown in the thread, kept up to date by CommentService
 	AuthorID  string    `gorm:"type:varchar(36);not null;index" json:"authorId"`
 	Author    User      `gorm:"foreignKey:AuthorID" json:"author"`
+	Entities  TextEntities `gorm:"type:json" json:"entities"`
+	Hidden    bool      `gorm:"not null;default:false" json:"-"` // hidden by a moderator or held by the content filter
+	Version   int       `gorm:"not null;default:1" json:"version"` // bumped by every edit and by hiding
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// IsPlaceholder reports whether the comment is deleted or hidden but stays
+// in its thread, without its content, because it has replies.
+func (c *Comment) IsPlaceholder() bool {
+	return c.DeletedAt.Valid || c.Hidden
+}
+
 
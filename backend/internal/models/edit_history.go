@@ -6,10 +6,17 @@ import (
 	"github.com/google/uuid"
 )
 
+// EditHistory records one edit of a post or comment. Version numbers the
+// edits of an entity from 1; entries written before versions existed read
+// back numbered by position. RevertOf is set when the edit restored the
+// content from before an earlier entry.
 type EditHistory struct {
 	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
 	EntityType string   `gorm:"type:varchar(20);not null;index" json:"entityType"` // "post" or "comment"
 	EntityID   string   `gorm:"type:varchar(36);not null;index" json:"entityId"`
+	EditorID   string   `gorm:"type:varchar(36);index" json:"editorId"`
+	Version    int      `gorm:"not null;default:0" json:"version"`
+	RevertOf   *string  `gorm:"type:varchar(36)" json:"revertOf"`
 	OldContent string   `gorm:"type:text" json:"oldContent"`
 	NewContent string   `gorm:"type:text;not null" json:"newContent"`
 	CreatedAt  time.Time `json:"createdAt"`
@@ -19,13 +26,13 @@ func (EditHistory) TableName() string {
 	return "edit_histories"
 }
 
-func NewEditHistory(entityType, entityID, oldContent, newContent string) *EditHistory {
+func NewEditHistory(entityType, entityID, editorID, oldContent, newContent string) *EditHistory {
 	return &EditHistory{
 		ID:         uuid.New().String(),
 		EntityType: entityType,
 		EntityID:   entityID,
+		EditorID:   editorID,
 		OldContent: oldContent,
 		NewContent: newContent,
 	}
 }
-
@@ -8,11 +8,18 @@ import (
 
 type GameScore struct {
 	ID         string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
-	UserID     string    `gorm:"type:varchar(36);not null;index" json:"userId"`
+	UserID     string    `gorm:"type:varchar(36);not null;index;uniqueIndex:idx_game_scores_user_client" json:"userId"`
 	User       User      `gorm:"foreignKey:UserID" json:"user"`
+	ClientID   *string   `gorm:"type:varchar(64);uniqueIndex:idx_game_scores_user_client" json:"clientId,omitempty"` // set by offline clients to make retries idempotent
 	Score      int       `gorm:"not null" json:"score"`
 	WordsTyped int       `gorm:"not null" json:"wordsTyped"`
 	Difficulty string    `gorm:"type:varchar(20)" json:"difficulty"`
+	PassageID  *string   `gorm:"type:varchar(36);index" json:"passageId,omitempty"` // set for passage mode runs
+	WPM        float64   `gorm:"default:0" json:"wpm"`
+	Accuracy   float64   `gorm:"default:0" json:"accuracy"` // percent, 0-100
+	PlayedAt   time.Time `gorm:"index" json:"playedAt"`
+	IsGuest    bool      `gorm:"default:false;index" json:"-"` // guest runs are kept off public leaderboards
+	Flagged    bool      `gorm:"default:false;index" json:"-"` // suspicious runs, excluded from leaderboards and certificates
 	CreatedAt  time.Time `json:"createdAt"`
 }
 
@@ -27,6 +34,6 @@ func NewGameScore(userID string, score, wordsTyped int, difficulty string) *Game
 		Score:      score,
 		WordsTyped: wordsTyped,
 		Difficulty: difficulty,
+		PlayedAt:   time.Now(),
 	}
 }
-
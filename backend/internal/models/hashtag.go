@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const (
+	EntitySourcePost    = "post"
+	EntitySourceComment = "comment"
+)
+
+// HashtagUse records one tag used in a post or comment. PostID is the post
+// itself or the post a comment belongs to.
+type HashtagUse struct {
+	Tag        string    `gorm:"primaryKey;type:varchar(100);index:idx_hashtag_uses_tag_created,priority:1" json:"tag"`
+	SourceType string    `gorm:"primaryKey;type:varchar(20)" json:"sourceType"`
+	SourceID   string    `gorm:"primaryKey;type:varchar(36);index" json:"sourceId"`
+	PostID     string    `gorm:"type:varchar(36);not null;index" json:"postId"`
+	AuthorID   string    `gorm:"type:varchar(36);not null" json:"authorId"`
+	CreatedAt  time.Time `gorm:"index;index:idx_hashtag_uses_tag_created,priority:2" json:"createdAt"`
+}
+
+// Mention records a user mentioned in a post or comment.
+type Mention struct {
+	ID              string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	SourceType      string    `gorm:"type:varchar(20);not null;index:idx_mentions_source,priority:1" json:"sourceType"`
+	SourceID        string    `gorm:"type:varchar(36);not null;index:idx_mentions_source,priority:2" json:"sourceId"`
+	PostID          string    `gorm:"type:varchar(36);not null" json:"postId"`
+	AuthorID        string    `gorm:"type:varchar(36);not null" json:"authorId"`
+	Author          User      `gorm:"foreignKey:AuthorID" json:"author"`
+	MentionedUserID string    `gorm:"type:varchar(36);not null;index:idx_mentions_user_created,priority:1" json:"mentionedUserId"`
+	CreatedAt       time.Time `gorm:"index:idx_mentions_user_created,priority:2" json:"createdAt"`
+}
@@ -41,6 +41,7 @@ type Message struct {
 	Sender         User         `gorm:"foreignKey:SenderID" json:"sender"`
 	Content        string       `gorm:"type:text;not null" json:"content"`
 	IsRead         bool         `gorm:"default:false" json:"isRead"`
+	Hidden         bool         `gorm:"not null;default:false" json:"-"` // hidden by a moderator or held by the content filter
 	CreatedAt      time.Time    `json:"createdAt"`
 }
 
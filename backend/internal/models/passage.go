@@ -0,0 +1,51 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Passage is a quote or paragraph used by the sentence typing mode.
+type Passage struct {
+	ID           string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	Text         string    `gorm:"type:text;not null" json:"text"`
+	Source       string    `gorm:"type:varchar(255)" json:"source"`
+	Language     string    `gorm:"type:varchar(10);not null;index" json:"language"`
+	LengthBucket string    `gorm:"type:varchar(10);not null;index" json:"lengthBucket"` // short, medium, long
+	CharCount    int       `gorm:"not null" json:"charCount"`
+	ContentHash  string    `gorm:"type:varchar(64);uniqueIndex" json:"-"` // keeps re-imports idempotent
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+func (Passage) TableName() string {
+	return "passages"
+}
+
+func NewPassage(text, source, language string) *Passage {
+	count := utf8.RuneCountInString(text)
+	sum := sha256.Sum256([]byte(language + "\x00" + text))
+	return &Passage{
+		ID:           uuid.New().String(),
+		Text:         text,
+		Source:       source,
+		Language:     language,
+		LengthBucket: PassageLengthBucket(count),
+		CharCount:    count,
+		ContentHash:  hex.EncodeToString(sum[:]),
+	}
+}
+
+func PassageLengthBucket(charCount int) string {
+	switch {
+	case charCount <= 100:
+		return "short"
+	case charCount <= 300:
+		return "medium"
+	default:
+		return "long"
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Poll is a question attached to a post. Tallies are kept on the options so
+// feeds don't have to count votes.
+type Poll struct {
+	ID                    string       `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	PostID                string       `gorm:"type:varchar(36);not null;uniqueIndex" json:"postId"`
+	MultipleChoice        bool         `gorm:"not null;default:false" json:"multipleChoice"`
+	HideResultsUntilVoted bool         `gorm:"not null;default:false" json:"hideResultsUntilVoted"`
+	ClosesAt              *time.Time   `gorm:"index:idx_polls_closes_at" json:"closesAt"`
+	ClosedAt              *time.Time   `json:"closedAt"`
+	Voters                int          `gorm:"not null;default:0" json:"voters"`
+	Options               []PollOption `gorm:"foreignKey:PollID" json:"options"`
+	CreatedAt             time.Time    `json:"createdAt"`
+}
+
+// IsClosed reports whether voting has ended at now. A poll past its closing
+// time counts as closed even before the close job has marked it.
+func (p *Poll) IsClosed(now time.Time) bool {
+	return p.ClosedAt != nil || (p.ClosesAt != nil && !now.Before(*p.ClosesAt))
+}
+
+type PollOption struct {
+	ID       string `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	PollID   string `gorm:"type:varchar(36);not null;index" json:"pollId"`
+	Position int    `gorm:"not null;default:0" json:"position"`
+	Text     string `gorm:"type:varchar(100);not null" json:"text"`
+	Votes    int    `gorm:"not null;default:0" json:"votes"`
+}
+
+// PollVote is one option chosen by a user. Multiple-choice polls get one row
+// per chosen option.
+type PollVote struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	PollID    string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_poll_votes_poll_user_option,priority:1" json:"pollId"`
+	UserID    string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_poll_votes_poll_user_option,priority:2;index" json:"userId"`
+	OptionID  string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_poll_votes_poll_user_option,priority:3" json:"optionId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -6,25 +6,49 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	PostVisibilityPublic  = "public"
+	PostVisibilityFriends = "friends"
+	PostVisibilityOnlyMe  = "only_me"
+)
+
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+)
+
 type Post struct {
-	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
-	Content   string    `gorm:"type:text;not null" json:"content"`
-	AuthorID  string    `gorm:"type:varchar(36);not null;index" json:"authorId"`
-	Author    User      `gorm:"foreignKey:AuthorID" json:"author"`
-	Likes     int       `gorm:"default:0" json:"likes"`
-	Comments  int       `gorm:"default:0" json:"comments"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID          string           `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	Content     string           `gorm:"type:text;not null" json:"content"`
+	AuthorID    string           `gorm:"type:varchar(36);not null;index;index:idx_posts_author_created,priority:1" json:"authorId"`
+	Author      User             `gorm:"foreignKey:AuthorID" json:"author"`
+	Visibility  string           `gorm:"type:varchar(20);not null;default:'public';index" json:"visibility"`                                       // public, friends, only_me
+	Status      string           `gorm:"type:varchar(20);not null;default:'published';index:idx_posts_status_publish_at,priority:1" json:"status"` // draft, scheduled, published
+	PublishAt   *time.Time       `gorm:"index:idx_posts_status_publish_at,priority:2" json:"publishAt"`
+	Likes       int              `gorm:"default:0" json:"likes"` // reactions of any kind
+	Comments    int              `gorm:"default:0" json:"comments"`
+	Reposts     int              `gorm:"default:0" json:"reposts"`
+	Hidden      bool             `gorm:"not null;default:false" json:"-"`   // hidden by a moderator or held by the content filter; only the author still sees it
+	HotScore    float64          `gorm:"not null;default:0;index" json:"-"` // refreshed periodically, see PostRepository.RefreshHotScores
+	Version     int              `gorm:"not null;default:1" json:"version"` // bumped by edits, hiding and publishing, see PostRepository.Update
+	RepostOfID  *string          `gorm:"type:varchar(36);index" json:"repostOfId"`
+	Attachments []PostAttachment `gorm:"foreignKey:PostID" json:"attachments"`
+	Poll        *Poll            `gorm:"foreignKey:PostID" json:"poll,omitempty"`
+	Entities    TextEntities     `gorm:"type:json" json:"entities"`
+	CreatedAt   time.Time        `gorm:"index;index:idx_posts_author_created,priority:2" json:"createdAt"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt   `gorm:"index" json:"-"`
 }
 
-type PostReaction struct {
-	ID        string    `gorm:"primaryKey;type:varchar(36)"`
-	PostID    string    `gorm:"type:varchar(36);not null;index"`
-	UserID    string    `gorm:"type:varchar(36);not null;index"`
-	Reaction  string    `gorm:"type:varchar(20);not null;default:'like'"` // like, love, haha, wow, sad, angry
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	DeletedAt gorm.DeletedAt `gorm:"index"`
+// IsPublished reports whether the post is live. Drafts and scheduled posts
+// are only visible to their author.
+func (p *Post) IsPublished() bool {
+	return p.Status == "" || p.Status == PostStatusPublished
 }
 
+// IsPlainRepost reports whether the post only shares another post, without
+// a quote comment or images of its own.
+func (p *Post) IsPlainRepost() bool {
+	return p.RepostOfID != nil && p.Content == "" && len(p.Attachments) == 0
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PostAttachment is an image stored outside the database. Keys point into the
+// configured storage backend; URLs are built from them when responding.
+type PostAttachment struct {
+	ID           string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	PostID       string    `gorm:"type:varchar(36);not null;index" json:"postId"`
+	Position     int       `gorm:"not null;default:0" json:"position"`
+	StorageKey   string    `gorm:"type:varchar(255);not null" json:"-"`
+	ThumbnailKey string    `gorm:"type:varchar(255);not null" json:"-"`
+	ContentType  string    `gorm:"type:varchar(50);not null" json:"contentType"`
+	Size         int64     `gorm:"not null" json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// What can be reacted to; the same values as the report targets.
+const (
+	ReactionTargetPost    = "post"
+	ReactionTargetComment = "comment"
+	ReactionTargetMessage = "message"
+)
+
+// Reaction is one user's reaction to a post, comment or message. A user has
+// at most one reaction per target. Reaction is a name from the reaction
+// registry or a single emoji.
+type Reaction struct {
+	ID         string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	TargetType string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_reactions_target_user,priority:1;index:idx_reactions_target_created,priority:1" json:"targetType"`
+	TargetID   string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_reactions_target_user,priority:2;index:idx_reactions_target_created,priority:2" json:"targetId"`
+	UserID     string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_reactions_target_user,priority:3;index" json:"userId"`
+	User       User      `gorm:"foreignKey:UserID" json:"-"`
+	Reaction   string    `gorm:"type:varchar(64);not null" json:"reaction"`
+	CreatedAt  time.Time `gorm:"index:idx_reactions_target_created,priority:3" json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Kinds of content a report can point at.
+const (
+	ReportTargetPost    = "post"
+	ReportTargetComment = "comment"
+	ReportTargetMessage = "message"
+	ReportTargetUser    = "user"
+)
+
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusInReview  = "in_review"
+	ReportStatusResolved  = "resolved"
+	ReportStatusDismissed = "dismissed"
+)
+
+// SystemReporterID stands in for the reporter on reports the content filter
+// files by itself.
+const SystemReporterID = "system"
+
+// Report is a user's complaint about a piece of content or another user.
+// TargetOwnerID is the author of the content (or the user itself), who is
+// never shown who reported them.
+type Report struct {
+	ID            string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	ReporterID    string     `gorm:"type:varchar(36);not null;index" json:"reporterId"`
+	TargetType    string     `gorm:"type:varchar(20);not null;index:idx_reports_target,priority:1" json:"targetType"` // post, comment, message, user
+	TargetID      string     `gorm:"type:varchar(36);not null;index:idx_reports_target,priority:2" json:"targetId"`
+	TargetOwnerID string     `gorm:"type:varchar(36);not null;index" json:"targetOwnerId"`
+	Reason        string     `gorm:"type:varchar(500);not null" json:"reason"`
+	Status        string     `gorm:"type:varchar(20);not null;default:'open';index:idx_reports_status_created,priority:1" json:"status"` // open, in_review, resolved, dismissed
+	AssigneeID    *string    `gorm:"type:varchar(36);index" json:"assigneeId"`
+	ResolvedAt    *time.Time `json:"resolvedAt"`
+	CreatedAt     time.Time  `gorm:"index:idx_reports_status_created,priority:2" json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// Moderation actions. Hide, delete and approve apply to content; warn and
+// suspend to the user behind it. Approve puts hidden content back, such as
+// content the content filter held for review.
+const (
+	ModerationHide    = "hide"
+	ModerationApprove = "approve"
+	ModerationDelete  = "delete"
+	ModerationWarn    = "warn"
+	ModerationSuspend = "suspend"
+	ModerationDismiss = "dismiss"
+)
+
+// ModerationAction is the audit log of what moderators did and why. Content
+// is a copy of the target's text at the time, so the log still makes sense
+// after the content is deleted.
+type ModerationAction struct {
+	ID             string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	ReportID       *string    `gorm:"type:varchar(36);index" json:"reportId"`
+	ModeratorID    string     `gorm:"type:varchar(36);not null;index" json:"moderatorId"`
+	Action         string     `gorm:"type:varchar(20);not null" json:"action"` // hide, delete, approve, warn, suspend, dismiss
+	TargetType     string     `gorm:"type:varchar(20);not null" json:"targetType"`
+	TargetID       string     `gorm:"type:varchar(36);not null" json:"targetId"`
+	TargetOwnerID  string     `gorm:"type:varchar(36);not null;index:idx_moderation_actions_owner_created,priority:1" json:"targetOwnerId"`
+	Content        string     `gorm:"type:text" json:"content"`
+	Reason         string     `gorm:"type:varchar(500);not null" json:"reason"`
+	SuspendedUntil *time.Time `json:"suspendedUntil"`
+	CreatedAt      time.Time  `gorm:"index:idx_moderation_actions_owner_created,priority:2" json:"createdAt"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SearchDocument is a row of the MySQL search index. Body holds the case and
+// width folded text, indexed with the ngram parser so Thai and Japanese text
+// (which has no spaces between words) can be matched.
+type SearchDocument struct {
+	DocType   string    `gorm:"primaryKey;type:varchar(20)" json:"docType"`
+	DocID     string    `gorm:"primaryKey;type:varchar(36)" json:"docId"`
+	AuthorID  string    `gorm:"type:varchar(36);not null;index" json:"authorId"`
+	Body      string    `gorm:"type:text;not null;index:idx_search_documents_body,class:FULLTEXT,option:WITH PARSER ngram" json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// TextEntity marks a hashtag or mention inside post or comment content, so
+// clients can link it without parsing the text again. Start and End count
+// code points and include the # or @ prefix.
+type TextEntity struct {
+	Type   string `json:"type"` // hashtag, mention
+	Value  string `json:"value"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	UserID string `json:"userId,omitempty"` // set for mentions that matched a user
+}
+
+// TextEntities is stored as a JSON column.
+type TextEntities []TextEntity
+
+func (e TextEntities) Value() (driver.Value, error) {
+	if e == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(e)
+	return string(data), err
+}
+
+func (e *TextEntities) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for TextEntities")
+	}
+	return json.Unmarshal(data, e)
+}
@@ -6,13 +6,42 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	UserRoleUser      = "user"
+	UserRoleModerator = "moderator"
+	UserRoleAdmin     = "admin"
+)
+
+// Who besides the author may read the edit history of their posts and
+// comments: everyone who can see the content, or only the times of edits,
+// or nobody.
+const (
+	EditHistoryFull       = "full"
+	EditHistoryTimestamps = "timestamps"
+	EditHistoryHidden     = "hidden"
+)
+
 type User struct {
-	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
-	Name      string    `gorm:"type:varchar(255);not null" json:"name"`
-	Email     string    `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
-	Password  string    `gorm:"type:varchar(255);not null" json:"-"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             string         `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	Name           string         `gorm:"type:varchar(255);not null" json:"name"`
+	Email          string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
+	Password       string         `gorm:"type:varchar(255);not null" json:"-"`
+	IsGuest        bool           `gorm:"default:false;index" json:"isGuest"`
+	Role           string         `gorm:"type:varchar(20);not null;default:'user'" json:"role"` // user, moderator, admin
+	SuspendedUntil *time.Time     `json:"suspendedUntil,omitempty"`
+	EditHistoryVisibility string  `gorm:"type:varchar(20);not null;default:'full'" json:"editHistoryVisibility"` // full, timestamps, hidden
+	CreatedAt      time.Time      `json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// IsModerator reports whether the user may work the moderation queue.
+// Admins are moderators too.
+func (u *User) IsModerator() bool {
+	return u.Role == UserRoleModerator || u.Role == UserRoleAdmin
+}
+
+// IsSuspended reports whether a moderator has suspended the user as of now.
+func (u *User) IsSuspended(now time.Time) bool {
+	return u.SuspendedUntil != nil && now.Before(*u.SuspendedUntil)
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"typinggame-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// BookmarkPageQuery selects one page of a user's bookmarks, most recently
+// saved first.
+type BookmarkPageQuery struct {
+	UserID           string
+	CollectionID     string   // empty for every collection
+	ExcludeAuthorIDs []string // e.g. users blocked by or blocking the user
+	After            *Cursor
+	Limit            int
+}
+
+type BookmarkRepository interface {
+	Find(userID, postID string) (*models.Bookmark, error)
+	Save(bookmark *models.Bookmark) error
+	Delete(userID, postID string) error
+	FindPage(query BookmarkPageQuery) ([]models.Bookmark, error)
+	FindBookmarkedPostIDs(userID string, postIDs []string) ([]string, error)
+	CreateCollection(collection *models.BookmarkCollection) error
+	FindCollection(id string) (*models.BookmarkCollection, error)
+	FindCollectionByName(userID, name string) (*models.BookmarkCollection, error)
+	FindCollections(userID string) ([]models.BookmarkCollection, error)
+	UpdateCollection(collection *models.BookmarkCollection) error
+	DeleteCollection(id string) error
+}
+
+type bookmarkRepository struct {
+	db *gorm.DB
+}
+
+func NewBookmarkRepository(db *gorm.DB) BookmarkRepository {
+	return &bookmarkRepository{db: db}
+}
+
+func (r *bookmarkRepository) Find(userID, postID string) (*models.Bookmark, error) {
+	var bookmark models.Bookmark
+	err := r.db.Where("user_id = ? AND post_id = ?", userID, postID).First(&bookmark).Error
+	if err != nil {
+		return nil, err
+	}
+	return &bookmark, nil
+}
+
+// Save creates the bookmark, or updates its collection if it already exists.
+func (r *bookmarkRepository) Save(bookmark *models.Bookmark) error {
+	if bookmark.ID == "" {
+		bookmark.ID = uuid.New().String()
+		return r.db.Omit("Post").Create(bookmark).Error
+	}
+	return r.db.Model(bookmark).Update("collection_id", bookmark.CollectionID).Error
+}
+
+func (r *bookmarkRepository) Delete(userID, postID string) error {
+	return r.db.Where("user_id = ? AND post_id = ?", userID, postID).Delete(&models.Bookmark{}).Error
+}
+
+// FindPage only returns bookmarks of posts that still exist and that the user
+// may still see, so deleted and restricted posts drop out on their own.
+func (r *bookmarkRepository) FindPage(query BookmarkPageQuery) ([]models.Bookmark, error) {
+	var bookmarks []models.Bookmark
+	db := r.db.Preload("Post.Author").
+		Preload("Post.Attachments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position ASC")
+		}).
+		Joins("JOIN posts ON posts.id = bookmarks.post_id AND posts.deleted_at IS NULL").
+		Where("bookmarks.user_id = ? AND posts.status = ? AND posts.hidden = ?", query.UserID, models.PostStatusPublished, false)
+	db = visibleTo(r.db, db, query.UserID)
+
+	if query.CollectionID != "" {
+		db = db.Where("bookmarks.collection_id = ?", query.CollectionID)
+	}
+	if len(query.ExcludeAuthorIDs) > 0 {
+		db = db.Where("posts.author_id NOT IN ?", query.ExcludeAuthorIDs)
+	}
+	if query.After != nil {
+		db = db.Where("(bookmarks.created_at < ? OR (bookmarks.created_at = ? AND bookmarks.id < ?))",
+			query.After.CreatedAt, query.After.CreatedAt, query.After.ID)
+	}
+
+	err := db.Order("bookmarks.created_at DESC, bookmarks.id DESC").Limit(query.Limit).Find(&bookmarks).Error
+	return bookmarks, err
+}
+
+// FindBookmarkedPostIDs returns which of postIDs userID has bookmarked.
+func (r *bookmarkRepository) FindBookmarkedPostIDs(userID string, postIDs []string) ([]string, error) {
+	var ids []string
+	if len(postIDs) == 0 {
+		return ids, nil
+	}
+	err := r.db.Model(&models.Bookmark{}).Where("user_id = ? AND post_id IN ?", userID, postIDs).Pluck("post_id", &ids).Error
+	return ids, err
+}
+
+func (r *bookmarkRepository) CreateCollection(collection *models.BookmarkCollection) error {
+	collection.ID = uuid.New().String()
+	return r.db.Create(collection).Error
+}
+
+func (r *bookmarkRepository) FindCollection(id string) (*models.BookmarkCollection, error) {
+	var collection models.BookmarkCollection
+	err := r.db.Where("id = ?", id).First(&collection).Error
+	if err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+func (r *bookmarkRepository) FindCollectionByName(userID, name string) (*models.BookmarkCollection, error) {
+	var collection models.BookmarkCollection
+	err := r.db.Where("user_id = ? AND name = ?", userID, name).First(&collection).Error
+	if err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+func (r *bookmarkRepository) FindCollections(userID string) ([]models.BookmarkCollection, error) {
+	var collections []models.BookmarkCollection
+	err := r.db.Where("user_id = ?", userID).Order("name ASC").Find(&collections).Error
+	return collections, err
+}
+
+func (r *bookmarkRepository) UpdateCollection(collection *models.BookmarkCollection) error {
+	return r.db.Save(collection).Error
+}
+
+// DeleteCollection removes a collection and keeps its bookmarks, which go
+// back to being uncollected.
+func (r *bookmarkRepository) DeleteCollection(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Bookmark{}).Where("collection_id = ?", id).Update("collection_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.BookmarkCollection{}, "id = ?", id).Error
+	})
+}
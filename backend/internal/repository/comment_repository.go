@@ -1,20 +1,56 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"typinggame-api/internal/models"
 	"gorm.io/gorm"
 )
 
+// CommentPageQuery selects one page of a thread, oldest first: the comments
+// on a post, or the replies to one comment. Deleted and hidden comments are
+// included as placeholders while they have replies.
+type CommentPageQuery struct {
+	PostID           string
+	ParentID         string   // empty for comments on the post itself
+	ExcludeAuthorIDs []string // e.g. users blocked by or blocking the viewer
+	After            *Cursor
+	Limit            int
+}
+
 type CommentRepository interface {
 	Create(comment *models.Comment) error
-	FindByPostID(postID string) ([]models.Comment, error)
+	// FindThread returns every comment in the post's thread, oldest first,
+	// placeholders included.
+	FindThread(postID string) ([]models.Comment, error)
+	FindPage(query CommentPageQuery) ([]models.Comment, error)
 	FindByID(id string) (*models.Comment, error)
-	Update(comment *models.Comment) error
+	// FindInThread finds a comment that is visible or is a placeholder.
+	FindInThread(id string) (*models.Comment, error)
+	FindByIDs(ids []string) ([]models.Comment, error)
+	FindBatch(afterID string, limit int) ([]models.Comment, error)
+	// Update reports false, and changes nothing, when the comment is no
+	// longer at comment.Version.
+	Update(comment *models.Comment) (bool, error)
 	Delete(id string) error
 	CountByPostID(postID string) (int64, error)
+	SetHidden(id string, hidden bool) error
+	FindDeleted(id string) (*models.Comment, error)
+	FindTrashPage(query TrashPageQuery) ([]models.Comment, error)
+	Restore(id string) error
+	FindPurgeable(deletedBefore time.Time, limit int) ([]models.Comment, error)
+	Purge(ids []string) error
+	// CountReplies counts the direct replies of a comment that show in its
+	// thread, which is what Comment.Replies holds.
+	CountReplies(parentID string) (int64, error)
+	SetReplies(id string, replies int) error
 }
 
+// inThread matches comments shown in their thread, as themselves or as
+// placeholders.
+const inThread = "((comments.deleted_at IS NULL AND comments.hidden = false) OR comments.replies > 0)"
+
 type commentRepository struct {
 	db *gorm.DB
 }
@@ -25,12 +61,33 @@ func NewCommentRepository(db *gorm.DB) CommentRepository {
 
 func (r *commentRepository) Create(comment *models.Comment) error {
 	comment.ID = uuid.New().String()
+	comment.Version = 1
 	return r.db.Create(comment).Error
 }
 
-func (r *commentRepository) FindByPostID(postID string) ([]models.Comment, error) {
+func (r *commentRepository) FindThread(postID string) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := r.db.Unscoped().Preload("Author").Where("post_id = ?", postID).Where(inThread).
+		Order("created_at ASC, id ASC").Find(&comments).Error
+	return comments, err
+}
+
+func (r *commentRepository) FindPage(query CommentPageQuery) ([]models.Comment, error) {
 	var comments []models.Comment
-	err := r.db.Preload("Author").Where("post_id = ?", postID).Order("created_at ASC").Find(&comments).Error
+	db := r.db.Unscoped().Preload("Author").Where("post_id = ?", query.PostID).Where(inThread)
+	if query.ParentID == "" {
+		db = db.Where("parent_id IS NULL")
+	} else {
+		db = db.Where("parent_id = ?", query.ParentID)
+	}
+	if len(query.ExcludeAuthorIDs) > 0 {
+		db = db.Where("author_id NOT IN ?", query.ExcludeAuthorIDs)
+	}
+	if query.After != nil {
+		db = db.Where("(created_at > ? OR (created_at = ? AND id > ?))",
+			query.After.CreatedAt, query.After.CreatedAt, query.After.ID)
+	}
+	err := db.Order("created_at ASC, id ASC").Limit(query.Limit).Find(&comments).Error
 	return comments, err
 }
 
@@ -43,8 +100,46 @@ func (r *commentRepository) FindByID(id string) (*models.Comment, error) {
 	return &comment, nil
 }
 
-func (r *commentRepository) Update(comment *models.Comment) error {
-	return r.db.Save(comment).Error
+func (r *commentRepository) FindInThread(id string) (*models.Comment, error) {
+	var comment models.Comment
+	err := r.db.Unscoped().Preload("Author").Where("id = ?", id).Where(inThread).First(&comment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (r *commentRepository) FindByIDs(ids []string) ([]models.Comment, error) {
+	var comments []models.Comment
+	if len(ids) == 0 {
+		return comments, nil
+	}
+	err := r.db.Preload("Author").Where("id IN ?", ids).Find(&comments).Error
+	return comments, err
+}
+
+// FindBatch walks all comments in ID order, limit at a time.
+func (r *commentRepository) FindBatch(afterID string, limit int) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := r.db.Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&comments).Error
+	return comments, err
+}
+
+// Update saves the comment's content if nobody has changed it since it was
+// loaded; on success comment.Version is the new version. The reply count and
+// hidden flag are left to SetReplies and SetHidden.
+func (r *commentRepository) Update(comment *models.Comment) (bool, error) {
+	expected := comment.Version
+	comment.Version++
+	result := r.db.Model(comment).
+		Select("content", "entities", "version", "updated_at").
+		Where("version = ?", expected).
+		Updates(comment)
+	if result.Error != nil || result.RowsAffected == 0 {
+		comment.Version = expected
+		return false, result.Error
+	}
+	return true, nil
 }
 
 func (r *commentRepository) Delete(id string) error {
@@ -53,7 +148,84 @@ func (r *commentRepository) Delete(id string) error {
 
 func (r *commentRepository) CountByPostID(postID string) (int64, error) {
 	var count int64
-	err := r.db.Model(&models.Comment{}).Where("post_id = ?", postID).Count(&count).Error
+	err := r.db.Model(&models.Comment{}).Where("post_id = ? AND hidden = ?", postID, false).Count(&count).Error
 	return count, err
 }
 
+// SetHidden takes a comment out of its post's thread and comment count, or
+// puts it back. Like an edit, it bumps the version.
+func (r *commentRepository) SetHidden(id string, hidden bool) error {
+	return r.db.Model(&models.Comment{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"hidden":  hidden,
+		"version": gorm.Expr("version + 1"),
+	}).Error
+}
+
+func (r *commentRepository) FindDeleted(id string) (*models.Comment, error) {
+	var comment models.Comment
+	err := r.db.Unscoped().Preload("Author").Where("id = ? AND deleted_at IS NOT NULL", id).First(&comment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// FindTrashPage leaves out placeholders whose content was already purged.
+func (r *commentRepository) FindTrashPage(query TrashPageQuery) ([]models.Comment, error) {
+	var comments []models.Comment
+	db := r.db.Unscoped().Where("author_id = ? AND deleted_at IS NOT NULL AND hidden = ? AND content <> ''", query.AuthorID, false)
+	if query.After != nil {
+		db = db.Where("(deleted_at < ? OR (deleted_at = ? AND id < ?))",
+			query.After.CreatedAt, query.After.CreatedAt, query.After.ID)
+	}
+	err := db.Order("deleted_at DESC, id DESC").Limit(query.Limit).Find(&comments).Error
+	return comments, err
+}
+
+func (r *commentRepository) Restore(id string) error {
+	return r.db.Unscoped().Model(&models.Comment{}).Where("id = ?", id).UpdateColumn("deleted_at", nil).Error
+}
+
+// FindPurgeable skips placeholders Purge already emptied; they come back
+// once their last reply is gone.
+func (r *commentRepository) FindPurgeable(deletedBefore time.Time, limit int) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := r.db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+		Where("(replies = 0 OR content <> '')").
+		Order("deleted_at ASC").Limit(limit).Find(&comments).Error
+	return comments, err
+}
+
+// Purge permanently deletes comments, their edit history and reactions. A
+// comment that still has replies keeps its row as an empty placeholder, so
+// the thread below it stays reachable.
+func (r *commentRepository) Purge(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("entity_type = ? AND entity_id IN ?", "comment", ids).Delete(&models.EditHistory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("target_type = ? AND target_id IN ?", models.ReactionTargetComment, ids).Delete(&models.Reaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Comment{}).Where("id IN ? AND replies > 0", ids).
+			UpdateColumns(map[string]interface{}{"content": "", "entities": nil}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN ? AND replies = 0", ids).Delete(&models.Comment{}).Error
+	})
+}
+
+func (r *commentRepository) CountReplies(parentID string) (int64, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&models.Comment{}).Where("parent_id = ?", parentID).Where(inThread).Count(&count).Error
+	return count, err
+}
+
+// SetReplies also works on deleted comments and, being bookkeeping, leaves
+// updated_at and the version alone.
+func (r *commentRepository) SetReplies(id string, replies int) error {
+	return r.db.Unscoped().Model(&models.Comment{}).Where("id = ?", id).UpdateColumn("replies", replies).Error
+}
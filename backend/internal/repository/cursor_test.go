@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		createdAt time.Time
+		id        string
+	}{
+		{"uuid", time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC), "2f1c6b9e-3c1d-4a56-9a0b-7a9d4c3e2b10"},
+		{"id with a colon", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), "a:b"},
+		{"before 1970", time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC), "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := DecodeCursor(EncodeCursor(tt.createdAt, tt.id))
+			if err != nil {
+				t.Fatalf("DecodeCursor: %v", err)
+			}
+			if !cursor.CreatedAt.Equal(tt.createdAt) || cursor.ID != tt.id {
+				t.Errorf("got (%v, %q), want (%v, %q)", cursor.CreatedAt, cursor.ID, tt.createdAt, tt.id)
+			}
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+	tests := []struct {
+		name    string
+		cursor  string
+		wantNil bool
+		wantErr error
+	}{
+		{"empty is the first page", "", true, nil},
+		{"not base64", "!!!", true, ErrInvalidCursor},
+		{"no separator", encode("1700000000"), true, ErrInvalidCursor},
+		{"no id", encode("1700000000:"), true, ErrInvalidCursor},
+		{"bad time", encode("soon:abc"), true, ErrInvalidCursor},
+		{"valid", encode("1700000000:abc"), false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := DecodeCursor(tt.cursor)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("DecodeCursor(%q) error = %v, want %v", tt.cursor, err, tt.wantErr)
+			}
+			if (cursor == nil) != tt.wantNil {
+				t.Errorf("DecodeCursor(%q) = %+v, want nil: %v", tt.cursor, cursor, tt.wantNil)
+			}
+		})
+	}
+}
@@ -6,7 +6,9 @@ import (
 )
 
 type EditHistoryRepository interface {
+	// Create gives the entry the next version number for its entity.
 	Create(history *models.EditHistory) error
+	FindByID(id string) (*models.EditHistory, error)
 	FindByEntity(entityType, entityID string) ([]models.EditHistory, error)
 }
 
@@ -19,15 +21,41 @@ func NewEditHistoryRepository(db *gorm.DB) EditHistoryRepository {
 }
 
 func (r *editHistoryRepository) Create(history *models.EditHistory) error {
-	return r.db.Create(history).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.EditHistory{}).
+			Where("entity_type = ? AND entity_id = ?", history.EntityType, history.EntityID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		history.Version = int(count) + 1
+		return tx.Create(history).Error
+	})
 }
 
+func (r *editHistoryRepository) FindByID(id string) (*models.EditHistory, error) {
+	var history models.EditHistory
+	err := r.db.Where("id = ?", id).First(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// FindByEntity returns the entity's history newest first.
 func (r *editHistoryRepository) FindByEntity(entityType, entityID string) ([]models.EditHistory, error) {
 	var histories []models.EditHistory
 	err := r.db.Where("entity_type = ? AND entity_id = ?", entityType, entityID).
 		Order("created_at DESC").
 		Find(&histories).Error
-	return histories, err
+	if err != nil {
+		return nil, err
+	}
+	// Entries from before versions were stored are numbered by position
+	for i := range histories {
+		if histories[i].Version == 0 {
+			histories[i].Version = len(histories) - i
+		}
+	}
+	return histories, nil
 }
-
-
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"typinggame-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// TagCount is one row of the trending hashtags list.
+type TagCount struct {
+	Tag     string `json:"tag"`
+	Uses    int64  `json:"uses"`
+	Authors int64  `json:"authors"`
+}
+
+// TrendingQuery selects the tags to rank. Only uses in posts the viewer may
+// see, and in comments on them, are counted.
+type TrendingQuery struct {
+	ViewerID         string
+	ExcludeAuthorIDs []string // e.g. users blocked by or blocking the viewer
+	Since            time.Time
+	Limit            int
+}
+
+// MentionPageQuery selects one page of mentions of a user, newest first.
+type MentionPageQuery struct {
+	UserID           string
+	ExcludeAuthorIDs []string
+	After            *Cursor
+	Limit            int
+}
+
+// EntityRepository stores the hashtag and mention index of posts and comments.
+type EntityRepository interface {
+	ReplaceForSource(sourceType, sourceID string, tags []models.HashtagUse, mentions []models.Mention) error
+	DeleteForSource(sourceType, sourceID string) error
+	Trending(query TrendingQuery) ([]TagCount, error)
+	FindMentions(query MentionPageQuery) ([]models.Mention, error)
+}
+
+type entityRepository struct {
+	db *gorm.DB
+}
+
+func NewEntityRepository(db *gorm.DB) EntityRepository {
+	return &entityRepository{db: db}
+}
+
+// ReplaceForSource swaps the index rows of one post or comment in a single
+// transaction, so an edit never leaves a mix of old and new entities.
+func (r *entityRepository) ReplaceForSource(sourceType, sourceID string, tags []models.HashtagUse, mentions []models.Mention) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteForSource(tx, sourceType, sourceID); err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			if err := tx.Create(&tags).Error; err != nil {
+				return err
+			}
+		}
+		if len(mentions) > 0 {
+			for i := range mentions {
+				mentions[i].ID = uuid.New().String()
+			}
+			if err := tx.Omit("Author").Create(&mentions).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *entityRepository) DeleteForSource(sourceType, sourceID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return deleteForSource(tx, sourceType, sourceID)
+	})
+}
+
+func deleteForSource(tx *gorm.DB, sourceType, sourceID string) error {
+	if err := tx.Where("source_type = ? AND source_id = ?", sourceType, sourceID).Delete(&models.HashtagUse{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("source_type = ? AND source_id = ?", sourceType, sourceID).Delete(&models.Mention{}).Error
+}
+
+// Trending ranks tags by how many different people used them since the given
+// time, so one account repeating a tag can't push it up alone. Tags are
+// counted through the post they belong to, so uses in posts that are
+// private, unpublished, hidden or deleted don't leak into the list.
+func (r *entityRepository) Trending(query TrendingQuery) ([]TagCount, error) {
+	var counts []TagCount
+	db := r.db.Model(&models.HashtagUse{}).
+		Select("hashtag_uses.tag, COUNT(*) AS uses, COUNT(DISTINCT hashtag_uses.author_id) AS authors").
+		Joins("JOIN posts ON posts.id = hashtag_uses.post_id").
+		Where("hashtag_uses.created_at >= ?", query.Since).
+		Where("posts.status = ? AND posts.hidden = ? AND posts.deleted_at IS NULL", models.PostStatusPublished, false)
+	db = visibleTo(r.db, db, query.ViewerID)
+
+	if len(query.ExcludeAuthorIDs) > 0 {
+		db = db.Where("hashtag_uses.author_id NOT IN ? AND posts.author_id NOT IN ?", query.ExcludeAuthorIDs, query.ExcludeAuthorIDs)
+	}
+
+	err := db.Group("hashtag_uses.tag").
+		Order("authors DESC, uses DESC, hashtag_uses.tag ASC").
+		Limit(query.Limit).
+		Scan(&counts).Error
+	return counts, err
+}
+
+func (r *entityRepository) FindMentions(query MentionPageQuery) ([]models.Mention, error) {
+	var mentions []models.Mention
+	db := r.db.Preload("Author").Where("mentioned_user_id = ?", query.UserID)
+
+	if len(query.ExcludeAuthorIDs) > 0 {
+		db = db.Where("author_id NOT IN ?", query.ExcludeAuthorIDs)
+	}
+	if query.After != nil {
+		db = db.Where("(created_at < ? OR (created_at = ? AND id < ?))",
+			query.After.CreatedAt, query.After.CreatedAt, query.After.ID)
+	}
+
+	err := db.Order("created_at DESC, id DESC").Limit(query.Limit).Find(&mentions).Error
+	return mentions, err
+}
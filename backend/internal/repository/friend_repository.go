@@ -19,6 +19,7 @@ type FriendRepository interface {
 	UnblockUser(userID, blockedUserID string) error
 	IsBlocked(userID, blockedUserID string) (bool, error)
 	GetBlockedUsers(userID string) ([]models.User, error)
+	GetBlockRelatedUserIDs(userID string) ([]string, error)
 }
 
 type friendRepository struct {
@@ -129,7 +130,7 @@ func (r *friendRepository) SearchUsers(query string, excludeUserID string, limit
 	}
 	
 	// Build query excluding blocked users
-	queryBuilder := r.db.Where("(name LIKE ? OR email LIKE ?) AND id != ?", "%"+query+"%", "%"+query+"%", excludeUserID)
+	queryBuilder := r.db.Where("(name LIKE ? OR email LIKE ?) AND id != ? AND is_guest = ?", "%"+query+"%", "%"+query+"%", excludeUserID, false)
 	
 	// Exclude blocked users if any
 	if len(blockedIDs) > 0 {
@@ -194,3 +195,23 @@ func (r *friendRepository) GetBlockedUsers(userID string) ([]models.User, error)
 	return users, nil
 }
 
+
+// GetBlockRelatedUserIDs returns everyone userID has blocked or been blocked by.
+func (r *friendRepository) GetBlockRelatedUserIDs(userID string) ([]string, error) {
+	var ids []string
+	err := r.db.Raw(
+		"SELECT blocked_user_id FROM blocked_users WHERE user_id = ? "+
+			"UNION SELECT user_id FROM blocked_users WHERE blocked_user_id = ?",
+		userID, userID).
+		Scan(&ids).Error
+	return ids, err
+}
+
+// acceptedFriendIDs is a subquery selecting the IDs of userID's friends, for
+// use in "author_id IN (?)" filters.
+func acceptedFriendIDs(db *gorm.DB, userID string) *gorm.DB {
+	return db.Raw(
+		"SELECT receiver_id FROM friend_requests WHERE requester_id = ? AND status = ? "+
+			"UNION SELECT requester_id FROM friend_requests WHERE receiver_id = ? AND status = ?",
+		userID, "accepted", userID, "accepted")
+}
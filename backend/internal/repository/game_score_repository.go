@@ -1,14 +1,28 @@
 package repository
 
 import (
+	"time"
+
 	"typinggame-api/internal/models"
 	"gorm.io/gorm"
 )
 
+// LeaderboardQuery narrows the public leaderboard. Zero values mean no filter.
+// Passage runs are scored differently and have their own boards, see
+// GetPassageLeaderboard.
+type LeaderboardQuery struct {
+	Difficulty string
+	Since      time.Time
+	UserIDs    []string // only scores by these players, e.g. a friends board
+	Limit      int
+}
+
 type GameScoreRepository interface {
 	Create(score *models.GameScore) error
-	GetTopScores(limit int) ([]models.GameScore, error)
-	GetTopScoresByDifficulty(difficulty string, limit int) ([]models.GameScore, error)
+	FindByID(id string) (*models.GameScore, error)
+	FindByClientID(userID, clientID string) (*models.GameScore, error)
+	GetLeaderboard(query LeaderboardQuery) ([]models.GameScore, error)
+	GetPassageLeaderboard(passageID string, minAccuracy float64, limit int) ([]models.GameScore, error)
 	GetUserBestScore(userID string) (*models.GameScore, error)
 	GetUserScores(userID string, limit int) ([]models.GameScore, error)
 }
@@ -25,29 +39,67 @@ func (r *gameScoreRepository) Create(score *models.GameScore) error {
 	return r.db.Create(score).Error
 }
 
-func (r *gameScoreRepository) GetTopScores(limit int) ([]models.GameScore, error) {
+func (r *gameScoreRepository) FindByID(id string) (*models.GameScore, error) {
+	var score models.GameScore
+	err := r.db.Preload("User").Where("id = ?", id).First(&score).Error
+	if err != nil {
+		return nil, err
+	}
+	return &score, nil
+}
+
+func (r *gameScoreRepository) FindByClientID(userID, clientID string) (*models.GameScore, error) {
+	var score models.GameScore
+	err := r.db.Where("user_id = ? AND client_id = ?", userID, clientID).First(&score).Error
+	if err != nil {
+		return nil, err
+	}
+	return &score, nil
+}
+
+func (r *gameScoreRepository) GetLeaderboard(query LeaderboardQuery) ([]models.GameScore, error) {
 	var scores []models.GameScore
-	err := r.db.Preload("User").
-		Order("score DESC, words_typed DESC").
-		Limit(limit).
+	db := r.db.Preload("User").Where("is_guest = ? AND flagged = ? AND passage_id IS NULL", false, false)
+
+	if query.Difficulty != "" {
+		db = db.Where("difficulty = ?", query.Difficulty)
+	}
+	if !query.Since.IsZero() {
+		db = db.Where("COALESCE(played_at, created_at) >= ?", query.Since)
+	}
+	if query.UserIDs != nil {
+		if len(query.UserIDs) == 0 {
+			return scores, nil
+		}
+		db = db.Where("user_id IN ?", query.UserIDs)
+	}
+
+	err := db.Order("score DESC, words_typed DESC, created_at ASC").
+		Limit(query.Limit).
 		Find(&scores).Error
 	return scores, err
 }
 
-func (r *gameScoreRepository) GetTopScoresByDifficulty(difficulty string, limit int) ([]models.GameScore, error) {
+// GetPassageLeaderboard ranks runs of a single passage by net WPM, the
+// speed scaled by accuracy. Runs below minAccuracy are left out; accuracy
+// counts untyped characters as errors, so this also keeps out runs that
+// stopped partway.
+func (r *gameScoreRepository) GetPassageLeaderboard(passageID string, minAccuracy float64, limit int) ([]models.GameScore, error) {
 	var scores []models.GameScore
 	err := r.db.Preload("User").
-		Where("difficulty = ?", difficulty).
-		Order("score DESC, words_typed DESC").
+		Where("passage_id = ? AND is_guest = ? AND flagged = ? AND accuracy >= ?", passageID, false, false, minAccuracy).
+		Order("wpm * accuracy DESC, accuracy DESC, created_at ASC").
 		Limit(limit).
 		Find(&scores).Error
 	return scores, err
 }
 
+// GetUserBestScore and GetUserScores cover word mode only; a passage run's
+// score counts characters and isn't comparable.
 func (r *gameScoreRepository) GetUserBestScore(userID string) (*models.GameScore, error) {
 	var score models.GameScore
 	err := r.db.Preload("User").
-		Where("user_id = ?", userID).
+		Where("user_id = ? AND passage_id IS NULL", userID).
 		Order("score DESC, words_typed DESC").
 		First(&score).Error
 	if err != nil {
@@ -59,7 +111,7 @@ func (r *gameScoreRepository) GetUserBestScore(userID string) (*models.GameScore
 func (r *gameScoreRepository) GetUserScores(userID string, limit int) ([]models.GameScore, error) {
 	var scores []models.GameScore
 	err := r.db.Preload("User").
-		Where("user_id = ?", userID).
+		Where("user_id = ? AND passage_id IS NULL", userID).
 		Order("score DESC, words_typed DESC").
 		Limit(limit).
 		Find(&scores).Error
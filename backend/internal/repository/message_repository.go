@@ -14,6 +14,9 @@ type MessageRepository interface {
 	GetMessages(conversationID string, limit, offset int) ([]models.Message, error)
 	GetUnreadCount(userID, conversationID string) (int64, error)
 	MarkAsRead(conversationID, userID string) error
+	FindMessageByID(id string) (*models.Message, error)
+	SetMessageHidden(id string, hidden bool) error
+	DeleteMessage(id string) error
 }
 
 type messageRepository struct {
@@ -77,7 +80,7 @@ func (r *messageRepository) CreateMessage(message *models.Message) error {
 
 func (r *messageRepository) GetMessages(conversationID string, limit, offset int) ([]models.Message, error) {
 	var messages []models.Message
-	query := r.db.Where("conversation_id = ?", conversationID).
+	query := r.db.Where("conversation_id = ? AND hidden = ?", conversationID, false).
 		Preload("Sender").
 		Order("created_at DESC")
 	
@@ -95,7 +98,7 @@ func (r *messageRepository) GetMessages(conversationID string, limit, offset int
 func (r *messageRepository) GetUnreadCount(userID, conversationID string) (int64, error) {
 	var count int64
 	err := r.db.Model(&models.Message{}).
-		Where("conversation_id = ? AND sender_id != ? AND is_read = ?", conversationID, userID, false).
+		Where("conversation_id = ? AND sender_id != ? AND is_read = ? AND hidden = ?", conversationID, userID, false, false).
 		Count(&count).Error
 	return count, err
 }
@@ -106,3 +109,25 @@ func (r *messageRepository) MarkAsRead(conversationID, userID string) error {
 		Update("is_read", true).Error
 }
 
+func (r *messageRepository) FindMessageByID(id string) (*models.Message, error) {
+	var message models.Message
+	err := r.db.Where("id = ?", id).First(&message).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (r *messageRepository) SetMessageHidden(id string, hidden bool) error {
+	return r.db.Model(&models.Message{}).Where("id = ?", id).Update("hidden", hidden).Error
+}
+
+// DeleteMessage deletes the message and its reactions.
+func (r *messageRepository) DeleteMessage(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("target_type = ? AND target_id = ?", models.ReactionTargetMessage, id).Delete(&models.Reaction{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Message{}, "id = ?", id).Error
+	})
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"typinggame-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type PassageRepository interface {
+	Create(passage *models.Passage) error
+	ExistsByHash(hash string) (bool, error)
+	FindByID(id string) (*models.Passage, error)
+	FindRandom(language, lengthBucket string) (*models.Passage, error)
+}
+
+type passageRepository struct {
+	db *gorm.DB
+}
+
+func NewPassageRepository(db *gorm.DB) PassageRepository {
+	return &passageRepository{db: db}
+}
+
+func (r *passageRepository) Create(passage *models.Passage) error {
+	return r.db.Create(passage).Error
+}
+
+func (r *passageRepository) ExistsByHash(hash string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Passage{}).Where("content_hash = ?", hash).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *passageRepository) FindByID(id string) (*models.Passage, error) {
+	var passage models.Passage
+	err := r.db.Where("id = ?", id).First(&passage).Error
+	if err != nil {
+		return nil, err
+	}
+	return &passage, nil
+}
+
+func (r *passageRepository) FindRandom(language, lengthBucket string) (*models.Passage, error) {
+	var passage models.Passage
+	query := r.db.Model(&models.Passage{})
+	if language != "" {
+		query = query.Where("language = ?", language)
+	}
+	if lengthBucket != "" {
+		query = query.Where("length_bucket = ?", lengthBucket)
+	}
+
+	// The corpus is small enough that RAND() is fine
+	err := query.Order("RAND()").Take(&passage).Error
+	if err != nil {
+		return nil, err
+	}
+	return &passage, nil
+}
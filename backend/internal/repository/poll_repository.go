@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"typinggame-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PollRepository interface {
+	FindByPostID(postID string) (*models.Poll, error)
+	FindByPostIDs(postIDs []string) ([]models.Poll, error)
+	FindUserVotes(userID string, pollIDs []string) ([]models.PollVote, error)
+	Vote(pollID, userID string, optionIDs []string) (bool, error)
+	CloseDue(now time.Time) (int64, error)
+}
+
+type pollRepository struct {
+	db *gorm.DB
+}
+
+func NewPollRepository(db *gorm.DB) PollRepository {
+	return &pollRepository{db: db}
+}
+
+func preloadOptions(db *gorm.DB) *gorm.DB {
+	return db.Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	})
+}
+
+func (r *pollRepository) FindByPostID(postID string) (*models.Poll, error) {
+	var poll models.Poll
+	err := preloadOptions(r.db).Where("post_id = ?", postID).First(&poll).Error
+	if err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+func (r *pollRepository) FindByPostIDs(postIDs []string) ([]models.Poll, error) {
+	var polls []models.Poll
+	if len(postIDs) == 0 {
+		return polls, nil
+	}
+	err := preloadOptions(r.db).Where("post_id IN ?", postIDs).Find(&polls).Error
+	return polls, err
+}
+
+func (r *pollRepository) FindUserVotes(userID string, pollIDs []string) ([]models.PollVote, error) {
+	var votes []models.PollVote
+	if len(pollIDs) == 0 {
+		return votes, nil
+	}
+	err := r.db.Where("user_id = ? AND poll_id IN ?", userID, pollIDs).Find(&votes).Error
+	return votes, err
+}
+
+// Vote records the user's choices and bumps the tallies, and reports false
+// if the user had already voted. The poll row is locked for the check so
+// two concurrent requests from one user can't both count.
+func (r *pollRepository) Vote(pollID, userID string, optionIDs []string) (bool, error) {
+	voted := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var poll models.Poll
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", pollID).First(&poll).Error; err != nil {
+			return err
+		}
+
+		var existing int64
+		if err := tx.Model(&models.PollVote{}).Where("poll_id = ? AND user_id = ?", pollID, userID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return nil
+		}
+
+		votes := make([]models.PollVote, 0, len(optionIDs))
+		for _, optionID := range optionIDs {
+			votes = append(votes, models.PollVote{ID: uuid.New().String(), PollID: pollID, UserID: userID, OptionID: optionID})
+		}
+		if err := tx.Create(&votes).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PollOption{}).Where("id IN ?", optionIDs).
+			Update("votes", gorm.Expr("votes + 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&poll).Update("voters", gorm.Expr("voters + 1")).Error; err != nil {
+			return err
+		}
+		voted = true
+		return nil
+	})
+	return voted, err
+}
+
+// CloseDue marks polls past their closing time as closed and returns how
+// many it marked. Only open polls are updated, so several servers can run it
+// at once.
+func (r *pollRepository) CloseDue(now time.Time) (int64, error) {
+	result := r.db.Model(&models.Poll{}).
+		Where("closed_at IS NULL AND closes_at <= ?", now).
+		Update("closed_at", gorm.Expr("closes_at"))
+	return result.RowsAffected, result.Error
+}
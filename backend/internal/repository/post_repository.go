@@ -1,23 +1,83 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"typinggame-api/internal/models"
 	"gorm.io/gorm"
 )
 
+// Post orderings other than newest first.
+const (
+	PostSortTop = "top" // most engagement
+	PostSortHot = "hot" // highest hot score
+)
+
+// engagementScore weighs the interactions on a post. Comments and reposts
+// take more effort than a reaction, so they count for more.
+const engagementScore = "(posts.likes + 2 * posts.comments + 3 * posts.reposts)"
+
+// hotScoreGravity controls how fast hot scores decay with age: a post's
+// engagement is divided by (age in hours + 2) raised to this power.
+const hotScoreGravity = 1.5
+
+// PostPageQuery selects one page of posts, newest first unless Sort says
+// otherwise.
+type PostPageQuery struct {
+	ViewerID         string     // only posts this user may see
+	ExcludeAuthorIDs []string   // e.g. users blocked by or blocking the viewer
+	AuthorID         string     // empty for every author
+	FriendsOf        string     // only posts by this user and their friends
+	Hashtag          string     // only posts tagged with this normalized tag
+	Statuses         []string   // only posts in these states; published posts when empty
+	Since            *time.Time // only posts created at or after this time
+	Sort             string     // empty for newest first, or PostSortTop / PostSortHot
+	After            *Cursor    // nil for the first page; newest first only
+	Offset           int        // rows to skip; ranked orderings only
+	Limit            int
+}
+
+// TrashPageQuery selects one page of a user's deleted posts or comments,
+// most recently deleted first. After is a cursor over the deletion time.
+// Content a moderator hid is left out, so what a moderator removed can't be
+// restored.
+type TrashPageQuery struct {
+	AuthorID string
+	After    *Cursor
+	Limit    int
+}
+
 type PostRepository interface {
 	Create(post *models.Post) error
-	FindAll() ([]models.Post, error)
+	FindPage(query PostPageQuery) ([]models.Post, error)
 	FindByID(id string) (*models.Post, error)
-	FindByAuthorID(authorID string) ([]models.Post, error)
-	Update(post *models.Post) error
+	FindByIDs(ids []string) ([]models.Post, error)
+	FindWithDetailsByIDs(ids []string) ([]models.Post, error)
+	FindBatch(afterID string, limit int) ([]models.Post, error)
+	// Update reports false, and changes nothing, when the post is no longer
+	// at post.Version.
+	Update(post *models.Post) (bool, error)
 	Delete(id string) error
-	ReactToPost(postID, userID, reaction string) error
-	GetUserReaction(postID, userID string) (string, error)
-	GetReactionsCount(postID string) (map[string]int64, error)
+	AddAttachments(attachments []models.PostAttachment) error
+	DeleteAttachments(postID string, ids []string) error
 	UpdateLikesCount(postID string) error
 	UpdateCommentsCount(postID string, count int) error
+	FindPlainRepost(authorID, originalID string) (*models.Post, error)
+	// EnsurePlainRepostIndex adds the unique index that keeps each user to
+	// one plain repost of a post, first deleting all but the oldest of any
+	// duplicates, and returns how many it deleted.
+	EnsurePlainRepostIndex() (int64, error)
+	FindDueScheduled(now time.Time, limit int) ([]models.Post, error)
+	ClaimScheduled(postID string, publishedAt time.Time) (bool, error)
+	UpdateRepostsCount(postID string) error
+	RefreshHotScores(since, now time.Time) (int64, error)
+	SetHidden(id string, hidden bool) error
+	FindDeleted(id string) (*models.Post, error)
+	FindTrashPage(query TrashPageQuery) ([]models.Post, error)
+	Restore(id string) error
+	FindPurgeable(deletedBefore time.Time, limit int) ([]models.Post, error)
+	Purge(ids []string) ([]string, error)
 }
 
 type postRepository struct {
@@ -30,98 +90,349 @@ func NewPostRepository(db *gorm.DB) PostRepository {
 
 func (r *postRepository) Create(post *models.Post) error {
 	post.ID = uuid.New().String()
+	post.Version = 1
 	return r.db.Create(post).Error
 }
 
-func (r *postRepository) FindAll() ([]models.Post, error) {
+func (r *postRepository) FindPage(query PostPageQuery) ([]models.Post, error) {
 	var posts []models.Post
-	err := r.db.Preload("Author").Order("created_at DESC").Find(&posts).Error
+	db := preloadAttachments(r.db.Preload("Author"))
+
+	if query.ViewerID != "" {
+		db = visibleTo(r.db, db, query.ViewerID)
+	}
+	statuses := query.Statuses
+	if len(statuses) == 0 {
+		statuses = []string{models.PostStatusPublished}
+	}
+	db = db.Where("status IN ? AND hidden = ?", statuses, false)
+	if len(query.ExcludeAuthorIDs) > 0 {
+		db = db.Where("author_id NOT IN ?", query.ExcludeAuthorIDs)
+	}
+	if query.AuthorID != "" {
+		db = db.Where("author_id = ?", query.AuthorID)
+	}
+	if query.FriendsOf != "" {
+		db = db.Where("(author_id = ? OR author_id IN (?))", query.FriendsOf, acceptedFriendIDs(r.db, query.FriendsOf))
+	}
+	if query.Hashtag != "" {
+		db = db.Where("id IN (?)", r.db.Model(&models.HashtagUse{}).Select("source_id").
+			Where("tag = ? AND source_type = ?", query.Hashtag, models.EntitySourcePost))
+	}
+	if query.Since != nil {
+		db = db.Where("created_at >= ?", *query.Since)
+	}
+
+	switch query.Sort {
+	case PostSortTop:
+		db = db.Order(engagementScore + " DESC").Offset(query.Offset)
+	case PostSortHot:
+		db = db.Order("hot_score DESC").Offset(query.Offset)
+	default:
+		if query.After != nil {
+			db = db.Where("(created_at < ? OR (created_at = ? AND id < ?))",
+				query.After.CreatedAt, query.After.CreatedAt, query.After.ID)
+		}
+	}
+
+	err := db.Order("created_at DESC, id DESC").Limit(query.Limit).Find(&posts).Error
 	return posts, err
 }
 
+// visibleTo limits a posts query to what viewerID is allowed to see: public
+// posts, their own posts, and friends-only posts by their friends.
+func visibleTo(root, db *gorm.DB, viewerID string) *gorm.DB {
+	return db.Where("(posts.visibility = ? OR posts.author_id = ? OR (posts.visibility = ? AND posts.author_id IN (?)))",
+		models.PostVisibilityPublic, viewerID, models.PostVisibilityFriends, acceptedFriendIDs(root, viewerID))
+}
+
 func (r *postRepository) FindByID(id string) (*models.Post, error) {
 	var post models.Post
-	err := r.db.Preload("Author").Where("id = ?", id).First(&post).Error
+	err := preloadAttachments(r.db.Preload("Author")).Where("id = ?", id).First(&post).Error
 	if err != nil {
 		return nil, err
 	}
 	return &post, nil
 }
 
-func (r *postRepository) FindByAuthorID(authorID string) ([]models.Post, error) {
+func preloadAttachments(db *gorm.DB) *gorm.DB {
+	return db.Preload("Attachments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	})
+}
+
+// FindByIDs loads posts without their author or attachments, for checks
+// that only need the post row.
+func (r *postRepository) FindByIDs(ids []string) ([]models.Post, error) {
 	var posts []models.Post
-	err := r.db.Preload("Author").Where("author_id = ?", authorID).Order("created_at DESC").Find(&posts).Error
+	if len(ids) == 0 {
+		return posts, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&posts).Error
+	return posts, err
+}
+
+// FindWithDetailsByIDs loads posts with their author and attachments, in no
+// particular order.
+func (r *postRepository) FindWithDetailsByIDs(ids []string) ([]models.Post, error) {
+	var posts []models.Post
+	if len(ids) == 0 {
+		return posts, nil
+	}
+	err := preloadAttachments(r.db.Preload("Author")).Where("id IN ?", ids).Find(&posts).Error
 	return posts, err
 }
 
-func (r *postRepository) Update(post *models.Post) error {
-	return r.db.Save(post).Error
+// FindBatch walks all posts in ID order, limit at a time, for rebuilding
+// derived data such as the search index.
+func (r *postRepository) FindBatch(afterID string, limit int) ([]models.Post, error) {
+	var posts []models.Post
+	err := r.db.Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&posts).Error
+	return posts, err
+}
+
+// Update saves the columns an edit changes; attachments change through
+// AddAttachments and DeleteAttachments, and polls can't be changed. Counters,
+// the hot score and the hidden flag are left alone, so an edit never undoes
+// what moderators or background jobs wrote in the meantime. The version
+// check makes it a compare-and-swap, so of two edits made from the same
+// version only the first is saved. On success post.Version is the new
+// version.
+func (r *postRepository) Update(post *models.Post) (bool, error) {
+	expected := post.Version
+	post.Version++
+	result := r.db.Model(post).
+		Select("content", "entities", "visibility", "status", "publish_at", "created_at", "version", "updated_at").
+		Where("version = ?", expected).
+		Updates(post)
+	if result.Error != nil || result.RowsAffected == 0 {
+		post.Version = expected
+		return false, result.Error
+	}
+	return true, nil
+}
+
+// SetHidden takes a post out of every listing, or puts it back; its author
+// can always open it. It bumps the version, so an edit started before the
+// change is refused as stale.
+func (r *postRepository) SetHidden(id string, hidden bool) error {
+	return r.db.Model(&models.Post{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"hidden":  hidden,
+		"version": gorm.Expr("version + 1"),
+	}).Error
 }
 
 func (r *postRepository) Delete(id string) error {
 	return r.db.Delete(&models.Post{}, "id = ?", id).Error
 }
 
-func (r *postRepository) ReactToPost(postID, userID, reaction string) error {
-	var existing models.PostReaction
-	err := r.db.Where("post_id = ? AND user_id = ?", postID, userID).First(&existing).Error
-	
-	if err == gorm.ErrRecordNotFound {
-		// Create new reaction
-		newReaction := models.PostReaction{
-			ID:       uuid.New().String(),
-			PostID:   postID,
-			UserID:   userID,
-			Reaction: reaction,
-		}
-		return r.db.Create(&newReaction).Error
-	} else if err == nil {
-		// Update existing reaction
-		if existing.Reaction == reaction {
-			// Same reaction, remove it (unlike)
-			return r.db.Delete(&existing).Error
-		} else {
-			// Different reaction, update it
-			existing.Reaction = reaction
-			return r.db.Save(&existing).Error
-		}
+func (r *postRepository) AddAttachments(attachments []models.PostAttachment) error {
+	if len(attachments) == 0 {
+		return nil
 	}
-	return err
+	return r.db.Create(&attachments).Error
 }
 
-func (r *postRepository) GetUserReaction(postID, userID string) (string, error) {
-	var reaction models.PostReaction
-	err := r.db.Where("post_id = ? AND user_id = ?", postID, userID).First(&reaction).Error
-	if err == gorm.ErrRecordNotFound {
-		return "", nil // No reaction
+func (r *postRepository) DeleteAttachments(postID string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
 	}
-	if err != nil {
-		return "", err
+	return r.db.Where("post_id = ? AND id IN ?", postID, ids).Delete(&models.PostAttachment{}).Error
+}
+
+// UpdateLikesCount stores the post's total number of reactions of any kind.
+func (r *postRepository) UpdateLikesCount(postID string) error {
+	var count int64
+	if err := r.db.Model(&models.Reaction{}).Where("target_type = ? AND target_id = ?", models.ReactionTargetPost, postID).Count(&count).Error; err != nil {
+		return err
 	}
-	return reaction.Reaction, nil
+	return r.db.Model(&models.Post{}).Where("id = ?", postID).Update("likes", count).Error
 }
 
-func (r *postRepository) GetReactionsCount(postID string) (map[string]int64, error) {
-	var reactions []models.PostReaction
-	err := r.db.Where("post_id = ?", postID).Find(&reactions).Error
+func (r *postRepository) UpdateCommentsCount(postID string, count int) error {
+	return r.db.Model(&models.Post{}).Where("id = ?", postID).Update("comments", count).Error
+}
+
+// FindPlainRepost returns authorID's plain repost of a post, if there is one.
+// Creating or restoring a second one fails on plainRepostIndex.
+func (r *postRepository) FindPlainRepost(authorID, originalID string) (*models.Post, error) {
+	var post models.Post
+	err := r.db.Where("author_id = ? AND repost_of_id = ? AND content = ''", authorID, originalID).First(&post).Error
 	if err != nil {
 		return nil, err
 	}
+	return &post, nil
+}
+
+// plainRepostIndex is unique on (author_id, plain_repost_of_id). The column
+// is generated: it holds repost_of_id for live plain reposts and NULL
+// otherwise, and MySQL lets any number of NULLs through a unique index.
+const plainRepostIndex = "idx_posts_author_plain_repost"
+
+func (r *postRepository) EnsurePlainRepostIndex() (int64, error) {
+	migrator := r.db.Migrator()
+	if !migrator.HasColumn(&models.Post{}, "plain_repost_of_id") {
+		err := r.db.Exec(`ALTER TABLE posts ADD COLUMN plain_repost_of_id varchar(36)
+			GENERATED ALWAYS AS (CASE WHEN content = '' AND deleted_at IS NULL THEN repost_of_id END) STORED`).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+	if migrator.HasIndex(&models.Post{}, plainRepostIndex) {
+		return 0, nil
+	}
 
-	counts := make(map[string]int64)
-	for _, r := range reactions {
-		counts[r.Reaction]++
+	// Reposts made before the index existed could race past the check in
+	// PostService.Repost; keep the oldest of each and recount the originals
+	var originalIDs []string
+	err := r.db.Raw(`SELECT DISTINCT plain_repost_of_id FROM posts
+		WHERE plain_repost_of_id IS NOT NULL
+		GROUP BY author_id, plain_repost_of_id HAVING COUNT(*) > 1`).Scan(&originalIDs).Error
+	if err != nil {
+		return 0, err
+	}
+	result := r.db.Exec(`UPDATE posts p JOIN posts q
+		ON q.author_id = p.author_id AND q.plain_repost_of_id = p.plain_repost_of_id
+		AND (q.created_at < p.created_at OR (q.created_at = p.created_at AND q.id < p.id))
+		SET p.deleted_at = ?`, time.Now())
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	for _, id := range originalIDs {
+		if err := r.UpdateRepostsCount(id); err != nil {
+			return result.RowsAffected, err
+		}
 	}
-	return counts, nil
+	err = r.db.Exec("CREATE UNIQUE INDEX " + plainRepostIndex + " ON posts (author_id, plain_repost_of_id)").Error
+	return result.RowsAffected, err
 }
 
-func (r *postRepository) UpdateLikesCount(postID string) error {
+// UpdateRepostsCount recounts the reposts and quotes of a post.
+func (r *postRepository) UpdateRepostsCount(postID string) error {
 	var count int64
-	r.db.Model(&models.PostReaction{}).Where("post_id = ?", postID).Count(&count)
-	return r.db.Model(&models.Post{}).Where("id = ?", postID).Update("likes", count).Error
+	if err := r.db.Model(&models.Post{}).Where("repost_of_id = ?", postID).Count(&count).Error; err != nil {
+		return err
+	}
+	return r.db.Model(&models.Post{}).Where("id = ?", postID).Update("reposts", count).Error
 }
 
-func (r *postRepository) UpdateCommentsCount(postID string, count int) error {
-	return r.db.Model(&models.Post{}).Where("id = ?", postID).Update("comments", count).Error
+// FindDueScheduled returns scheduled posts whose publish time has come,
+// oldest first.
+func (r *postRepository) FindDueScheduled(now time.Time, limit int) ([]models.Post, error) {
+	var posts []models.Post
+	err := r.db.Where("status = ? AND publish_at <= ?", models.PostStatusScheduled, now).
+		Order("publish_at ASC").Limit(limit).Find(&posts).Error
+	return posts, err
+}
+
+// ClaimScheduled publishes a scheduled post and reports whether this call
+// did it. The status check makes the update a compare-and-swap, so when
+// several servers see the same due post only one of them publishes it. It
+// bumps the version like an edit would.
+func (r *postRepository) ClaimScheduled(postID string, publishedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.Post{}).
+		Where("id = ? AND status = ?", postID, models.PostStatusScheduled).
+		Updates(map[string]interface{}{
+			"status":     models.PostStatusPublished,
+			"created_at": publishedAt,
+			"version":    gorm.Expr("version + 1"),
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// RefreshHotScores recomputes the hot score of published posts created since
+// since, as of now, and returns how many posts it updated. Older posts keep
+// their last score; hot listings never reach back that far.
+func (r *postRepository) RefreshHotScores(since, now time.Time) (int64, error) {
+	result := r.db.Model(&models.Post{}).
+		Where("status = ? AND created_at >= ?", models.PostStatusPublished, since).
+		UpdateColumn("hot_score", gorm.Expr(
+			engagementScore+" / POW(GREATEST(TIMESTAMPDIFF(SECOND, posts.created_at, ?), 0) / 3600 + 2, ?)",
+			now, hotScoreGravity))
+	return result.RowsAffected, result.Error
 }
 
+// FindDeleted loads a soft-deleted post with its attachments.
+func (r *postRepository) FindDeleted(id string) (*models.Post, error) {
+	var post models.Post
+	err := preloadAttachments(r.db.Unscoped().Preload("Author")).
+		Where("id = ? AND deleted_at IS NOT NULL", id).First(&post).Error
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+func (r *postRepository) FindTrashPage(query TrashPageQuery) ([]models.Post, error) {
+	var posts []models.Post
+	db := r.db.Unscoped().Where("author_id = ? AND deleted_at IS NOT NULL AND hidden = ?", query.AuthorID, false)
+	if query.After != nil {
+		db = db.Where("(deleted_at < ? OR (deleted_at = ? AND id < ?))",
+			query.After.CreatedAt, query.After.CreatedAt, query.After.ID)
+	}
+	err := db.Order("deleted_at DESC, id DESC").Limit(query.Limit).Find(&posts).Error
+	return posts, err
+}
+
+func (r *postRepository) Restore(id string) error {
+	return r.db.Unscoped().Model(&models.Post{}).Where("id = ?", id).UpdateColumn("deleted_at", nil).Error
+}
+
+// FindPurgeable returns posts deleted before deletedBefore, with their
+// attachments so the files can be removed too.
+func (r *postRepository) FindPurgeable(deletedBefore time.Time, limit int) ([]models.Post, error) {
+	var posts []models.Post
+	err := r.db.Unscoped().Preload("Attachments").
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+		Order("deleted_at ASC").Limit(limit).Find(&posts).Error
+	return posts, err
+}
+
+// Purge permanently deletes posts with everything hanging off them:
+// comments, reactions, edit history, tags and mentions, polls, bookmarks and
+// attachment rows. It returns the IDs of the comments it deleted.
+func (r *postRepository) Purge(ids []string) ([]string, error) {
+	var commentIDs []string
+	if len(ids) == 0 {
+		return commentIDs, nil
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Comment{}).Where("post_id IN ?", ids).Pluck("id", &commentIDs).Error; err != nil {
+			return err
+		}
+		if len(commentIDs) > 0 {
+			if err := tx.Where("entity_type = ? AND entity_id IN ?", "comment", commentIDs).Delete(&models.EditHistory{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("target_type = ? AND target_id IN ?", models.ReactionTargetComment, commentIDs).Delete(&models.Reaction{}).Error; err != nil {
+				return err
+			}
+		}
+		pollIDs := tx.Model(&models.Poll{}).Select("id").Where("post_id IN ?", ids)
+		// Children go before the rows they reference
+		deletes := []struct {
+			model interface{}
+			query string
+			args  []interface{}
+		}{
+			{&models.EditHistory{}, "entity_type = ? AND entity_id IN ?", []interface{}{"post", ids}},
+			{&models.HashtagUse{}, "post_id IN ?", []interface{}{ids}},
+			{&models.Mention{}, "post_id IN ?", []interface{}{ids}},
+			{&models.Comment{}, "post_id IN ?", []interface{}{ids}},
+			{&models.Reaction{}, "target_type = ? AND target_id IN ?", []interface{}{models.ReactionTargetPost, ids}},
+			{&models.PollVote{}, "poll_id IN (?)", []interface{}{pollIDs}},
+			{&models.PollOption{}, "poll_id IN (?)", []interface{}{pollIDs}},
+			{&models.Poll{}, "post_id IN ?", []interface{}{ids}},
+			{&models.Bookmark{}, "post_id IN ?", []interface{}{ids}},
+			{&models.PostAttachment{}, "post_id IN ?", []interface{}{ids}},
+			{&models.Post{}, "id IN ?", []interface{}{ids}},
+		}
+		for _, d := range deletes {
+			if err := tx.Unscoped().Where(d.query, d.args...).Delete(d.model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return commentIDs, err
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"typinggame-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReactionSummary aggregates the reactions on one target as seen by one
+// viewer.
+type ReactionSummary struct {
+	Counts       map[string]int64
+	Total        int64
+	UserReaction string // empty when the viewer hasn't reacted
+}
+
+// ReactorPageQuery selects one page of the users who reacted to a target,
+// most recent first. After is a cursor over when each user first reacted,
+// which stays put when they switch reactions, so nobody moves between pages.
+type ReactorPageQuery struct {
+	TargetType     string
+	TargetID       string
+	Reaction       string   // empty for every reaction
+	ExcludeUserIDs []string // e.g. users blocked by or blocking the viewer
+	After          *Cursor
+	Limit          int
+}
+
+type ReactionRepository interface {
+	// Toggle sets the user's reaction to a target, replacing a different
+	// one, or removes it when it is the same. When two first reactions race,
+	// the later one replaces the earlier instead of failing.
+	Toggle(targetType, targetID, userID, reaction string) error
+	GetSummaries(targetType string, targetIDs []string, viewerID string) (map[string]*ReactionSummary, error)
+	FindReactors(query ReactorPageQuery) ([]models.Reaction, error)
+	// ImportPostReactions moves reactions from the post_reactions table,
+	// where they were kept before comments and messages had reactions, and
+	// drops that table.
+	ImportPostReactions() (int64, error)
+}
+
+type reactionRepository struct {
+	db *gorm.DB
+}
+
+func NewReactionRepository(db *gorm.DB) ReactionRepository {
+	return &reactionRepository{db: db}
+}
+
+func (r *reactionRepository) Toggle(targetType, targetID, userID, reaction string) error {
+	var existing models.Reaction
+	err := r.db.Where("target_type = ? AND target_id = ? AND user_id = ?", targetType, targetID, userID).First(&existing).Error
+
+	if err == gorm.ErrRecordNotFound {
+		// Another request may have added a reaction since the lookup; the
+		// unique index turns this insert into an update of that row
+		return r.db.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"reaction", "updated_at"}),
+		}).Create(&models.Reaction{
+			ID:         uuid.New().String(),
+			TargetType: targetType,
+			TargetID:   targetID,
+			UserID:     userID,
+			Reaction:   reaction,
+		}).Error
+	} else if err == nil {
+		if existing.Reaction == reaction {
+			// Same reaction, remove it
+			return r.db.Delete(&existing).Error
+		}
+		return r.db.Model(&existing).Update("reaction", reaction).Error
+	}
+	return err
+}
+
+// GetSummaries counts reactions for every target in targetIDs with a single
+// GROUP BY, and picks out viewerID's own reaction in the same pass. Every
+// requested target gets a summary, even one without reactions.
+func (r *reactionRepository) GetSummaries(targetType string, targetIDs []string, viewerID string) (map[string]*ReactionSummary, error) {
+	summaries := make(map[string]*ReactionSummary, len(targetIDs))
+	for _, id := range targetIDs {
+		summaries[id] = &ReactionSummary{Counts: map[string]int64{}}
+	}
+	if len(targetIDs) == 0 {
+		return summaries, nil
+	}
+
+	var rows []struct {
+		TargetID string
+		Reaction string
+		Total    int64
+		Mine     bool
+	}
+	err := r.db.Model(&models.Reaction{}).
+		Select("target_id, reaction, COUNT(*) AS total, MAX(user_id = ?) AS mine", viewerID).
+		Where("target_type = ? AND target_id IN ?", targetType, targetIDs).
+		Group("target_id, reaction").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		summary := summaries[row.TargetID]
+		summary.Counts[row.Reaction] = row.Total
+		summary.Total += row.Total
+		if row.Mine {
+			summary.UserReaction = row.Reaction
+		}
+	}
+	return summaries, nil
+}
+
+func (r *reactionRepository) FindReactors(query ReactorPageQuery) ([]models.Reaction, error) {
+	var reactions []models.Reaction
+	db := r.db.Preload("User").Where("target_type = ? AND target_id = ?", query.TargetType, query.TargetID)
+	if query.Reaction != "" {
+		db = db.Where("reaction = ?", query.Reaction)
+	}
+	if len(query.ExcludeUserIDs) > 0 {
+		db = db.Where("user_id NOT IN ?", query.ExcludeUserIDs)
+	}
+	if query.After != nil {
+		db = db.Where("(created_at < ? OR (created_at = ? AND id < ?))",
+			query.After.CreatedAt, query.After.CreatedAt, query.After.ID)
+	}
+	err := db.Order("created_at DESC, id DESC").Limit(query.Limit).Find(&reactions).Error
+	return reactions, err
+}
+
+// ImportPostReactions is safe to run on every start: rows already moved are
+// skipped, and once the old table is gone there is nothing left to do. The
+// old table used soft deletes, so removed reactions stay behind.
+func (r *reactionRepository) ImportPostReactions() (int64, error) {
+	if !r.db.Migrator().HasTable("post_reactions") {
+		return 0, nil
+	}
+	result := r.db.Exec(`INSERT IGNORE INTO reactions (id, target_type, target_id, user_id, reaction, created_at, updated_at)
+		SELECT id, ?, post_id, user_id, reaction, created_at, updated_at FROM post_reactions WHERE deleted_at IS NULL`,
+		models.ReactionTargetPost)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, r.db.Migrator().DropTable("post_reactions")
+}
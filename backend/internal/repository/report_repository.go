@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"typinggame-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// ReportPageQuery selects one page of the moderation queue, oldest first.
+type ReportPageQuery struct {
+	Statuses       []string // empty for every status
+	AssigneeID     string   // only reports assigned to this moderator
+	Unassigned     bool     // only reports nobody has picked up
+	ExcludeOwnerID string   // leave out reports about this user
+	After          *Cursor  // nil for the first page
+	Limit          int
+}
+
+// ActionPageQuery selects one page of the moderation log, newest first.
+type ActionPageQuery struct {
+	ReportID      string // empty for every report
+	TargetOwnerID string // empty for every user
+	Actions       []string
+	After         *Cursor
+	Limit         int
+}
+
+type ReportRepository interface {
+	Create(report *models.Report) error
+	FindByID(id string) (*models.Report, error)
+	FindOpen(reporterID, targetType, targetID string) (*models.Report, error)
+	FindPage(query ReportPageQuery) ([]models.Report, error)
+	Update(report *models.Report) error
+	CreateAction(action *models.ModerationAction) error
+	FindActions(query ActionPageQuery) ([]models.ModerationAction, error)
+}
+
+type reportRepository struct {
+	db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) ReportRepository {
+	return &reportRepository{db: db}
+}
+
+func (r *reportRepository) Create(report *models.Report) error {
+	report.ID = uuid.New().String()
+	return r.db.Create(report).Error
+}
+
+func (r *reportRepository) FindByID(id string) (*models.Report, error) {
+	var report models.Report
+	err := r.db.Where("id = ?", id).First(&report).Error
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// FindOpen returns the reporter's unhandled report on a target, if any.
+func (r *reportRepository) FindOpen(reporterID, targetType, targetID string) (*models.Report, error) {
+	var report models.Report
+	err := r.db.Where("reporter_id = ? AND target_type = ? AND target_id = ? AND status IN ?",
+		reporterID, targetType, targetID, []string{models.ReportStatusOpen, models.ReportStatusInReview}).
+		First(&report).Error
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *reportRepository) FindPage(query ReportPageQuery) ([]models.Report, error) {
+	var reports []models.Report
+	db := r.db
+	if len(query.Statuses) > 0 {
+		db = db.Where("status IN ?", query.Statuses)
+	}
+	if query.AssigneeID != "" {
+		db = db.Where("assignee_id = ?", query.AssigneeID)
+	}
+	if query.Unassigned {
+		db = db.Where("assignee_id IS NULL")
+	}
+	if query.ExcludeOwnerID != "" {
+		db = db.Where("target_owner_id <> ?", query.ExcludeOwnerID)
+	}
+	if query.After != nil {
+		db = db.Where("(created_at > ? OR (created_at = ? AND id > ?))",
+			query.After.CreatedAt, query.After.CreatedAt, query.After.ID)
+	}
+
+	err := db.Order("created_at ASC, id ASC").Limit(query.Limit).Find(&reports).Error
+	return reports, err
+}
+
+func (r *reportRepository) Update(report *models.Report) error {
+	return r.db.Save(report).Error
+}
+
+func (r *reportRepository) CreateAction(action *models.ModerationAction) error {
+	action.ID = uuid.New().String()
+	return r.db.Create(action).Error
+}
+
+func (r *reportRepository) FindActions(query ActionPageQuery) ([]models.ModerationAction, error) {
+	var actions []models.ModerationAction
+	db := r.db
+	if query.ReportID != "" {
+		db = db.Where("report_id = ?", query.ReportID)
+	}
+	if query.TargetOwnerID != "" {
+		db = db.Where("target_owner_id = ?", query.TargetOwnerID)
+	}
+	if len(query.Actions) > 0 {
+		db = db.Where("action IN ?", query.Actions)
+	}
+	if query.After != nil {
+		db = db.Where("(created_at < ? OR (created_at = ? AND id < ?))",
+			query.After.CreatedAt, query.After.CreatedAt, query.After.ID)
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+
+	err := db.Order("created_at DESC, id DESC").Find(&actions).Error
+	return actions, err
+}
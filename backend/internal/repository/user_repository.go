@@ -8,9 +8,15 @@ import (
 
 type UserRepository interface {
 	Create(user *models.User) error
+	// ClaimGuest creates user and hands it the guest's scores, retiring the
+	// guest account, all in one transaction.
+	ClaimGuest(user *models.User, guestID string) error
 	FindByEmail(email string) (*models.User, error)
 	FindByID(id string) (*models.User, error)
+	FindByIDs(ids []string) ([]models.User, error)
+	FindBatch(afterID string, limit int) ([]models.User, error)
 	Update(user *models.User) error
+	FindByHandles(handles []string) ([]models.User, error)
 }
 
 type userRepository struct {
@@ -26,6 +32,21 @@ func (r *userRepository) Create(user *models.User) error {
 	return r.db.Create(user).Error
 }
 
+func (r *userRepository) ClaimGuest(user *models.User, guestID string) error {
+	user.ID = uuid.New().String()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.GameScore{}).
+			Where("user_id = ?", guestID).
+			Updates(map[string]interface{}{"user_id": user.ID, "is_guest": false}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, "id = ?", guestID).Error
+	})
+}
+
 func (r *userRepository) FindByEmail(email string) (*models.User, error) {
 	var user models.User
 	err := r.db.Where("email = ?", email).First(&user).Error
@@ -44,7 +65,35 @@ func (r *userRepository) FindByID(id string) (*models.User, error) {
 	return &user, nil
 }
 
+func (r *userRepository) FindByIDs(ids []string) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
+// FindBatch walks all registered users in ID order, limit at a time. Guests
+// are left out.
+func (r *userRepository) FindBatch(afterID string, limit int) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("id > ? AND is_guest = ?", afterID, false).Order("id ASC").Limit(limit).Find(&users).Error
+	return users, err
+}
+
 func (r *userRepository) Update(user *models.User) error {
 	return r.db.Save(user).Error
 }
 
+
+// FindByHandles looks users up by @handle, which is their name with spaces
+// replaced by underscores. Guests can't be mentioned.
+func (r *userRepository) FindByHandles(handles []string) ([]models.User, error) {
+	var users []models.User
+	if len(handles) == 0 {
+		return users, nil
+	}
+	err := r.db.Where("REPLACE(name, ' ', '_') IN ? AND is_guest = ?", handles, false).Find(&users).Error
+	return users, err
+}
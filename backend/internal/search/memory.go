@@ -0,0 +1,117 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type memoryDoc struct {
+	Document
+	folded string
+}
+
+type memoryIndex struct {
+	mu   sync.RWMutex
+	docs map[DocType]map[string]memoryDoc
+}
+
+// NewMemoryIndex returns an index held in process memory. It matches the
+// same way as the MySQL index and suits tests and small local setups; its
+// contents are lost on restart.
+func NewMemoryIndex() Index {
+	return &memoryIndex{docs: map[DocType]map[string]memoryDoc{}}
+}
+
+func (m *memoryIndex) Put(doc Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.docs[doc.Type] == nil {
+		m.docs[doc.Type] = map[string]memoryDoc{}
+	}
+	m.docs[doc.Type][doc.ID] = memoryDoc{Document: doc, folded: Fold(doc.Body)}
+	return nil
+}
+
+func (m *memoryIndex) Delete(docType DocType, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.docs[docType], id)
+	return nil
+}
+
+// Search scores documents with tf-idf over the query terms: rarer terms and
+// repeated matches count for more.
+func (m *memoryIndex) Search(query Query) ([]Hit, error) {
+	terms := Terms(query.Text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	excluded := make(map[string]bool, len(query.ExcludeAuthorIDs))
+	for _, id := range query.ExcludeAuthorIDs {
+		excluded[id] = true
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	docs := m.docs[query.Type]
+	frequency := make(map[string]int, len(terms))
+	for _, doc := range docs {
+		for _, term := range terms {
+			if strings.Contains(doc.folded, term) {
+				frequency[term]++
+			}
+		}
+	}
+
+	type scored struct {
+		doc   memoryDoc
+		score float64
+	}
+	var matches []scored
+	for _, doc := range docs {
+		if excluded[doc.AuthorID] {
+			continue
+		}
+		score := 0.0
+		for _, term := range terms {
+			count := strings.Count(doc.folded, term)
+			if count == 0 {
+				score = 0
+				break
+			}
+			idf := math.Log(1 + float64(len(docs))/float64(frequency[term]))
+			score += (1 + math.Log(float64(count))) * idf
+		}
+		if score > 0 {
+			matches = append(matches, scored{doc: doc, score: score})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if !a.doc.CreatedAt.Equal(b.doc.CreatedAt) {
+			return a.doc.CreatedAt.After(b.doc.CreatedAt)
+		}
+		return a.doc.ID < b.doc.ID
+	})
+
+	if query.Offset >= len(matches) {
+		return nil, nil
+	}
+	matches = matches[query.Offset:]
+	if query.Limit > 0 && len(matches) > query.Limit {
+		matches = matches[:query.Limit]
+	}
+
+	hits := make([]Hit, 0, len(matches))
+	for _, match := range matches {
+		hits = append(hits, Hit{ID: match.doc.ID, Score: match.score})
+	}
+	return hits, nil
+}
@@ -0,0 +1,78 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemoryIndexSearch(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	index := NewMemoryIndex()
+	docs := []Document{
+		{Type: Post, ID: "a", AuthorID: "u1", Body: "typing typing game", CreatedAt: base},
+		{Type: Post, ID: "b", AuthorID: "u2", Body: "Typing game", CreatedAt: base.Add(time.Hour)},
+		{Type: Post, ID: "c", AuthorID: "u1", Body: "typing game", CreatedAt: base},
+		{Type: Post, ID: "d", AuthorID: "u3", Body: "game night", CreatedAt: base},
+		{Type: Post, ID: "e", AuthorID: "u3", Body: "เกมพิมพ์ดีดภาษาไทย", CreatedAt: base},
+		{Type: Comment, ID: "f", AuthorID: "u1", Body: "typing", CreatedAt: base},
+	}
+	for _, doc := range docs {
+		if err := index.Put(doc); err != nil {
+			t.Fatalf("Put(%s): %v", doc.ID, err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"repeated matches rank first", Query{Type: Post, Text: "typing"}, []string{"a", "b", "c"}},
+		{"every term must match", Query{Type: Post, Text: "typing night"}, nil},
+		{"equal scores go to the newest", Query{Type: Post, Text: "game"}, []string{"b", "a", "c", "d"}},
+		{"thai substring", Query{Type: Post, Text: "พิมพ์"}, []string{"e"}},
+		{"full-width query", Query{Type: Post, Text: "ＴＹＰＩＮＧ"}, []string{"a", "b", "c"}},
+		{"excluded authors", Query{Type: Post, Text: "typing", ExcludeAuthorIDs: []string{"u1"}}, []string{"b"}},
+		{"offset and limit", Query{Type: Post, Text: "typing", Offset: 1, Limit: 1}, []string{"b"}},
+		{"offset past the end", Query{Type: Post, Text: "typing", Offset: 3}, nil},
+		{"other type", Query{Type: Comment, Text: "typing"}, []string{"f"}},
+		{"query too short", Query{Type: Post, Text: "t"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := index.Search(tt.query)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			var got []string
+			for _, hit := range hits {
+				got = append(got, hit.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%+v) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryIndexPutAndDelete(t *testing.T) {
+	index := NewMemoryIndex()
+	index.Put(Document{Type: Post, ID: "a", Body: "first draft"})
+	index.Put(Document{Type: Post, ID: "a", Body: "second draft"})
+
+	hits, _ := index.Search(Query{Type: Post, Text: "first"})
+	if len(hits) != 0 {
+		t.Errorf("replaced body still matches: %v", hits)
+	}
+	hits, _ = index.Search(Query{Type: Post, Text: "second"})
+	if len(hits) != 1 || hits[0].ID != "a" {
+		t.Errorf("Search(second) = %v, want [a]", hits)
+	}
+
+	index.Delete(Post, "a")
+	hits, _ = index.Search(Query{Type: Post, Text: "second"})
+	if len(hits) != 0 {
+		t.Errorf("deleted document still matches: %v", hits)
+	}
+}
@@ -0,0 +1,61 @@
+package search
+
+import (
+	"strings"
+
+	"typinggame-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type mysqlIndex struct {
+	db *gorm.DB
+}
+
+// NewMySQLIndex returns an index kept in the search_documents table, whose
+// FULLTEXT index uses MySQL's ngram parser.
+func NewMySQLIndex(db *gorm.DB) Index {
+	return &mysqlIndex{db: db}
+}
+
+func (m *mysqlIndex) Put(doc Document) error {
+	return m.db.Save(&models.SearchDocument{
+		DocType:   string(doc.Type),
+		DocID:     doc.ID,
+		AuthorID:  doc.AuthorID,
+		Body:      Fold(doc.Body),
+		CreatedAt: doc.CreatedAt,
+	}).Error
+}
+
+func (m *mysqlIndex) Delete(docType DocType, id string) error {
+	return m.db.Delete(&models.SearchDocument{}, "doc_type = ? AND doc_id = ?", string(docType), id).Error
+}
+
+// Search requires every term as a phrase, which with the ngram parser means
+// its characters in order, and ranks by MySQL's relevance score.
+func (m *mysqlIndex) Search(query Query) ([]Hit, error) {
+	terms := Terms(query.Text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	// Terms only hold letters, marks and digits, so they need no escaping
+	phrases := make([]string, 0, len(terms))
+	for _, term := range terms {
+		phrases = append(phrases, `+"`+term+`"`)
+	}
+	against := strings.Join(phrases, " ")
+
+	db := m.db.Model(&models.SearchDocument{}).
+		Select("doc_id AS id, MATCH(body) AGAINST(? IN BOOLEAN MODE) AS score", against).
+		Where("doc_type = ? AND MATCH(body) AGAINST(? IN BOOLEAN MODE)", string(query.Type), against)
+	if len(query.ExcludeAuthorIDs) > 0 {
+		db = db.Where("author_id NOT IN ?", query.ExcludeAuthorIDs)
+	}
+
+	var hits []Hit
+	err := db.Order("score DESC, created_at DESC, doc_id ASC").
+		Offset(query.Offset).
+		Limit(query.Limit).
+		Scan(&hits).Error
+	return hits, err
+}
@@ -0,0 +1,138 @@
+// Package search is the full-text index behind /api/search. Text is matched
+// as substrings of case and width folded text instead of whole words, which
+// is what makes Thai and Japanese searchable: neither puts spaces between
+// words, so there are no words to split on.
+package search
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+type DocType string
+
+const (
+	Post    DocType = "post"
+	Comment DocType = "comment"
+	User    DocType = "user"
+)
+
+// MinTermLength is the shortest term that can be searched for. MySQL's ngram
+// parser indexes pairs of characters, so single characters never match.
+const MinTermLength = 2
+
+// Document is one searchable item. AuthorID is the user a document belongs
+// to (the user itself for users) and is used to leave out blocked users.
+type Document struct {
+	Type      DocType
+	ID        string
+	AuthorID  string
+	Body      string
+	CreatedAt time.Time
+}
+
+// Query matches documents of one type that contain every term of Text.
+type Query struct {
+	Type             DocType
+	Text             string
+	ExcludeAuthorIDs []string
+	Offset           int
+	Limit            int
+}
+
+// Hit is a matching document, best matches first.
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+type Index interface {
+	// Put adds a document or replaces the one with the same type and ID.
+	Put(doc Document) error
+	Delete(docType DocType, id string) error
+	Search(query Query) ([]Hit, error)
+}
+
+// Range is a highlighted part of a text, in code points with End exclusive,
+// the same way entity offsets are given.
+type Range struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Fold lowercases text and folds full-width and other compatibility forms
+// one character at a time, so code point offsets into the folded text are
+// the same as into the original.
+func Fold(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for _, r := range text {
+		b.WriteRune(foldRune(r))
+	}
+	return b.String()
+}
+
+func foldRune(r rune) rune {
+	// Characters that decompose into several (such as Thai sara am) are kept
+	// as they are to preserve offsets
+	if folded := norm.NFKC.String(string(r)); utf8.RuneCountInString(folded) == 1 {
+		r, _ = utf8.DecodeRuneInString(folded)
+	}
+	return unicode.ToLower(r)
+}
+
+// Terms splits a query into distinct folded terms. Anything other than
+// letters, combining marks and digits separates terms, and terms shorter
+// than MinTermLength are dropped.
+func Terms(query string) []string {
+	fields := strings.FieldsFunc(Fold(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsMark(r) && !unicode.IsDigit(r)
+	})
+
+	seen := map[string]bool{}
+	var terms []string
+	for _, field := range fields {
+		if utf8.RuneCountInString(field) < MinTermLength || seen[field] {
+			continue
+		}
+		seen[field] = true
+		terms = append(terms, field)
+	}
+	return terms
+}
+
+// Highlight returns where the terms occur in text, sorted and with
+// overlapping matches merged.
+func Highlight(text string, terms []string) []Range {
+	folded := []rune(Fold(text))
+	var ranges []Range
+	for _, term := range terms {
+		needle := []rune(term)
+		for i := 0; i+len(needle) <= len(folded); i++ {
+			if string(folded[i:i+len(needle)]) == term {
+				ranges = append(ranges, Range{Start: i, End: i + len(needle)})
+				i += len(needle) - 1
+			}
+		}
+	}
+	if len(ranges) == 0 {
+		return []Range{}
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End {
+			last.End = max(last.End, r.End)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
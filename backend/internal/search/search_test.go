@@ -0,0 +1,81 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"ascii", "Hello World", "hello world"},
+		{"full-width latin", "ＧＯ　Ｌａｎｇ", "go lang"},
+		{"full-width digits", "１２３", "123"},
+		{"half-width katakana", "ｶﾀｶﾅ", "カタカナ"},
+		{"hiragana and kanji", "東京へいく", "東京へいく"},
+		{"thai", "สวัสดีครับ", "สวัสดีครับ"},
+		{"thai sara am", "น้ำ", "น้ำ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Fold(tt.in)
+			if got != tt.want {
+				t.Errorf("Fold(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if utf8.RuneCountInString(got) != utf8.RuneCountInString(tt.in) {
+				t.Errorf("Fold(%q) changed the length from %d to %d code points", tt.in, utf8.RuneCountInString(tt.in), utf8.RuneCountInString(got))
+			}
+		})
+	}
+}
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"punctuation splits", "Hello, world!", []string{"hello", "world"}},
+		{"short terms dropped", "a go b", []string{"go"}},
+		{"duplicates dropped", "Go GO ｇｏ", []string{"go"}},
+		{"thai keeps marks", "สวัสดี ครับ", []string{"สวัสดี", "ครับ"}},
+		{"japanese punctuation", "東京、タワー。", []string{"東京", "タワー"}},
+		{"only separators", " - ! ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Terms(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Terms(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  []Range
+	}{
+		{"every occurrence", "Hello hello", []string{"hello"}, []Range{{0, 5}, {6, 11}}},
+		{"sorted across terms", "world hello", []string{"hello", "world"}, []Range{{0, 5}, {6, 11}}},
+		{"overlaps merged", "abcd", []string{"abc", "bcd"}, []Range{{0, 4}}},
+		{"adjacent merged", "abcd", []string{"ab", "cd"}, []Range{{0, 4}}},
+		{"thai offsets in code points", "ฉันชอบกาแฟ", []string{"กาแฟ"}, []Range{{6, 10}}},
+		{"full-width text", "ＧＯ言語", []string{"go"}, []Range{{0, 2}}},
+		{"japanese", "東京タワーと東京駅", []string{"東京"}, []Range{{0, 2}, {6, 8}}},
+		{"no match", "hello", []string{"xyz"}, []Range{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Highlight(tt.text, tt.terms); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Highlight(%q, %q) = %v, want %v", tt.text, tt.terms, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"typinggame-api/internal/media"
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/storage"
+)
+
+var (
+	ErrTooManyAttachments    = errors.New("แนบไฟล์เกินจำนวนที่กำหนด")
+	ErrAttachmentTooLarge    = errors.New("ไฟล์มีขนาดใหญ่เกินไป")
+	ErrUnsupportedAttachment = errors.New("รองรับเฉพาะไฟล์รูปภาพ JPEG, PNG และ GIF")
+)
+
+// AttachmentUpload is one file as received from the client.
+type AttachmentUpload struct {
+	Filename string
+	Data     []byte
+}
+
+type AttachmentLimits struct {
+	MaxBytes      int64
+	MaxPerPost    int
+	ThumbnailSize int
+}
+
+type AttachmentService interface {
+	// Store processes and uploads files, numbering them from firstPosition.
+	// The returned attachments still need to be saved with their post.
+	Store(uploads []AttachmentUpload, firstPosition int) ([]models.PostAttachment, error)
+	// Remove deletes the stored files of attachments that are gone from the database.
+	Remove(attachments []models.PostAttachment)
+	URL(key string) string
+	Limits() AttachmentLimits
+}
+
+type attachmentService struct {
+	store  storage.Storage
+	limits AttachmentLimits
+	logger *zap.Logger
+}
+
+func NewAttachmentService(store storage.Storage, limits AttachmentLimits, logger *zap.Logger) AttachmentService {
+	return &attachmentService{store: store, limits: limits, logger: logger}
+}
+
+func (s *attachmentService) Store(uploads []AttachmentUpload, firstPosition int) ([]models.PostAttachment, error) {
+	// Process everything before uploading anything, so a bad file in the
+	// middle doesn't leave orphans behind
+	images := make([]*media.Image, 0, len(uploads))
+	for _, upload := range uploads {
+		if int64(len(upload.Data)) > s.limits.MaxBytes {
+			return nil, ErrAttachmentTooLarge
+		}
+		img, err := media.ProcessImage(upload.Data, s.limits.ThumbnailSize)
+		if errors.Is(err, media.ErrUnsupportedType) || errors.Is(err, media.ErrImageTooLarge) {
+			return nil, ErrUnsupportedAttachment
+		}
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+
+	attachments := make([]models.PostAttachment, 0, len(images))
+	for i, img := range images {
+		id := uuid.New().String()
+		attachment := models.PostAttachment{
+			ID:           id,
+			Position:     firstPosition + i,
+			StorageKey:   "posts/" + id + img.Ext,
+			ThumbnailKey: "posts/" + id + "_thumb" + img.ThumbnailExt,
+			ContentType:  img.ContentType,
+			Size:         int64(len(img.Data)),
+			Width:        img.Width,
+			Height:       img.Height,
+		}
+
+		if err := s.store.Put(attachment.StorageKey, img.Data, img.ContentType); err != nil {
+			s.Remove(attachments)
+			return nil, err
+		}
+		if err := s.store.Put(attachment.ThumbnailKey, img.Thumbnail, img.ThumbnailContentType); err != nil {
+			s.delete(attachment.StorageKey)
+			s.Remove(attachments)
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// Remove is best effort: a file left behind costs disk space, not correctness.
+func (s *attachmentService) Remove(attachments []models.PostAttachment) {
+	for _, attachment := range attachments {
+		s.delete(attachment.StorageKey)
+		s.delete(attachment.ThumbnailKey)
+	}
+}
+
+func (s *attachmentService) delete(key string) {
+	if err := s.store.Delete(key); err != nil {
+		s.logger.Error("Failed to delete attachment file", zap.String("key", key), zap.Error(err))
+	}
+}
+
+func (s *attachmentService) URL(key string) string {
+	return s.store.URL(key)
+}
+
+func (s *attachmentService) Limits() AttachmentLimits {
+	return s.limits
+}
@@ -5,32 +5,57 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"typinggame-api/config"
 	"typinggame-api/internal/models"
 	"typinggame-api/internal/repository"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	userTokenTTL  = time.Hour * 24 * 7
+	guestTokenTTL = time.Hour * 24 * 3
+)
+
 type AuthService interface {
-	Register(name, email, password string) (*models.User, error)
+	Register(name, email, password, guestToken string) (*models.User, error)
 	Login(email, password string) (string, *models.User, error)
+	StartGuestSession() (string, *models.User, error)
 }
 
 type authService struct {
 	userRepo repository.UserRepository
+	search   SearchService
+	logger   *zap.Logger
 }
 
-func NewAuthService(userRepo repository.UserRepository) AuthService {
-	return &authService{userRepo: userRepo}
+func NewAuthService(userRepo repository.UserRepository, search SearchService, logger *zap.Logger) AuthService {
+	return &authService{
+		userRepo: userRepo,
+		search:   search,
+		logger:   logger,
+	}
 }
 
-func (s *authService) Register(name, email, password string) (*models.User, error) {
+func (s *authService) Register(name, email, password, guestToken string) (*models.User, error) {
 	// Check if user exists
 	existingUser, _ := s.userRepo.FindByEmail(email)
 	if existingUser != nil {
 		return nil, errors.New("อีเมลนี้ถูกใช้งานแล้ว")
 	}
 
+	// Resolve the guest being claimed before creating anything, so a bad token
+	// doesn't leave a half-registered account behind
+	var guestID string
+	if guestToken != "" {
+		id, err := s.parseGuestToken(guestToken)
+		if err != nil {
+			return nil, err
+		}
+		guestID = id
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -44,10 +69,21 @@ func (s *authService) Register(name, email, password string) (*models.User, erro
 		Password: string(hashedPassword),
 	}
 
-	if err := s.userRepo.Create(user); err != nil {
+	// A claimed guest's scores move over and the guest account goes in the
+	// same transaction, so a failure leaves nothing half done to retry over
+	if guestID != "" {
+		err = s.userRepo.ClaimGuest(user, guestID)
+	} else {
+		err = s.userRepo.Create(user)
+	}
+	if err != nil {
 		return nil, err
 	}
 
+	if err := s.search.IndexUser(user); err != nil {
+		s.logger.Error("Failed to index user for search", zap.String("user_id", user.ID), zap.Error(err))
+	}
+
 	user.Password = ""
 	return user, nil
 }
@@ -55,7 +91,7 @@ func (s *authService) Register(name, email, password string) (*models.User, erro
 func (s *authService) Login(email, password string) (string, *models.User, error) {
 	// Find user
 	user, err := s.userRepo.FindByEmail(email)
-	if err != nil {
+	if err != nil || user.IsGuest {
 		return "", nil, errors.New("อีเมลหรือรหัสผ่านไม่ถูกต้อง")
 	}
 
@@ -63,15 +99,50 @@ func (s *authService) Login(email, password string) (string, *models.User, error
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
 		return "", nil, errors.New("อีเมลหรือรหัสผ่านไม่ถูกต้อง")
 	}
+	if user.IsSuspended(time.Now()) {
+		return "", nil, ErrUserSuspended
+	}
 
 	// Generate JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	tokenString, err := signToken(jwt.MapClaims{
 		"user_id": user.ID,
 		"email":   user.Email,
-		"exp":     time.Now().Add(time.Hour * 24 * 7).Unix(),
+		"exp":     time.Now().Add(userTokenTTL).Unix(),
 	})
+	if err != nil {
+		return "", nil, err
+	}
 
-	tokenString, err := token.SignedString([]byte(config.Get().JWT.Secret))
+	user.Password = ""
+	return tokenString, user, nil
+}
+
+// StartGuestSession creates a throwaway guest account and returns a token that
+// only grants access to the game routes.
+func (s *authService) StartGuestSession() (string, *models.User, error) {
+	// Guests never log in with a password, but the column is required
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(uuid.New().String()), bcrypt.DefaultCost)
+	if err != nil {
+		return "", nil, err
+	}
+
+	suffix := uuid.New().String()
+	user := &models.User{
+		Name:     "Guest-" + suffix[:8],
+		Email:    "guest-" + suffix + "@guest.local",
+		Password: string(hashedPassword),
+		IsGuest:  true,
+	}
+
+	if err := s.userRepo.Create(user); err != nil {
+		return "", nil, err
+	}
+
+	tokenString, err := signToken(jwt.MapClaims{
+		"user_id": user.ID,
+		"guest":   true,
+		"exp":     time.Now().Add(guestTokenTTL).Unix(),
+	})
 	if err != nil {
 		return "", nil, err
 	}
@@ -80,3 +151,40 @@ func (s *authService) Login(email, password string) (string, *models.User, error
 	return tokenString, user, nil
 }
 
+// parseGuestToken returns the guest user ID carried by a still valid guest token.
+func (s *authService) parseGuestToken(tokenString string) (string, error) {
+	invalid := errors.New("guest session ไม่ถูกต้องหรือหมดอายุ")
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, invalid
+		}
+		return []byte(config.Get().JWT.Secret), nil
+	})
+	if err != nil || !token.Valid {
+		return "", invalid
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", invalid
+	}
+	if guest, _ := claims["guest"].(bool); !guest {
+		return "", invalid
+	}
+	guestID, ok := claims["user_id"].(string)
+	if !ok {
+		return "", invalid
+	}
+
+	guest, err := s.userRepo.FindByID(guestID)
+	if err != nil || !guest.IsGuest {
+		return "", invalid
+	}
+	return guest.ID, nil
+}
+
+func signToken(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(config.Get().JWT.Secret))
+}
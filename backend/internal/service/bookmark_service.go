@@ -0,0 +1,208 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+const maxCollectionName = 100
+
+var (
+	ErrCollectionNotFound    = errors.New("ไม่พบคอลเลกชัน")
+	ErrCollectionExists      = errors.New("มีคอลเลกชันชื่อนี้อยู่แล้ว")
+	ErrInvalidCollectionName = errors.New("ชื่อคอลเลกชันต้องมี 1-100 ตัวอักษร")
+)
+
+// BookmarkPage is one page of saved posts. NextCursor is empty on the last
+// page.
+type BookmarkPage struct {
+	Bookmarks  []models.Bookmark
+	NextCursor string
+}
+
+type BookmarkService interface {
+	// Bookmark saves a post, or moves an existing bookmark to collectionID.
+	// An empty collectionID leaves the bookmark uncollected.
+	Bookmark(postID, userID, collectionID string) (*models.Bookmark, error)
+	RemoveBookmark(postID, userID string) error
+	GetBookmarks(userID, collectionID, cursor string, limit int) (*BookmarkPage, error)
+	GetBookmarkedPostIDs(userID string, posts []models.Post) (map[string]bool, error)
+	CreateCollection(userID, name string) (*models.BookmarkCollection, error)
+	GetCollections(userID string) ([]models.BookmarkCollection, error)
+	RenameCollection(collectionID, userID, name string) (*models.BookmarkCollection, error)
+	DeleteCollection(collectionID, userID string) error
+}
+
+type bookmarkService struct {
+	bookmarkRepo repository.BookmarkRepository
+	postRepo     repository.PostRepository
+	policy       ContentPolicy
+}
+
+func NewBookmarkService(bookmarkRepo repository.BookmarkRepository, postRepo repository.PostRepository, policy ContentPolicy) BookmarkService {
+	return &bookmarkService{
+		bookmarkRepo: bookmarkRepo,
+		postRepo:     postRepo,
+		policy:       policy,
+	}
+}
+
+func (s *bookmarkService) Bookmark(postID, userID, collectionID string) (*models.Bookmark, error) {
+	post, err := s.postRepo.FindByID(postID)
+	if err != nil {
+		return nil, ErrPostNotFound
+	}
+	visible, err := s.policy.CanViewPost(userID, post)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, ErrPostNotFound
+	}
+
+	var collection *string
+	if collectionID != "" {
+		if _, err := s.findOwnCollection(collectionID, userID); err != nil {
+			return nil, err
+		}
+		collection = &collectionID
+	}
+
+	bookmark, err := s.bookmarkRepo.Find(userID, postID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		bookmark = &models.Bookmark{UserID: userID, PostID: postID}
+	} else if err != nil {
+		return nil, err
+	}
+	bookmark.CollectionID = collection
+
+	if err := s.bookmarkRepo.Save(bookmark); err != nil {
+		return nil, err
+	}
+	return bookmark, nil
+}
+
+func (s *bookmarkService) RemoveBookmark(postID, userID string) error {
+	return s.bookmarkRepo.Delete(userID, postID)
+}
+
+func (s *bookmarkService) GetBookmarks(userID, collectionID, cursor string, limit int) (*BookmarkPage, error) {
+	if collectionID != "" {
+		if _, err := s.findOwnCollection(collectionID, userID); err != nil {
+			return nil, err
+		}
+	}
+	after, err := repository.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := s.policy.HiddenUserIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	bookmarks, err := s.bookmarkRepo.FindPage(repository.BookmarkPageQuery{
+		UserID:           userID,
+		CollectionID:     collectionID,
+		ExcludeAuthorIDs: hidden,
+		After:            after,
+		Limit:            limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &BookmarkPage{Bookmarks: bookmarks}
+	if len(bookmarks) > limit {
+		page.Bookmarks = bookmarks[:limit]
+		last := page.Bookmarks[limit-1]
+		page.NextCursor = repository.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
+
+func (s *bookmarkService) GetBookmarkedPostIDs(userID string, posts []models.Post) (map[string]bool, error) {
+	postIDs := make([]string, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+	ids, err := s.bookmarkRepo.FindBookmarkedPostIDs(userID, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	bookmarked := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		bookmarked[id] = true
+	}
+	return bookmarked, nil
+}
+
+func (s *bookmarkService) CreateCollection(userID, name string) (*models.BookmarkCollection, error) {
+	name, err := s.checkCollectionName(userID, name, "")
+	if err != nil {
+		return nil, err
+	}
+	collection := &models.BookmarkCollection{UserID: userID, Name: name}
+	if err := s.bookmarkRepo.CreateCollection(collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+func (s *bookmarkService) GetCollections(userID string) ([]models.BookmarkCollection, error) {
+	return s.bookmarkRepo.FindCollections(userID)
+}
+
+func (s *bookmarkService) RenameCollection(collectionID, userID, name string) (*models.BookmarkCollection, error) {
+	collection, err := s.findOwnCollection(collectionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	name, err = s.checkCollectionName(userID, name, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	collection.Name = name
+	if err := s.bookmarkRepo.UpdateCollection(collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+func (s *bookmarkService) DeleteCollection(collectionID, userID string) error {
+	if _, err := s.findOwnCollection(collectionID, userID); err != nil {
+		return err
+	}
+	return s.bookmarkRepo.DeleteCollection(collectionID)
+}
+
+// findOwnCollection treats other users' collections as missing.
+func (s *bookmarkService) findOwnCollection(collectionID, userID string) (*models.BookmarkCollection, error) {
+	collection, err := s.bookmarkRepo.FindCollection(collectionID)
+	if err != nil || collection.UserID != userID {
+		return nil, ErrCollectionNotFound
+	}
+	return collection, nil
+}
+
+// checkCollectionName trims name and makes sure no other collection of the
+// user (apart from exceptID) already uses it.
+func (s *bookmarkService) checkCollectionName(userID, name, exceptID string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxCollectionName {
+		return "", ErrInvalidCollectionName
+	}
+	existing, err := s.bookmarkRepo.FindCollectionByName(userID, name)
+	if err == nil && existing.ID != exceptID {
+		return "", ErrCollectionExists
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	return name, nil
+}
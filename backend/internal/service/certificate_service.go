@@ -0,0 +1,177 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+)
+
+var (
+	ErrScoreNotFound = errors.New("ไม่พบคะแนน")
+	ErrNotScoreOwner = errors.New("ไม่มีสิทธิ์ออกใบรับรองสำหรับคะแนนนี้")
+	ErrScoreFlagged  = errors.New("คะแนนนี้ถูกระงับ ไม่สามารถออกใบรับรองได้")
+)
+
+// ScoreCertificate is the signed statement about a single game score.
+type ScoreCertificate struct {
+	ScoreID  string    `json:"scoreId"`
+	UserName string    `json:"userName"`
+	WPM      float64   `json:"wpm"`
+	Accuracy float64   `json:"accuracy"`
+	Date     time.Time `json:"date"`
+	IssuedAt time.Time `json:"issuedAt"`
+	KeyID    string    `json:"keyId"`
+}
+
+// SignedCertificate carries the exact bytes that were signed, so verification
+// never depends on how a client re-serializes the JSON.
+type SignedCertificate struct {
+	Certificate ScoreCertificate
+	Payload     string // base64url of the signed JSON
+	Signature   string // base64url Ed25519 signature over Payload's bytes
+	PublicKey   string // base64url Ed25519 public key
+}
+
+type CertificateVerification struct {
+	Valid       bool
+	Reason      string
+	Certificate *ScoreCertificate
+}
+
+type CertificateService interface {
+	IssueCertificate(scoreID, userID string) (*SignedCertificate, error)
+	VerifyCertificate(payload, signature string) (*CertificateVerification, error)
+	PublicKey() string
+}
+
+type certificateService struct {
+	scoreRepo  repository.GameScoreRepository
+	privateKey ed25519.PrivateKey
+	keyID      string
+}
+
+func NewCertificateService(scoreRepo repository.GameScoreRepository, privateKey ed25519.PrivateKey) CertificateService {
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	sum := sha256.Sum256(publicKey)
+	return &certificateService{
+		scoreRepo:  scoreRepo,
+		privateKey: privateKey,
+		keyID:      hex.EncodeToString(sum[:8]),
+	}
+}
+
+// LoadCertificateKey decodes the configured signing key. When none is set a
+// fresh key is generated; certificates signed with it stop verifying after a
+// restart, so callers should warn about it.
+func LoadCertificateKey(encoded string) (key ed25519.PrivateKey, generated bool, err error) {
+	if encoded == "" {
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+		return key, true, err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false, errors.New("CERT_SIGNING_KEY is not valid base64")
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), false, nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), false, nil
+	default:
+		return nil, false, errors.New("CERT_SIGNING_KEY must be a 32 byte seed or 64 byte private key")
+	}
+}
+
+func (s *certificateService) IssueCertificate(scoreID, userID string) (*SignedCertificate, error) {
+	score, err := s.scoreRepo.FindByID(scoreID)
+	if err != nil {
+		return nil, ErrScoreNotFound
+	}
+	if score.UserID != userID {
+		return nil, ErrNotScoreOwner
+	}
+	if score.Flagged {
+		return nil, ErrScoreFlagged
+	}
+
+	cert := certificateFor(score)
+	cert.IssuedAt = time.Now().UTC().Truncate(time.Second)
+	cert.KeyID = s.keyID
+
+	payload, err := json.Marshal(cert)
+	if err != nil {
+		return nil, err
+	}
+	signature := ed25519.Sign(s.privateKey, payload)
+
+	return &SignedCertificate{
+		Certificate: cert,
+		Payload:     base64.RawURLEncoding.EncodeToString(payload),
+		Signature:   base64.RawURLEncoding.EncodeToString(signature),
+		PublicKey:   s.PublicKey(),
+	}, nil
+}
+
+func (s *certificateService) VerifyCertificate(payload, signature string) (*CertificateVerification, error) {
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return &CertificateVerification{Reason: "payload is not valid base64url"}, nil
+	}
+	signatureBytes, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return &CertificateVerification{Reason: "signature is not valid base64url"}, nil
+	}
+
+	publicKey := s.privateKey.Public().(ed25519.PublicKey)
+	if !ed25519.Verify(publicKey, payloadBytes, signatureBytes) {
+		return &CertificateVerification{Reason: "signature does not match"}, nil
+	}
+
+	var cert ScoreCertificate
+	if err := json.Unmarshal(payloadBytes, &cert); err != nil {
+		return &CertificateVerification{Reason: "payload is not a certificate"}, nil
+	}
+
+	// A genuine signature is not enough: the score must still stand
+	score, err := s.scoreRepo.FindByID(cert.ScoreID)
+	if err != nil {
+		return &CertificateVerification{Reason: "score no longer exists", Certificate: &cert}, nil
+	}
+	if score.Flagged {
+		return &CertificateVerification{Reason: "score has been flagged", Certificate: &cert}, nil
+	}
+	current := certificateFor(score)
+	if current.WPM != cert.WPM || current.Accuracy != cert.Accuracy || !current.Date.Equal(cert.Date) {
+		return &CertificateVerification{Reason: "score does not match certificate", Certificate: &cert}, nil
+	}
+
+	return &CertificateVerification{Valid: true, Certificate: &cert}, nil
+}
+
+func (s *certificateService) PublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(s.privateKey.Public().(ed25519.PublicKey))
+}
+
+func certificateFor(score *models.GameScore) ScoreCertificate {
+	// Scores saved before offline sync existed have no played_at
+	date := score.PlayedAt
+	if date.IsZero() {
+		date = score.CreatedAt
+	}
+	return ScoreCertificate{
+		ScoreID:  score.ID,
+		UserName: score.User.Name,
+		WPM:      score.WPM,
+		Accuracy: score.Accuracy,
+		Date:     date.UTC().Truncate(time.Second),
+	}
+}
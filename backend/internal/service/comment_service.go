@@ -1,17 +1,60 @@
 package service
 
 import (
+	"errors"
+
+	"typinggame-api/internal/filter"
 	"typinggame-api/internal/models"
 	"typinggame-api/internal/repository"
+	"typinggame-api/internal/search"
+	"go.uber.org/zap"
 )
 
+var ErrCommentNotFound = errors.New("ไม่พบความคิดเห็น")
+
+// maxCommentDepth bounds how deeply replies nest. A reply to a comment at
+// this depth is added next to it, as another reply to its parent.
+const maxCommentDepth = 3
+
+// CommentPage is one page of a thread, oldest first. NextCursor is empty on
+// the last page.
+type CommentPage struct {
+	Comments   []models.Comment
+	NextCursor string
+}
+
 type CommentService interface {
 	CreateComment(content, postID, authorID string) (*models.Comment, error)
-	GetCommentsByPostID(postID string) ([]models.Comment, error)
-	UpdateComment(commentID, userID, content string) (*models.Comment, error)
-	GetEditHistory(commentID string) ([]models.EditHistory, error)
+	ReplyToComment(content, parentID, authorID string) (*models.Comment, error)
+	// GetComments pages through the comments on a post itself; replies are
+	// counted, and listed by GetReplies.
+	GetComments(postID, viewerID, cursor string, limit int) (*CommentPage, error)
+	GetReplies(commentID, viewerID, cursor string, limit int) (*CommentPage, error)
+	// GetThread returns the post's whole thread, oldest first. Replies to
+	// comments the viewer can't see are left out with them.
+	GetThread(postID, viewerID string) ([]models.Comment, error)
+	GetComment(commentID, viewerID string) (*models.Comment, error)
+	// UpdateComment gives ErrStaleVersion when version is set and the
+	// comment has been edited since that version.
+	UpdateComment(commentID, userID, content string, version int) (*models.Comment, error)
+	// GetEditHistory follows the visibility and block rules of the comment
+	// and its post, and the author's edit history setting.
+	GetEditHistory(commentID, viewerID string) (*EditHistory, error)
 	DeleteComment(commentID, userID string) error
+	// RestoreComment brings one of the user's comments back from the trash.
+	RestoreComment(commentID, userID string) (*models.Comment, error)
+	// HideComment and RemoveComment are moderator actions and skip the
+	// author check.
+	HideComment(commentID string) error
+	UnhideComment(commentID string) error
+	RemoveComment(commentID string) error
 	UpdatePostCommentsCount(postID string) error
+	ReactToComment(commentID, userID, reaction string) error
+	GetReactionSummary(commentID, viewerID string) (*repository.ReactionSummary, error)
+	// GetReactionSummaries loads the reactions for a page of comments
+	// already loaded for viewerID. Placeholders are skipped.
+	GetReactionSummaries(comments []models.Comment, viewerID string) (map[string]*repository.ReactionSummary, error)
+	GetReactors(commentID, viewerID, reaction, cursor string, limit int) (*ReactorPage, error)
 }
 
 type commentService struct {
@@ -19,66 +62,209 @@ type commentService struct {
 	postRepo    repository.PostRepository
 	userRepo    repository.UserRepository
 	historyRepo repository.EditHistoryRepository
+	policy      ContentPolicy
+	entities    EntityService
+	search      SearchService
+	filter      filter.ContentFilter
+	review      ReviewQueue
+	reactions   ReactionService
+	logger      *zap.Logger
 }
 
-func NewCommentService(commentRepo repository.CommentRepository, postRepo repository.PostRepository, userRepo repository.UserRepository, historyRepo repository.EditHistoryRepository) CommentService {
+func NewCommentService(commentRepo repository.CommentRepository, postRepo repository.PostRepository, userRepo repository.UserRepository, historyRepo repository.EditHistoryRepository, policy ContentPolicy, entities EntityService, search SearchService, contentFilter filter.ContentFilter, review ReviewQueue, reactions ReactionService, logger *zap.Logger) CommentService {
 	return &commentService{
 		commentRepo: commentRepo,
 		postRepo:    postRepo,
 		userRepo:    userRepo,
 		historyRepo: historyRepo,
+		policy:      policy,
+		entities:    entities,
+		search:      search,
+		filter:      contentFilter,
+		review:      review,
+		reactions:   reactions,
+		logger:      logger,
+	}
+}
+
+// findVisiblePost returns the post if viewerID may see it, and ErrPostNotFound otherwise.
+func (s *commentService) findVisiblePost(postID, viewerID string) (*models.Post, error) {
+	post, err := s.postRepo.FindByID(postID)
+	if err != nil {
+		return nil, ErrPostNotFound
+	}
+	visible, err := s.policy.CanViewPost(viewerID, post)
+	if err != nil {
+		return nil, err
 	}
+	if !visible {
+		return nil, ErrPostNotFound
+	}
+	return post, nil
 }
 
 func (s *commentService) CreateComment(content, postID, authorID string) (*models.Comment, error) {
-	// Verify post exists
-	_, err := s.postRepo.FindByID(postID)
+	// Verify post exists and the author can see it
+	_, err := s.findVisiblePost(postID, authorID)
+	if err != nil {
+		return nil, err
+	}
+	return s.create(content, postID, authorID, nil)
+}
+
+// ReplyToComment answers a comment the author can see. Placeholders and
+// held comments can't be replied to.
+func (s *commentService) ReplyToComment(content, parentID, authorID string) (*models.Comment, error) {
+	parent, err := s.GetComment(parentID, authorID)
 	if err != nil {
 		return nil, err
 	}
+	if parent.Hidden {
+		return nil, ErrCommentNotFound
+	}
+	if parent.Depth >= maxCommentDepth {
+		if parent, err = s.commentRepo.FindInThread(*parent.ParentID); err != nil {
+			return nil, ErrCommentNotFound
+		}
+	}
+	return s.create(content, parent.PostID, authorID, parent)
+}
 
+// create adds a comment to the post, as a reply to parent when it is set.
+func (s *commentService) create(content, postID, authorID string, parent *models.Comment) (*models.Comment, error) {
 	// Verify user exists
-	_, err = s.userRepo.FindByID(authorID)
+	_, err := s.userRepo.FindByID(authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	screened, err := screen(s.filter, filter.Comment, authorID, content)
+	if err != nil {
+		return nil, err
+	}
+
+	entities, err := s.entities.Extract(screened.Text)
 	if err != nil {
 		return nil, err
 	}
 
 	comment := &models.Comment{
-		Content:  content,
+		Content:  screened.Text,
 		PostID:   postID,
 		AuthorID: authorID,
+		Entities: entities,
+		Hidden:   screened.Verdict == filter.Hold,
+	}
+	if parent != nil {
+		comment.ParentID = &parent.ID
+		comment.Depth = parent.Depth + 1
 	}
 
 	if err := s.commentRepo.Create(comment); err != nil {
 		return nil, err
 	}
 
+	// Held comments stay out of the thread until a moderator approves them
+	if comment.Hidden {
+		if err := hold(s.review, screened, models.ReportTargetComment, comment.ID, authorID); err != nil {
+			return nil, err
+		}
+		return s.commentRepo.FindByID(comment.ID)
+	}
+
+	if err := s.entities.Index(models.EntitySourceComment, comment.ID, postID, authorID, entities, comment.CreatedAt); err != nil {
+		s.logger.Error("Failed to index comment entities", zap.String("comment_id", comment.ID), zap.Error(err))
+	}
+	if err := s.search.IndexComment(comment); err != nil {
+		s.logger.Error("Failed to index comment for search", zap.String("comment_id", comment.ID), zap.Error(err))
+	}
+
 	// Update post comments count
 	if err := s.UpdatePostCommentsCount(postID); err != nil {
-		// Log error but don't fail the request
+		s.logger.Error("Failed to update comment count", zap.String("post_id", postID), zap.Error(err))
+	}
+	if err := s.updateReplyCounts(comment.ParentID); err != nil {
+		s.logger.Error("Failed to update reply counts", zap.String("comment_id", comment.ID), zap.Error(err))
 	}
 
 	// Reload comment with author
-	comments, err := s.commentRepo.FindByPostID(postID)
-	if err != nil {
+	return s.commentRepo.FindByID(comment.ID)
+}
+
+func (s *commentService) GetComments(postID, viewerID, cursor string, limit int) (*CommentPage, error) {
+	if _, err := s.findVisiblePost(postID, viewerID); err != nil {
 		return nil, err
 	}
+	return s.findPage(repository.CommentPageQuery{PostID: postID}, viewerID, cursor, limit)
+}
 
-	// Find the newly created comment
-	for _, c := range comments {
-		if c.ID == comment.ID {
-			return &c, nil
+// GetReplies also lists the replies of a placeholder.
+func (s *commentService) GetReplies(commentID, viewerID, cursor string, limit int) (*CommentPage, error) {
+	parent, err := s.commentRepo.FindInThread(commentID)
+	if err != nil {
+		return nil, ErrCommentNotFound
+	}
+	if !parent.IsPlaceholder() {
+		visible, err := s.policy.CanViewUser(viewerID, parent.AuthorID)
+		if err != nil {
+			return nil, err
+		}
+		if !visible {
+			return nil, ErrCommentNotFound
 		}
 	}
+	if _, err := s.findVisiblePost(parent.PostID, viewerID); err != nil {
+		return nil, err
+	}
+	return s.findPage(repository.CommentPageQuery{PostID: parent.PostID, ParentID: parent.ID}, viewerID, cursor, limit)
+}
 
-	return comment, nil
+// findPage leaves out comments by users hidden from the viewer and loads one
+// extra row to learn whether another page exists.
+func (s *commentService) findPage(query repository.CommentPageQuery, viewerID, cursor string, limit int) (*CommentPage, error) {
+	after, err := repository.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := s.policy.HiddenUserIDs(viewerID)
+	if err != nil {
+		return nil, err
+	}
+	query.ExcludeAuthorIDs = hidden
+	query.After = after
+	query.Limit = limit + 1
+
+	comments, err := s.commentRepo.FindPage(query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &CommentPage{Comments: comments}
+	if len(comments) > limit {
+		page.Comments = comments[:limit]
+		last := page.Comments[limit-1]
+		page.NextCursor = repository.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	return page, nil
 }
 
-func (s *commentService) GetCommentsByPostID(postID string) ([]models.Comment, error) {
-	return s.commentRepo.FindByPostID(postID)
+func (s *commentService) GetThread(postID, viewerID string) ([]models.Comment, error) {
+	if _, err := s.findVisiblePost(postID, viewerID); err != nil {
+		return nil, err
+	}
+
+	comments, err := s.commentRepo.FindThread(postID)
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := s.policy.HiddenUserIDs(viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return filterComments(comments, hidden), nil
 }
 
-func (s *commentService) UpdateComment(commentID, userID, content string) (*models.Comment, error) {
+func (s *commentService) UpdateComment(commentID, userID, content string, version int) (*models.Comment, error) {
 	// Find comment by ID
 	comment, err := s.commentRepo.FindByID(commentID)
 	if err != nil {
@@ -89,28 +275,91 @@ func (s *commentService) UpdateComment(commentID, userID, content string) (*mode
 	if comment.AuthorID != userID {
 		return nil, nil // Not authorized
 	}
+	if version != 0 && version != comment.Version {
+		return nil, ErrStaleVersion
+	}
 
 	// Save old content for history
 	oldContent := comment.Content
 
+	screened, err := screen(s.filter, filter.Comment, userID, content)
+	if err != nil {
+		return nil, err
+	}
+
+	entities, err := s.entities.Extract(screened.Text)
+	if err != nil {
+		return nil, err
+	}
+
 	// Update content
-	comment.Content = content
-	if err := s.commentRepo.Update(comment); err != nil {
+	comment.Content = screened.Text
+	comment.Entities = entities
+	saved, err := s.commentRepo.Update(comment)
+	if err != nil {
 		return nil, err
 	}
+	if !saved {
+		return nil, ErrStaleVersion
+	}
+
+	// A held edit takes the comment out of its thread until a moderator
+	// approves it; comments hidden earlier stay out of the indexes
+	if screened.Verdict == filter.Hold {
+		if err := s.takeDown(comment, s.hide); err != nil {
+			return nil, err
+		}
+		if err := hold(s.review, screened, models.ReportTargetComment, commentID, userID); err != nil {
+			return nil, err
+		}
+	} else if !comment.Hidden {
+		if err := s.entities.Index(models.EntitySourceComment, commentID, comment.PostID, userID, entities, comment.CreatedAt); err != nil {
+			s.logger.Error("Failed to index comment entities", zap.String("comment_id", commentID), zap.Error(err))
+		}
+		if err := s.search.IndexComment(comment); err != nil {
+			s.logger.Error("Failed to index comment for search", zap.String("comment_id", commentID), zap.Error(err))
+		}
+	}
 
 	// Save edit history
-	history := models.NewEditHistory("comment", commentID, oldContent, content)
+	history := models.NewEditHistory("comment", commentID, userID, oldContent, comment.Content)
 	if err := s.historyRepo.Create(history); err != nil {
-		// Log error but don't fail the update
+		s.logger.Error("Failed to save comment edit history", zap.String("comment_id", commentID), zap.Error(err))
 	}
 
 	// Reload with author
 	return s.commentRepo.FindByID(commentID)
 }
 
-func (s *commentService) GetEditHistory(commentID string) ([]models.EditHistory, error) {
-	return s.historyRepo.FindByEntity("comment", commentID)
+// GetComment applies the same rules as the comment's thread: the post must
+// be visible, and hidden comments and comments by blocked users are left out.
+func (s *commentService) GetComment(commentID, viewerID string) (*models.Comment, error) {
+	comment, err := s.commentRepo.FindByID(commentID)
+	if err != nil {
+		return nil, ErrCommentNotFound
+	}
+	if comment.Hidden && comment.AuthorID != viewerID {
+		return nil, ErrCommentNotFound
+	}
+	visible, err := s.policy.CanViewUser(viewerID, comment.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, ErrCommentNotFound
+	}
+	if _, err := s.findVisiblePost(comment.PostID, viewerID); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (s *commentService) GetEditHistory(commentID, viewerID string) (*EditHistory, error) {
+	comment, err := s.GetComment(commentID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return findEditHistory(s.historyRepo, s.userRepo, "comment", commentID, comment.AuthorID, viewerID)
 }
 
 func (s *commentService) DeleteComment(commentID, userID string) error {
@@ -125,13 +374,144 @@ func (s *commentService) DeleteComment(commentID, userID string) error {
 		return nil // Not authorized
 	}
 
-	postID := comment.PostID
-	if err := s.commentRepo.Delete(commentID); err != nil {
+	return s.takeDown(comment, s.commentRepo.Delete)
+}
+
+// RemoveComment hides the comment before deleting it, which keeps it out of
+// its author's trash.
+func (s *commentService) RemoveComment(commentID string) error {
+	comment, err := s.commentRepo.FindByID(commentID)
+	if err != nil {
+		return ErrCommentNotFound
+	}
+	return s.takeDown(comment, func(id string) error {
+		if err := s.hide(id); err != nil {
+			return err
+		}
+		return s.commentRepo.Delete(id)
+	})
+}
+
+// RestoreComment undoes DeleteComment, as long as the post is still there
+// and visible to the author.
+func (s *commentService) RestoreComment(commentID, userID string) (*models.Comment, error) {
+	comment, err := s.commentRepo.FindDeleted(commentID)
+	// An empty comment is a placeholder whose content was already purged
+	if err != nil || comment.AuthorID != userID || comment.Hidden || comment.Content == "" {
+		return nil, ErrTrashItemNotFound
+	}
+	if _, err := s.findVisiblePost(comment.PostID, userID); err != nil {
+		return nil, err
+	}
+	// A reply can't come back once the comment it answered has been purged
+	if comment.ParentID != nil {
+		if _, err := s.findWithDeleted(*comment.ParentID); err != nil {
+			return nil, ErrTrashItemNotFound
+		}
+	}
+
+	if err := s.commentRepo.Restore(commentID); err != nil {
+		return nil, err
+	}
+	if err := s.entities.Index(models.EntitySourceComment, comment.ID, comment.PostID, userID, comment.Entities, comment.CreatedAt); err != nil {
+		s.logger.Error("Failed to index comment entities", zap.String("comment_id", comment.ID), zap.Error(err))
+	}
+	if err := s.search.IndexComment(comment); err != nil {
+		s.logger.Error("Failed to index comment for search", zap.String("comment_id", comment.ID), zap.Error(err))
+	}
+	if err := s.UpdatePostCommentsCount(comment.PostID); err != nil {
+		return nil, err
+	}
+	if err := s.updateReplyCounts(comment.ParentID); err != nil {
+		return nil, err
+	}
+
+	return s.commentRepo.FindByID(commentID)
+}
+
+func (s *commentService) findWithDeleted(id string) (*models.Comment, error) {
+	comment, err := s.commentRepo.FindByID(id)
+	if err != nil {
+		return s.commentRepo.FindDeleted(id)
+	}
+	return comment, nil
+}
+
+// updateReplyCounts recounts the replies of parentID and of each comment
+// above it. Whether a deleted comment stays in its thread as a placeholder
+// depends on its count, so a change can travel all the way up.
+func (s *commentService) updateReplyCounts(parentID *string) error {
+	for parentID != nil {
+		count, err := s.commentRepo.CountReplies(*parentID)
+		if err != nil {
+			return err
+		}
+		if err := s.commentRepo.SetReplies(*parentID, int(count)); err != nil {
+			return err
+		}
+		parent, err := s.findWithDeleted(*parentID)
+		if err != nil {
+			return err
+		}
+		parentID = parent.ParentID
+	}
+	return nil
+}
+
+func (s *commentService) HideComment(commentID string) error {
+	comment, err := s.commentRepo.FindByID(commentID)
+	if err != nil {
+		return ErrCommentNotFound
+	}
+	return s.takeDown(comment, s.hide)
+}
+
+func (s *commentService) hide(id string) error {
+	return s.commentRepo.SetHidden(id, true)
+}
+
+// UnhideComment undoes HideComment, putting the comment back in its thread,
+// the derived indexes and its post's comment count.
+func (s *commentService) UnhideComment(commentID string) error {
+	comment, err := s.commentRepo.FindByID(commentID)
+	if err != nil {
+		return ErrCommentNotFound
+	}
+	if err := s.commentRepo.SetHidden(commentID, false); err != nil {
+		return err
+	}
+	comment.Hidden = false
+	if err := s.entities.Index(models.EntitySourceComment, comment.ID, comment.PostID, comment.AuthorID, comment.Entities, comment.CreatedAt); err != nil {
+		return err
+	}
+	if err := s.search.IndexComment(comment); err != nil {
+		return err
+	}
+	if err := s.updateReplyCounts(comment.ParentID); err != nil {
+		return err
+	}
+	return s.UpdatePostCommentsCount(comment.PostID)
+}
+
+// takeDown deletes or hides a comment with apply, then drops it from the
+// derived indexes and its post's comment count. A comment with replies
+// stays in its thread as a placeholder.
+func (s *commentService) takeDown(comment *models.Comment, apply func(id string) error) error {
+	if err := apply(comment.ID); err != nil {
+		return err
+	}
+	if err := s.updateReplyCounts(comment.ParentID); err != nil {
+		return err
+	}
+	if err := s.entities.Remove(models.EntitySourceComment, comment.ID); err != nil {
+		return err
+	}
+	if err := s.search.Remove(search.Comment, comment.ID); err != nil {
 		return err
 	}
 
 	// Update post comments count
-	return s.UpdatePostCommentsCount(postID)
+	return s.UpdatePostCommentsCount(comment.PostID)
 }
 
 func (s *commentService) UpdatePostCommentsCount(postID string) error {
@@ -142,3 +522,39 @@ func (s *commentService) UpdatePostCommentsCount(postID string) error {
 	return s.postRepo.UpdateCommentsCount(postID, int(count))
 }
 
+
+func (s *commentService) ReactToComment(commentID, userID, reaction string) error {
+	// GetComment applies the block rules too, so blocked users can't react
+	if _, err := s.GetComment(commentID, userID); err != nil {
+		return err
+	}
+	return s.reactions.React(models.ReactionTargetComment, commentID, userID, reaction)
+}
+
+func (s *commentService) GetReactionSummary(commentID, viewerID string) (*repository.ReactionSummary, error) {
+	if _, err := s.GetComment(commentID, viewerID); err != nil {
+		return nil, err
+	}
+	summaries, err := s.reactions.GetSummaries(models.ReactionTargetComment, []string{commentID}, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return summaries[commentID], nil
+}
+
+func (s *commentService) GetReactionSummaries(comments []models.Comment, viewerID string) (map[string]*repository.ReactionSummary, error) {
+	commentIDs := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		if !comment.IsPlaceholder() {
+			commentIDs = append(commentIDs, comment.ID)
+		}
+	}
+	return s.reactions.GetSummaries(models.ReactionTargetComment, commentIDs, viewerID)
+}
+
+func (s *commentService) GetReactors(commentID, viewerID, reaction, cursor string, limit int) (*ReactorPage, error) {
+	if _, err := s.GetComment(commentID, viewerID); err != nil {
+		return nil, err
+	}
+	return s.reactions.GetReactors(models.ReactionTargetComment, commentID, viewerID, reaction, cursor, limit)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+)
+
+// ErrUserNotFound is also returned for users hidden by a block.
+var ErrUserNotFound = errors.New("ไม่พบผู้ใช้")
+
+// ContentPolicy decides what one user may see of, and do to, another user's
+// content. Blocking works both ways: once either user blocks the other,
+// neither sees the other's profile, posts or comments.
+type ContentPolicy interface {
+	// CanViewUser is false when either user has blocked the other.
+	CanViewUser(viewerID, userID string) (bool, error)
+	// CanViewPost applies the post's visibility level, publishing status,
+	// moderation and blocks.
+	CanViewPost(viewerID string, post *models.Post) (bool, error)
+	// CanInteract reports whether actorID may comment on or react to content owned by ownerID.
+	CanInteract(actorID, ownerID string) (bool, error)
+	// HiddenUserIDs lists the users whose content viewerID must not see.
+	HiddenUserIDs(viewerID string) ([]string, error)
+}
+
+type contentPolicy struct {
+	friendRepo repository.FriendRepository
+}
+
+func NewContentPolicy(friendRepo repository.FriendRepository) ContentPolicy {
+	return &contentPolicy{friendRepo: friendRepo}
+}
+
+func (p *contentPolicy) CanViewUser(viewerID, userID string) (bool, error) {
+	if viewerID == userID {
+		return true, nil
+	}
+	blocked, err := p.friendRepo.IsBlocked(viewerID, userID)
+	if err != nil {
+		return false, err
+	}
+	return !blocked, nil
+}
+
+func (p *contentPolicy) CanViewPost(viewerID string, post *models.Post) (bool, error) {
+	if post.AuthorID == viewerID {
+		return true, nil
+	}
+	if !post.IsPublished() || post.Hidden {
+		return false, nil
+	}
+
+	visible, err := p.CanViewUser(viewerID, post.AuthorID)
+	if err != nil || !visible {
+		return false, err
+	}
+
+	switch post.Visibility {
+	case models.PostVisibilityPublic, "":
+		return true, nil
+	case models.PostVisibilityFriends:
+		return p.friendRepo.IsFriend(post.AuthorID, viewerID)
+	default:
+		return false, nil
+	}
+}
+
+func (p *contentPolicy) CanInteract(actorID, ownerID string) (bool, error) {
+	return p.CanViewUser(actorID, ownerID)
+}
+
+func (p *contentPolicy) HiddenUserIDs(viewerID string) ([]string, error) {
+	return p.friendRepo.GetBlockRelatedUserIDs(viewerID)
+}
+
+// filterComments drops comments whose authors are in hidden.
+func filterComments(comments []models.Comment, hidden []string) []models.Comment {
+	if len(hidden) == 0 {
+		return comments
+	}
+	skip := make(map[string]bool, len(hidden))
+	for _, id := range hidden {
+		skip[id] = true
+	}
+
+	filtered := make([]models.Comment, 0, len(comments))
+	for _, comment := range comments {
+		if !skip[comment.AuthorID] {
+			filtered = append(filtered, comment)
+		}
+	}
+	return filtered
+}
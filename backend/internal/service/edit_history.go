@@ -0,0 +1,49 @@
+package service
+
+import (
+	"errors"
+
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+)
+
+// ErrEditHistoryHidden is returned when the author keeps their edit history
+// to themselves.
+var ErrEditHistoryHidden = errors.New("ผู้เขียนซ่อนประวัติการแก้ไข")
+
+// EditHistory is the edit history of a post or comment as one viewer may
+// see it. With TimestampsOnly set the author shares when they edited but
+// not what changed, and Entries carry no content.
+type EditHistory struct {
+	Entries        []models.EditHistory
+	TimestampsOnly bool
+}
+
+// findEditHistory loads an entity's history and applies its author's
+// EditHistoryVisibility setting. Authors always see their full history.
+func findEditHistory(historyRepo repository.EditHistoryRepository, userRepo repository.UserRepository, entityType, entityID, authorID, viewerID string) (*EditHistory, error) {
+	visibility := models.EditHistoryFull
+	if authorID != viewerID {
+		author, err := userRepo.FindByID(authorID)
+		if err != nil {
+			return nil, err
+		}
+		visibility = author.EditHistoryVisibility
+	}
+	if visibility == models.EditHistoryHidden {
+		return nil, ErrEditHistoryHidden
+	}
+
+	entries, err := historyRepo.FindByEntity(entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	history := &EditHistory{Entries: entries, TimestampsOnly: visibility == models.EditHistoryTimestamps}
+	if history.TimestampsOnly {
+		for i := range history.Entries {
+			history.Entries[i].OldContent = ""
+			history.Entries[i].NewContent = ""
+		}
+	}
+	return history, nil
+}
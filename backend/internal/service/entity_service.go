@@ -0,0 +1,191 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+	"typinggame-api/internal/textseg"
+)
+
+const maxTrendingWindow = 7 * 24 * time.Hour
+
+// MentionPage is one page of mentions of a user. NextCursor is empty on the
+// last page.
+type MentionPage struct {
+	Mentions   []models.Mention
+	NextCursor string
+}
+
+// EntityService keeps the hashtag and @mention index of posts and comments.
+type EntityService interface {
+	// Extract finds the entities in text and resolves mentions to users.
+	Extract(text string) (models.TextEntities, error)
+	// Index replaces the index rows of one post or comment.
+	Index(sourceType, sourceID, postID, authorID string, entities models.TextEntities, createdAt time.Time) error
+	Remove(sourceType, sourceID string) error
+	// GetTrending only counts tags in posts viewerID may see, and in
+	// comments on them, leaving out blocked users.
+	GetTrending(viewerID string, window time.Duration, limit int) ([]repository.TagCount, error)
+	GetMentions(userID, cursor string, limit int) (*MentionPage, error)
+}
+
+type entityService struct {
+	entityRepo repository.EntityRepository
+	userRepo   repository.UserRepository
+	postRepo   repository.PostRepository
+	policy     ContentPolicy
+}
+
+func NewEntityService(entityRepo repository.EntityRepository, userRepo repository.UserRepository, postRepo repository.PostRepository, policy ContentPolicy) EntityService {
+	return &entityService{
+		entityRepo: entityRepo,
+		userRepo:   userRepo,
+		postRepo:   postRepo,
+		policy:     policy,
+	}
+}
+
+func (s *entityService) Extract(text string) (models.TextEntities, error) {
+	parsed := textseg.Parse(text)
+	if len(parsed) == 0 {
+		return nil, nil
+	}
+
+	users, err := s.userRepo.FindByHandles(textseg.Values(parsed, textseg.Mention))
+	if err != nil {
+		return nil, err
+	}
+	// A handle only resolves when exactly one user has it; names aren't unique
+	matches := map[string][]string{}
+	for _, user := range users {
+		handle := textseg.Normalize(strings.ReplaceAll(user.Name, " ", "_"))
+		matches[handle] = append(matches[handle], user.ID)
+	}
+
+	entities := make(models.TextEntities, 0, len(parsed))
+	for _, entity := range parsed {
+		textEntity := models.TextEntity{
+			Type:  string(entity.Kind),
+			Value: entity.Value,
+			Start: entity.Start,
+			End:   entity.End,
+		}
+		if entity.Kind == textseg.Mention && len(matches[entity.Value]) == 1 {
+			textEntity.UserID = matches[entity.Value][0]
+		}
+		entities = append(entities, textEntity)
+	}
+	return entities, nil
+}
+
+func (s *entityService) Index(sourceType, sourceID, postID, authorID string, entities models.TextEntities, createdAt time.Time) error {
+	var tags []models.HashtagUse
+	var mentions []models.Mention
+	seenTags := map[string]bool{}
+	seenUsers := map[string]bool{}
+
+	for _, entity := range entities {
+		switch {
+		case entity.Type == string(textseg.Hashtag) && !seenTags[entity.Value]:
+			seenTags[entity.Value] = true
+			tags = append(tags, models.HashtagUse{
+				Tag:        entity.Value,
+				SourceType: sourceType,
+				SourceID:   sourceID,
+				PostID:     postID,
+				AuthorID:   authorID,
+				CreatedAt:  createdAt,
+			})
+		// Mentioning yourself doesn't show up in your own mentions
+		case entity.UserID != "" && entity.UserID != authorID && !seenUsers[entity.UserID]:
+			seenUsers[entity.UserID] = true
+			mentions = append(mentions, models.Mention{
+				SourceType:      sourceType,
+				SourceID:        sourceID,
+				PostID:          postID,
+				AuthorID:        authorID,
+				MentionedUserID: entity.UserID,
+				CreatedAt:       createdAt,
+			})
+		}
+	}
+	return s.entityRepo.ReplaceForSource(sourceType, sourceID, tags, mentions)
+}
+
+func (s *entityService) Remove(sourceType, sourceID string) error {
+	return s.entityRepo.DeleteForSource(sourceType, sourceID)
+}
+
+func (s *entityService) GetTrending(viewerID string, window time.Duration, limit int) ([]repository.TagCount, error) {
+	if window <= 0 || window > maxTrendingWindow {
+		window = maxTrendingWindow
+	}
+	hidden, err := s.policy.HiddenUserIDs(viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return s.entityRepo.Trending(repository.TrendingQuery{
+		ViewerID:         viewerID,
+		ExcludeAuthorIDs: hidden,
+		Since:            time.Now().Add(-window),
+		Limit:            limit,
+	})
+}
+
+// GetMentions lists where userID was mentioned, leaving out blocked users and
+// posts userID is no longer allowed to see. Pages can come back shorter than
+// the limit because of that filtering.
+func (s *entityService) GetMentions(userID, cursor string, limit int) (*MentionPage, error) {
+	after, err := repository.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := s.policy.HiddenUserIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	mentions, err := s.entityRepo.FindMentions(repository.MentionPageQuery{
+		UserID:           userID,
+		ExcludeAuthorIDs: hidden,
+		After:            after,
+		Limit:            limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &MentionPage{}
+	if len(mentions) > limit {
+		mentions = mentions[:limit]
+		last := mentions[limit-1]
+		page.NextCursor = repository.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	postIDs := make([]string, 0, len(mentions))
+	for _, mention := range mentions {
+		postIDs = append(postIDs, mention.PostID)
+	}
+	posts, err := s.postRepo.FindByIDs(postIDs)
+	if err != nil {
+		return nil, err
+	}
+	visible := make(map[string]bool, len(posts))
+	for i := range posts {
+		ok, err := s.policy.CanViewPost(userID, &posts[i])
+		if err != nil {
+			return nil, err
+		}
+		visible[posts[i].ID] = ok
+	}
+
+	page.Mentions = make([]models.Mention, 0, len(mentions))
+	for _, mention := range mentions {
+		if visible[mention.PostID] {
+			page.Mentions = append(page.Mentions, mention)
+		}
+	}
+	return page, nil
+}
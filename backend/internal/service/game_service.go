@@ -1,42 +1,272 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
+	"typinggame-api/config"
 	"typinggame-api/internal/models"
 	"typinggame-api/internal/repository"
+
+	"gorm.io/gorm"
 )
 
+// Allowed clock drift between an offline client and the server.
+const maxClockSkew = 5 * time.Minute
+
+// ErrInvalidScore wraps every reason a submitted run is refused.
+var ErrInvalidScore = errors.New("invalid score")
+
+const (
+	ScoreSyncCreated   = "created"
+	ScoreSyncDuplicate = "duplicate"
+	ScoreSyncRejected  = "rejected"
+)
+
+// ScoreSubmission is a run recorded locally by a client, possibly while offline.
+type ScoreSubmission struct {
+	ClientID   string
+	Score      int
+	WordsTyped int
+	Difficulty string
+	WPM        float64
+	Accuracy   float64
+	PlayedAt   time.Time
+}
+
+// LeaderboardFilter selects which leaderboard a client is looking at.
+type LeaderboardFilter struct {
+	Difficulty string // empty for every difficulty
+	Period     string // all, day, week, month
+	Friends    bool   // only the viewer and their friends
+	Limit      int
+}
+
+// ScoreListener is told about every score that is stored, e.g. to push
+// leaderboard updates to connected clients.
+type ScoreListener interface {
+	ScoreSaved(score *models.GameScore)
+}
+
+// ScoreSyncResult reports what happened to a single ScoreSubmission in a batch.
+type ScoreSyncResult struct {
+	ClientID string
+	Status   string // created, duplicate, rejected
+	Score    *models.GameScore
+	Message  string
+}
+
 type GameService interface {
-	SaveScore(userID string, score, wordsTyped int, difficulty string) (*models.GameScore, error)
-	GetTopScores(limit int) ([]models.GameScore, error)
-	GetTopScoresByDifficulty(difficulty string, limit int) ([]models.GameScore, error)
+	SaveScore(userID string, sub ScoreSubmission) (*models.GameScore, error)
+	SaveScoresBatch(userID string, submissions []ScoreSubmission) ([]ScoreSyncResult, error)
+	GetLeaderboard(viewerID string, filter LeaderboardFilter) ([]models.GameScore, error)
 	GetUserBestScore(userID string) (*models.GameScore, error)
 }
 
 type gameService struct {
-	scoreRepo repository.GameScoreRepository
+	scoreRepo  repository.GameScoreRepository
+	userRepo   repository.UserRepository
+	friendRepo repository.FriendRepository
+	listener   ScoreListener
 }
 
-func NewGameService(scoreRepo repository.GameScoreRepository) GameService {
-	return &gameService{scoreRepo: scoreRepo}
+func NewGameService(scoreRepo repository.GameScoreRepository, userRepo repository.UserRepository, friendRepo repository.FriendRepository, listener ScoreListener) GameService {
+	return &gameService{
+		scoreRepo:  scoreRepo,
+		userRepo:   userRepo,
+		friendRepo: friendRepo,
+		listener:   listener,
+	}
 }
 
-func (s *gameService) SaveScore(userID string, score, wordsTyped int, difficulty string) (*models.GameScore, error) {
-	gameScore := models.NewGameScore(userID, score, wordsTyped, difficulty)
+func (s *gameService) SaveScore(userID string, sub ScoreSubmission) (*models.GameScore, error) {
+	if err := validateStats(sub); err != nil {
+		return nil, err
+	}
+
+	player, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	gameScore := models.NewGameScore(userID, sub.Score, sub.WordsTyped, sub.Difficulty)
+	gameScore.WPM = sub.WPM
+	gameScore.Accuracy = sub.Accuracy
+	gameScore.IsGuest = player.IsGuest
+	gameScore.Flagged = implausible(sub)
 	if err := s.scoreRepo.Create(gameScore); err != nil {
 		return nil, err
 	}
+	s.notify(gameScore)
 	return gameScore, nil
 }
 
-func (s *gameService) GetTopScores(limit int) ([]models.GameScore, error) {
-	return s.scoreRepo.GetTopScores(limit)
+func (s *gameService) SaveScoresBatch(userID string, submissions []ScoreSubmission) ([]ScoreSyncResult, error) {
+	if len(submissions) == 0 {
+		return nil, errors.New("no scores to sync")
+	}
+	if max := config.Get().Game.MaxBatchSize; len(submissions) > max {
+		return nil, errors.New("too many scores in one batch")
+	}
+
+	player, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ScoreSyncResult, 0, len(submissions))
+	for _, sub := range submissions {
+		results = append(results, s.syncScore(player, sub))
+	}
+	return results, nil
 }
 
-func (s *gameService) GetTopScoresByDifficulty(difficulty string, limit int) ([]models.GameScore, error) {
-	return s.scoreRepo.GetTopScoresByDifficulty(difficulty, limit)
+func (s *gameService) syncScore(player *models.User, sub ScoreSubmission) ScoreSyncResult {
+	userID := player.ID
+	result := ScoreSyncResult{ClientID: sub.ClientID}
+
+	if err := validateSubmission(sub, time.Now()); err != nil {
+		result.Status = ScoreSyncRejected
+		result.Message = err.Error()
+		return result
+	}
+
+	// A retry of a run we already stored is not an error, just report the original
+	if existing, err := s.scoreRepo.FindByClientID(userID, sub.ClientID); err == nil {
+		result.Status = ScoreSyncDuplicate
+		result.Score = existing
+		return result
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		result.Status = ScoreSyncRejected
+		result.Message = err.Error()
+		return result
+	}
+
+	clientID := sub.ClientID
+	gameScore := models.NewGameScore(userID, sub.Score, sub.WordsTyped, sub.Difficulty)
+	gameScore.ClientID = &clientID
+	gameScore.WPM = sub.WPM
+	gameScore.Accuracy = sub.Accuracy
+	gameScore.PlayedAt = sub.PlayedAt
+	gameScore.IsGuest = player.IsGuest
+	gameScore.Flagged = implausible(sub)
+
+	if err := s.scoreRepo.Create(gameScore); err != nil {
+		// Two retries can race past the lookup above; the unique index lets only one win
+		if existing, findErr := s.scoreRepo.FindByClientID(userID, sub.ClientID); findErr == nil {
+			result.Status = ScoreSyncDuplicate
+			result.Score = existing
+			return result
+		}
+		result.Status = ScoreSyncRejected
+		result.Message = err.Error()
+		return result
+	}
+
+	s.notify(gameScore)
+	result.Status = ScoreSyncCreated
+	result.Score = gameScore
+	return result
+}
+
+func (s *gameService) notify(score *models.GameScore) {
+	if s.listener != nil && !score.IsGuest {
+		s.listener.ScoreSaved(score)
+	}
+}
+
+func validateSubmission(sub ScoreSubmission, now time.Time) error {
+	if sub.ClientID == "" || len(sub.ClientID) > 64 {
+		return fmt.Errorf("%w: clientId is required and must be at most 64 characters", ErrInvalidScore)
+	}
+	if sub.Score < 0 || sub.WordsTyped < 0 {
+		return fmt.Errorf("%w: score and wordsTyped must not be negative", ErrInvalidScore)
+	}
+	if err := validateStats(sub); err != nil {
+		return err
+	}
+	if sub.Difficulty == "" {
+		return fmt.Errorf("%w: difficulty is required", ErrInvalidScore)
+	}
+	if sub.PlayedAt.IsZero() {
+		return fmt.Errorf("%w: playedAt is required", ErrInvalidScore)
+	}
+	if sub.PlayedAt.After(now.Add(maxClockSkew)) {
+		return fmt.Errorf("%w: playedAt is in the future", ErrInvalidScore)
+	}
+	if sub.PlayedAt.Before(now.Add(-config.Get().Game.MaxBackdate)) {
+		return fmt.Errorf("%w: playedAt is older than the allowed sync window", ErrInvalidScore)
+	}
+	return nil
+}
+
+func validateStats(sub ScoreSubmission) error {
+	if sub.WPM < 0 {
+		return fmt.Errorf("%w: wpm must not be negative", ErrInvalidScore)
+	}
+	if sub.Accuracy < 0 || sub.Accuracy > 100 {
+		return fmt.Errorf("%w: accuracy must be between 0 and 100", ErrInvalidScore)
+	}
+	return nil
+}
+
+// implausible reports whether a run is too fast to be real. Such runs are
+// kept, so the player sees them, but flagged: leaderboards and certificates
+// leave them out.
+func implausible(sub ScoreSubmission) bool {
+	return sub.WPM > maxPlausibleWPM
+}
+
+func (s *gameService) GetLeaderboard(viewerID string, filter LeaderboardFilter) ([]models.GameScore, error) {
+	query, err := leaderboardQuery(s.friendRepo, viewerID, filter, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return s.scoreRepo.GetLeaderboard(query)
+}
+
+// ValidLeaderboardPeriod reports whether period is one GetLeaderboard understands.
+func ValidLeaderboardPeriod(period string) bool {
+	switch period {
+	case "", "all", "day", "week", "month":
+		return true
+	}
+	return false
+}
+
+func periodStart(period string, now time.Time) time.Time {
+	switch period {
+	case "day":
+		return now.AddDate(0, 0, -1)
+	case "week":
+		return now.AddDate(0, 0, -7)
+	case "month":
+		return now.AddDate(0, -1, 0)
+	}
+	return time.Time{}
+}
+
+func leaderboardQuery(friendRepo repository.FriendRepository, viewerID string, filter LeaderboardFilter, now time.Time) (repository.LeaderboardQuery, error) {
+	query := repository.LeaderboardQuery{
+		Difficulty: filter.Difficulty,
+		Since:      periodStart(filter.Period, now),
+		Limit:      filter.Limit,
+	}
+
+	if filter.Friends {
+		friends, err := friendRepo.GetFriends(viewerID)
+		if err != nil {
+			return query, err
+		}
+		query.UserIDs = []string{viewerID}
+		for _, friend := range friends {
+			query.UserIDs = append(query.UserIDs, friend.ID)
+		}
+	}
+	return query, nil
 }
 
 func (s *gameService) GetUserBestScore(userID string) (*models.GameScore, error) {
 	return s.scoreRepo.GetUserBestScore(userID)
 }
-
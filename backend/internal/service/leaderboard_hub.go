@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+)
+
+const (
+	// Scores arriving within one interval produce at most one event per board
+	leaderboardCoalesceInterval = 2 * time.Second
+	// Period boards also change as old scores age out, even with no new scores
+	leaderboardPeriodRefresh = time.Minute
+)
+
+// LeaderboardEntry is one row of a pushed leaderboard.
+type LeaderboardEntry struct {
+	Rank       int       `json:"rank"`
+	ScoreID    string    `json:"id"`
+	UserID     string    `json:"userId"`
+	UserName   string    `json:"userName"`
+	Score      int       `json:"score"`
+	WordsTyped int       `json:"wordsTyped"`
+	Difficulty string    `json:"difficulty"`
+	WPM        float64   `json:"wpm"`
+	Accuracy   float64   `json:"accuracy"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// RankChange describes a score that entered the board or moved on it.
+// PreviousRank is 0 for new entries.
+type RankChange struct {
+	ScoreID      string `json:"id"`
+	UserID       string `json:"userId"`
+	UserName     string `json:"userName"`
+	Rank         int    `json:"rank"`
+	PreviousRank int    `json:"previousRank"`
+}
+
+type LeaderboardEvent struct {
+	Entries []LeaderboardEntry `json:"entries"`
+	Changes []RankChange       `json:"changes"`
+}
+
+// LeaderboardSubscription receives the latest state of one board. Events only
+// holds the newest update; a slow client skips intermediate ones.
+type LeaderboardSubscription struct {
+	Snapshot []LeaderboardEntry
+	Events   <-chan LeaderboardEvent
+
+	events chan LeaderboardEvent
+	board  *leaderboardBoard
+}
+
+type LeaderboardHub interface {
+	ScoreListener
+	Subscribe(viewerID string, filter LeaderboardFilter) (*LeaderboardSubscription, error)
+	Unsubscribe(sub *LeaderboardSubscription)
+	Run(ctx context.Context)
+}
+
+type leaderboardBoard struct {
+	key         string
+	viewerID    string
+	filter      LeaderboardFilter
+	memberIDs   map[string]bool // nil unless this is a friends board
+	entries     []LeaderboardEntry
+	subscribers map[*LeaderboardSubscription]struct{}
+	dirty       bool
+	refreshedAt time.Time
+}
+
+type leaderboardHub struct {
+	scoreRepo  repository.GameScoreRepository
+	friendRepo repository.FriendRepository
+
+	mu     sync.Mutex
+	boards map[string]*leaderboardBoard
+	closed bool
+}
+
+func NewLeaderboardHub(scoreRepo repository.GameScoreRepository, friendRepo repository.FriendRepository) LeaderboardHub {
+	return &leaderboardHub{
+		scoreRepo:  scoreRepo,
+		friendRepo: friendRepo,
+		boards:     make(map[string]*leaderboardBoard),
+	}
+}
+
+func boardKey(viewerID string, filter LeaderboardFilter) string {
+	key := filter.Difficulty + "|" + filter.Period + "|" + strconv.Itoa(filter.Limit)
+	// Friends boards differ per viewer, public boards are shared
+	if filter.Friends {
+		key += "|friends:" + viewerID
+	}
+	return key
+}
+
+func (h *leaderboardHub) Subscribe(viewerID string, filter LeaderboardFilter) (*LeaderboardSubscription, error) {
+	query, err := leaderboardQuery(h.friendRepo, viewerID, filter, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	scores, err := h.scoreRepo.GetLeaderboard(query)
+	if err != nil {
+		return nil, err
+	}
+	entries := toLeaderboardEntries(scores)
+
+	events := make(chan LeaderboardEvent, 1)
+	sub := &LeaderboardSubscription{
+		Snapshot: entries,
+		Events:   events,
+		events:   events,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(events)
+		return sub, nil
+	}
+
+	key := boardKey(viewerID, filter)
+	board, ok := h.boards[key]
+	if !ok {
+		board = &leaderboardBoard{
+			key:         key,
+			viewerID:    viewerID,
+			filter:      filter,
+			entries:     entries,
+			subscribers: make(map[*LeaderboardSubscription]struct{}),
+			refreshedAt: time.Now(),
+		}
+		if query.UserIDs != nil {
+			board.memberIDs = make(map[string]bool, len(query.UserIDs))
+			for _, id := range query.UserIDs {
+				board.memberIDs[id] = true
+			}
+		}
+		h.boards[key] = board
+	}
+	board.subscribers[sub] = struct{}{}
+	sub.board = board
+
+	return sub, nil
+}
+
+func (h *leaderboardHub) Unsubscribe(sub *LeaderboardSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	board := sub.board
+	if board == nil {
+		return
+	}
+	if _, ok := board.subscribers[sub]; !ok {
+		return
+	}
+	delete(board.subscribers, sub)
+	close(sub.events)
+	if len(board.subscribers) == 0 {
+		delete(h.boards, board.key)
+	}
+}
+
+// ScoreSaved only marks affected boards; the expensive recompute happens on
+// the next tick of Run so a burst of scores costs one query per board.
+// Passage runs have boards of their own and never touch these.
+func (h *leaderboardHub) ScoreSaved(score *models.GameScore) {
+	if score.IsGuest || score.Flagged || score.PassageID != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, board := range h.boards {
+		if board.filter.Difficulty != "" && board.filter.Difficulty != score.Difficulty {
+			continue
+		}
+		if board.memberIDs != nil && !board.memberIDs[score.UserID] {
+			continue
+		}
+		board.dirty = true
+	}
+}
+
+// Run refreshes dirty boards until ctx is cancelled, then closes every
+// subscription so streaming handlers return and the server can shut down.
+func (h *leaderboardHub) Run(ctx context.Context) {
+	ticker := time.NewTicker(leaderboardCoalesceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.closeAll()
+			return
+		case now := <-ticker.C:
+			h.refresh(now)
+		}
+	}
+}
+
+func (h *leaderboardHub) refresh(now time.Time) {
+	h.mu.Lock()
+	var due []*leaderboardBoard
+	for _, board := range h.boards {
+		periodic := board.filter.Period != "" && board.filter.Period != "all" &&
+			now.Sub(board.refreshedAt) >= leaderboardPeriodRefresh
+		if board.dirty || periodic {
+			board.dirty = false
+			board.refreshedAt = now
+			due = append(due, board)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, board := range due {
+		query, err := leaderboardQuery(h.friendRepo, board.viewerID, board.filter, now)
+		if err != nil {
+			continue
+		}
+		scores, err := h.scoreRepo.GetLeaderboard(query)
+		if err != nil {
+			continue
+		}
+		entries := toLeaderboardEntries(scores)
+
+		h.mu.Lock()
+		changes := rankChanges(board.entries, entries)
+		if changes != nil {
+			board.entries = entries
+			event := LeaderboardEvent{Entries: entries, Changes: changes}
+			for sub := range board.subscribers {
+				publishLatest(sub.events, event)
+			}
+		}
+		h.mu.Unlock()
+	}
+}
+
+func (h *leaderboardHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for key, board := range h.boards {
+		for sub := range board.subscribers {
+			close(sub.events)
+			delete(board.subscribers, sub)
+		}
+		delete(h.boards, key)
+	}
+}
+
+// publishLatest replaces any undelivered event with the newer one.
+func publishLatest(events chan LeaderboardEvent, event LeaderboardEvent) {
+	select {
+	case <-events:
+	default:
+	}
+	select {
+	case events <- event:
+	default:
+	}
+}
+
+func toLeaderboardEntries(scores []models.GameScore) []LeaderboardEntry {
+	entries := make([]LeaderboardEntry, 0, len(scores))
+	for i, score := range scores {
+		entries = append(entries, LeaderboardEntry{
+			Rank:       i + 1,
+			ScoreID:    score.ID,
+			UserID:     score.UserID,
+			UserName:   score.User.Name,
+			Score:      score.Score,
+			WordsTyped: score.WordsTyped,
+			Difficulty: score.Difficulty,
+			WPM:        score.WPM,
+			Accuracy:   score.Accuracy,
+			CreatedAt:  score.CreatedAt,
+		})
+	}
+	return entries
+}
+
+// rankChanges returns nil when the board is unchanged, so callers can skip
+// sending anything.
+func rankChanges(before, after []LeaderboardEntry) []RankChange {
+	previous := make(map[string]int, len(before))
+	for _, entry := range before {
+		previous[entry.ScoreID] = entry.Rank
+	}
+
+	changes := []RankChange{}
+	for _, entry := range after {
+		if previous[entry.ScoreID] == entry.Rank {
+			continue
+		}
+		changes = append(changes, RankChange{
+			ScoreID:      entry.ScoreID,
+			UserID:       entry.UserID,
+			UserName:     entry.UserName,
+			Rank:         entry.Rank,
+			PreviousRank: previous[entry.ScoreID],
+		})
+	}
+
+	// Entries can also fall off the bottom without anything else moving
+	if len(changes) == 0 && len(before) == len(after) {
+		return nil
+	}
+	return changes
+}
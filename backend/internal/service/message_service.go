@@ -2,10 +2,13 @@ package service
 
 import (
 	"errors"
+	"typinggame-api/internal/filter"
 	"typinggame-api/internal/models"
 	"typinggame-api/internal/repository"
 )
 
+var ErrMessageNotFound = errors.New("ไม่พบข้อความ")
+
 type MessageService interface {
 	GetOrCreateConversation(user1ID, user2ID string) (*models.Conversation, error)
 	GetConversations(userID string) ([]models.Conversation, error)
@@ -13,17 +16,29 @@ type MessageService interface {
 	GetMessages(conversationID string, limit, offset int) ([]models.Message, error)
 	GetUnreadCount(userID, conversationID string) (int64, error)
 	MarkAsRead(conversationID, userID string) error
+	// ReactToMessage is limited to the two people in the conversation, and
+	// not while either has blocked the other.
+	ReactToMessage(messageID, userID, reaction string) error
+	GetReactionSummary(messageID, viewerID string) (*repository.ReactionSummary, error)
+	GetReactionSummaries(messages []models.Message, viewerID string) (map[string]*repository.ReactionSummary, error)
+	GetReactors(messageID, viewerID, reaction, cursor string, limit int) (*ReactorPage, error)
 }
 
 type messageService struct {
 	messageRepo repository.MessageRepository
 	friendRepo  repository.FriendRepository
+	filter      filter.ContentFilter
+	review      ReviewQueue
+	reactions   ReactionService
 }
 
-func NewMessageService(messageRepo repository.MessageRepository, friendRepo repository.FriendRepository) MessageService {
+func NewMessageService(messageRepo repository.MessageRepository, friendRepo repository.FriendRepository, contentFilter filter.ContentFilter, review ReviewQueue, reactions ReactionService) MessageService {
 	return &messageService{
 		messageRepo: messageRepo,
 		friendRepo:  friendRepo,
+		filter:      contentFilter,
+		review:      review,
+		reactions:   reactions,
 	}
 }
 
@@ -102,11 +117,30 @@ func (s *messageService) SendMessage(conversationID, senderID, content string) (
 		return nil, errors.New("คุณถูก " + receiverName + " บล็อค")
 	}
 
-	message := models.NewMessage(conversationID, senderID, content)
+	screened, err := screen(s.filter, filter.Message, senderID, content)
+	if err != nil {
+		return nil, err
+	}
+
+	message := models.NewMessage(conversationID, senderID, screened.Text)
+	message.Hidden = screened.Verdict == filter.Hold
 	if err := s.messageRepo.CreateMessage(message); err != nil {
 		return nil, err
 	}
 
+	// Held messages aren't delivered until a moderator approves them
+	if message.Hidden {
+		if err := hold(s.review, screened, models.ReportTargetMessage, message.ID, senderID); err != nil {
+			return nil, err
+		}
+		if conversation.User1ID == senderID {
+			message.Sender = conversation.User1
+		} else {
+			message.Sender = conversation.User2
+		}
+		return message, nil
+	}
+
 	messages, err := s.messageRepo.GetMessages(conversationID, 1, 0)
 	if err != nil || len(messages) == 0 {
 		return nil, err
@@ -127,3 +161,77 @@ func (s *messageService) MarkAsRead(conversationID, userID string) error {
 	return s.messageRepo.MarkAsRead(conversationID, userID)
 }
 
+
+// findVisibleMessage returns the message if userID is in its conversation,
+// and ErrMessageNotFound otherwise. Held messages are only visible to their
+// sender.
+func (s *messageService) findVisibleMessage(messageID, userID string) (*models.Message, *models.Conversation, error) {
+	message, err := s.messageRepo.FindMessageByID(messageID)
+	if err != nil {
+		return nil, nil, ErrMessageNotFound
+	}
+	if message.Hidden && message.SenderID != userID {
+		return nil, nil, ErrMessageNotFound
+	}
+	conversation, err := s.messageRepo.FindConversationByID(message.ConversationID)
+	if err != nil {
+		return nil, nil, ErrMessageNotFound
+	}
+	if conversation.User1ID != userID && conversation.User2ID != userID {
+		return nil, nil, ErrMessageNotFound
+	}
+	return message, conversation, nil
+}
+
+func (s *messageService) ReactToMessage(messageID, userID, reaction string) error {
+	_, conversation, err := s.findVisibleMessage(messageID, userID)
+	if err != nil {
+		return err
+	}
+
+	otherID := conversation.User1ID
+	if otherID == userID {
+		otherID = conversation.User2ID
+	}
+	blocked, err := s.friendRepo.IsBlocked(otherID, userID)
+	if err != nil {
+		return err
+	}
+	blocking, err := s.friendRepo.IsBlocked(userID, otherID)
+	if err != nil {
+		return err
+	}
+	if blocked || blocking {
+		return ErrMessageNotFound
+	}
+
+	return s.reactions.React(models.ReactionTargetMessage, messageID, userID, reaction)
+}
+
+func (s *messageService) GetReactionSummary(messageID, viewerID string) (*repository.ReactionSummary, error) {
+	if _, _, err := s.findVisibleMessage(messageID, viewerID); err != nil {
+		return nil, err
+	}
+	summaries, err := s.reactions.GetSummaries(models.ReactionTargetMessage, []string{messageID}, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return summaries[messageID], nil
+}
+
+// GetReactionSummaries expects messages already loaded for viewerID, e.g.
+// by GetMessages.
+func (s *messageService) GetReactionSummaries(messages []models.Message, viewerID string) (map[string]*repository.ReactionSummary, error) {
+	ids := make([]string, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+	return s.reactions.GetSummaries(models.ReactionTargetMessage, ids, viewerID)
+}
+
+func (s *messageService) GetReactors(messageID, viewerID, reaction, cursor string, limit int) (*ReactorPage, error) {
+	if _, _, err := s.findVisibleMessage(messageID, viewerID); err != nil {
+		return nil, err
+	}
+	return s.reactions.GetReactors(models.ReactionTargetMessage, messageID, viewerID, reaction, cursor, limit)
+}
@@ -0,0 +1,548 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+const maxReportReason = 500
+
+var (
+	ErrReportNotFound          = errors.New("ไม่พบรายงาน")
+	ErrReportTargetNotFound    = errors.New("ไม่พบสิ่งที่ต้องการรายงาน")
+	ErrInvalidReportTarget     = errors.New("ประเภทของสิ่งที่รายงานไม่ถูกต้อง")
+	ErrCannotReportSelf        = errors.New("ไม่สามารถรายงานตัวเองได้")
+	ErrAlreadyReported         = errors.New("คุณรายงานสิ่งนี้ไปแล้ว")
+	ErrInvalidReason           = errors.New("กรุณาระบุเหตุผล 1-500 ตัวอักษร")
+	ErrNotModerator            = errors.New("เฉพาะผู้ดูแลเท่านั้น")
+	ErrNotAdmin                = errors.New("เฉพาะผู้ดูแลระบบเท่านั้น")
+	ErrInvalidAssignee         = errors.New("ผู้รับผิดชอบต้องเป็นผู้ดูแล")
+	ErrInvalidModerationAction = errors.New("การดำเนินการไม่ถูกต้องสำหรับรายงานนี้")
+	ErrReportClosed            = errors.New("รายงานนี้ดำเนินการเสร็จแล้ว")
+	ErrInvalidSuspension       = errors.New("ต้องระบุเวลาสิ้นสุดการระงับในอนาคต")
+	ErrInvalidRole             = errors.New("บทบาทไม่ถูกต้อง")
+	ErrUserSuspended           = errors.New("บัญชีของคุณถูกระงับการใช้งานชั่วคราว")
+)
+
+// ReportInput is a user's complaint. TargetType is one of the
+// models.ReportTarget* kinds.
+type ReportInput struct {
+	TargetType string
+	TargetID   string
+	Reason     string
+}
+
+// QueueFilter narrows the moderation queue. Empty Statuses means reports
+// that still need work; Assignee is "me", "unassigned" or empty for anyone.
+type QueueFilter struct {
+	Statuses []string
+	Assignee string
+}
+
+// ActionInput is what a moderator does about a report. SuspendedUntil is
+// required for suspensions only.
+type ActionInput struct {
+	Action         string
+	Reason         string
+	SuspendedUntil *time.Time
+}
+
+// ReportPage is one page of the queue. NextCursor is empty on the last page.
+type ReportPage struct {
+	Reports    []models.Report
+	NextCursor string
+}
+
+type ActionPage struct {
+	Actions    []models.ModerationAction
+	NextCursor string
+}
+
+// ReportTarget is the current state of what a report points at. Available is
+// false once the content has been deleted.
+type ReportTarget struct {
+	Available bool
+	Content   string // the text, or the name for users
+	Hidden    bool
+}
+
+type ReportDetail struct {
+	Report  *models.Report
+	Target  ReportTarget
+	Actions []models.ModerationAction
+}
+
+type ModerationService interface {
+	Report(reporterID string, input ReportInput) (*models.Report, error)
+	GetQueue(moderatorID string, filter QueueFilter, cursor string, limit int) (*ReportPage, error)
+	GetReport(moderatorID, reportID string) (*ReportDetail, error)
+	// Assign hands a report to assigneeID, or to the moderator when empty.
+	Assign(moderatorID, reportID, assigneeID string) (*models.Report, error)
+	// Act applies an action to the report's target, logs it and closes the
+	// report.
+	Act(moderatorID, reportID string, input ActionInput) (*models.ModerationAction, error)
+	// GetActions pages through the moderation log, optionally for one user.
+	GetActions(moderatorID, targetOwnerID, cursor string, limit int) (*ActionPage, error)
+	// GetWarnings lists the warnings and suspensions a user has received.
+	GetWarnings(userID string) ([]models.ModerationAction, error)
+	SetRole(adminID, userID, role string) (*models.User, error)
+	// SuspendedUntil returns when the user's suspension ends, or nil if they
+	// aren't suspended.
+	SuspendedUntil(userID string) (*time.Time, error)
+}
+
+type moderationService struct {
+	reportRepo  repository.ReportRepository
+	userRepo    repository.UserRepository
+	postRepo    repository.PostRepository
+	commentRepo repository.CommentRepository
+	messageRepo repository.MessageRepository
+	posts       PostService
+	comments    CommentService
+	policy      ContentPolicy
+}
+
+func NewModerationService(reportRepo repository.ReportRepository, userRepo repository.UserRepository, postRepo repository.PostRepository, commentRepo repository.CommentRepository, messageRepo repository.MessageRepository, posts PostService, comments CommentService, policy ContentPolicy) ModerationService {
+	return &moderationService{
+		reportRepo:  reportRepo,
+		userRepo:    userRepo,
+		postRepo:    postRepo,
+		commentRepo: commentRepo,
+		messageRepo: messageRepo,
+		posts:       posts,
+		comments:    comments,
+		policy:      policy,
+	}
+}
+
+func (s *moderationService) Report(reporterID string, input ReportInput) (*models.Report, error) {
+	reason, err := checkReason(input.Reason)
+	if err != nil {
+		return nil, err
+	}
+	ownerID, err := s.reportableOwner(reporterID, input.TargetType, input.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	if ownerID == reporterID {
+		return nil, ErrCannotReportSelf
+	}
+
+	_, err = s.reportRepo.FindOpen(reporterID, input.TargetType, input.TargetID)
+	if err == nil {
+		return nil, ErrAlreadyReported
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	report := &models.Report{
+		ReporterID:    reporterID,
+		TargetType:    input.TargetType,
+		TargetID:      input.TargetID,
+		TargetOwnerID: ownerID,
+		Reason:        reason,
+		Status:        models.ReportStatusOpen,
+	}
+	if err := s.reportRepo.Create(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// reportableOwner returns who is behind a target the reporter is able to
+// see. Messages can only be reported by the people in the conversation.
+func (s *moderationService) reportableOwner(reporterID, targetType, targetID string) (string, error) {
+	switch targetType {
+	case models.ReportTargetPost:
+		post, err := s.postRepo.FindByID(targetID)
+		if err != nil {
+			return "", ErrReportTargetNotFound
+		}
+		if visible, err := s.policy.CanViewPost(reporterID, post); err != nil || !visible {
+			return "", notFoundOr(err)
+		}
+		return post.AuthorID, nil
+
+	case models.ReportTargetComment:
+		comment, err := s.commentRepo.FindByID(targetID)
+		if err != nil || comment.Hidden {
+			return "", ErrReportTargetNotFound
+		}
+		post, err := s.postRepo.FindByID(comment.PostID)
+		if err != nil {
+			return "", ErrReportTargetNotFound
+		}
+		if visible, err := s.policy.CanViewPost(reporterID, post); err != nil || !visible {
+			return "", notFoundOr(err)
+		}
+		return comment.AuthorID, nil
+
+	case models.ReportTargetMessage:
+		message, err := s.messageRepo.FindMessageByID(targetID)
+		if err != nil || message.Hidden {
+			return "", ErrReportTargetNotFound
+		}
+		conversation, err := s.messageRepo.FindConversationByID(message.ConversationID)
+		if err != nil || (conversation.User1ID != reporterID && conversation.User2ID != reporterID) {
+			return "", ErrReportTargetNotFound
+		}
+		return message.SenderID, nil
+
+	case models.ReportTargetUser:
+		user, err := s.userRepo.FindByID(targetID)
+		if err != nil || user.IsGuest {
+			return "", ErrReportTargetNotFound
+		}
+		return user.ID, nil
+	}
+	return "", ErrInvalidReportTarget
+}
+
+func notFoundOr(err error) error {
+	if err != nil {
+		return err
+	}
+	return ErrReportTargetNotFound
+}
+
+func checkReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" || utf8.RuneCountInString(reason) > maxReportReason {
+		return "", ErrInvalidReason
+	}
+	return reason, nil
+}
+
+func (s *moderationService) GetQueue(moderatorID string, filter QueueFilter, cursor string, limit int) (*ReportPage, error) {
+	if _, err := s.findModerator(moderatorID); err != nil {
+		return nil, err
+	}
+	after, err := repository.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	query := repository.ReportPageQuery{
+		Statuses:       filter.Statuses,
+		ExcludeOwnerID: moderatorID,
+		After:          after,
+		Limit:          limit + 1,
+	}
+	if len(query.Statuses) == 0 {
+		query.Statuses = []string{models.ReportStatusOpen, models.ReportStatusInReview}
+	}
+	switch filter.Assignee {
+	case "me":
+		query.AssigneeID = moderatorID
+	case "unassigned":
+		query.Unassigned = true
+	}
+
+	reports, err := s.reportRepo.FindPage(query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ReportPage{Reports: reports}
+	if len(reports) > limit {
+		page.Reports = reports[:limit]
+		last := page.Reports[limit-1]
+		page.NextCursor = repository.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
+
+func (s *moderationService) GetReport(moderatorID, reportID string) (*ReportDetail, error) {
+	if _, err := s.findModerator(moderatorID); err != nil {
+		return nil, err
+	}
+	report, err := s.findReport(moderatorID, reportID)
+	if err != nil {
+		return nil, err
+	}
+
+	actions, err := s.reportRepo.FindActions(repository.ActionPageQuery{ReportID: report.ID})
+	if err != nil {
+		return nil, err
+	}
+	return &ReportDetail{
+		Report:  report,
+		Target:  s.loadTarget(report.TargetType, report.TargetID),
+		Actions: actions,
+	}, nil
+}
+
+// loadTarget looks up what a report points at, whoever may normally see it.
+func (s *moderationService) loadTarget(targetType, targetID string) ReportTarget {
+	switch targetType {
+	case models.ReportTargetPost:
+		if post, err := s.postRepo.FindByID(targetID); err == nil {
+			return ReportTarget{Available: true, Content: post.Content, Hidden: post.Hidden}
+		}
+	case models.ReportTargetComment:
+		if comment, err := s.commentRepo.FindByID(targetID); err == nil {
+			return ReportTarget{Available: true, Content: comment.Content, Hidden: comment.Hidden}
+		}
+	case models.ReportTargetMessage:
+		if message, err := s.messageRepo.FindMessageByID(targetID); err == nil {
+			return ReportTarget{Available: true, Content: message.Content, Hidden: message.Hidden}
+		}
+	case models.ReportTargetUser:
+		if user, err := s.userRepo.FindByID(targetID); err == nil {
+			return ReportTarget{Available: true, Content: user.Name}
+		}
+	}
+	return ReportTarget{}
+}
+
+func (s *moderationService) Assign(moderatorID, reportID, assigneeID string) (*models.Report, error) {
+	if _, err := s.findModerator(moderatorID); err != nil {
+		return nil, err
+	}
+	report, err := s.findReport(moderatorID, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if isClosed(report) {
+		return nil, ErrReportClosed
+	}
+
+	if assigneeID == "" {
+		assigneeID = moderatorID
+	}
+	assignee, err := s.userRepo.FindByID(assigneeID)
+	if err != nil || !assignee.IsModerator() || assignee.ID == report.TargetOwnerID {
+		return nil, ErrInvalidAssignee
+	}
+
+	report.AssigneeID = &assignee.ID
+	report.Status = models.ReportStatusInReview
+	if err := s.reportRepo.Update(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *moderationService) Act(moderatorID, reportID string, input ActionInput) (*models.ModerationAction, error) {
+	moderator, err := s.findModerator(moderatorID)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.findReport(moderatorID, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if isClosed(report) {
+		return nil, ErrReportClosed
+	}
+	reason, err := checkReason(input.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	action := &models.ModerationAction{
+		ReportID:      &report.ID,
+		ModeratorID:   moderatorID,
+		Action:        input.Action,
+		TargetType:    report.TargetType,
+		TargetID:      report.TargetID,
+		TargetOwnerID: report.TargetOwnerID,
+		Content:       s.loadTarget(report.TargetType, report.TargetID).Content,
+		Reason:        reason,
+	}
+
+	switch input.Action {
+	case models.ModerationHide, models.ModerationDelete:
+		err = s.takeDown(report.TargetType, report.TargetID, input.Action == models.ModerationDelete)
+	case models.ModerationApprove:
+		err = s.approve(report.TargetType, report.TargetID)
+	case models.ModerationWarn:
+		_, err = s.punishable(moderator, report.TargetOwnerID)
+	case models.ModerationSuspend:
+		if input.SuspendedUntil == nil || !input.SuspendedUntil.After(time.Now()) {
+			return nil, ErrInvalidSuspension
+		}
+		action.SuspendedUntil = input.SuspendedUntil
+		err = s.suspend(moderator, report.TargetOwnerID, *input.SuspendedUntil)
+	case models.ModerationDismiss:
+	default:
+		return nil, ErrInvalidModerationAction
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.reportRepo.CreateAction(action); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	report.Status = models.ReportStatusResolved
+	if input.Action == models.ModerationDismiss {
+		report.Status = models.ReportStatusDismissed
+	}
+	if report.AssigneeID == nil {
+		report.AssigneeID = &moderator.ID
+	}
+	report.ResolvedAt = &now
+	if err := s.reportRepo.Update(report); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+// takeDown hides or deletes reported content. Users can only be warned or
+// suspended.
+func (s *moderationService) takeDown(targetType, targetID string, remove bool) error {
+	switch targetType {
+	case models.ReportTargetPost:
+		if remove {
+			return s.posts.RemovePost(targetID)
+		}
+		return s.posts.HidePost(targetID)
+	case models.ReportTargetComment:
+		if remove {
+			return s.comments.RemoveComment(targetID)
+		}
+		return s.comments.HideComment(targetID)
+	case models.ReportTargetMessage:
+		if remove {
+			return s.messageRepo.DeleteMessage(targetID)
+		}
+		return s.messageRepo.SetMessageHidden(targetID, true)
+	}
+	return ErrInvalidModerationAction
+}
+
+// approve puts hidden content back, typically content the content filter
+// held for review.
+func (s *moderationService) approve(targetType, targetID string) error {
+	switch targetType {
+	case models.ReportTargetPost:
+		return s.posts.UnhidePost(targetID)
+	case models.ReportTargetComment:
+		return s.comments.UnhideComment(targetID)
+	case models.ReportTargetMessage:
+		return s.messageRepo.SetMessageHidden(targetID, false)
+	}
+	return ErrInvalidModerationAction
+}
+
+// punishable loads a user the moderator may warn or suspend. Moderators
+// can't punish each other; only admins can.
+func (s *moderationService) punishable(moderator *models.User, userID string) (*models.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.IsModerator() && moderator.Role != models.UserRoleAdmin {
+		return nil, ErrNotAdmin
+	}
+	return user, nil
+}
+
+func (s *moderationService) suspend(moderator *models.User, userID string, until time.Time) error {
+	user, err := s.punishable(moderator, userID)
+	if err != nil {
+		return err
+	}
+	user.SuspendedUntil = &until
+	return s.userRepo.Update(user)
+}
+
+func (s *moderationService) GetActions(moderatorID, targetOwnerID, cursor string, limit int) (*ActionPage, error) {
+	if _, err := s.findModerator(moderatorID); err != nil {
+		return nil, err
+	}
+	after, err := repository.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	actions, err := s.reportRepo.FindActions(repository.ActionPageQuery{
+		TargetOwnerID: targetOwnerID,
+		After:         after,
+		Limit:         limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ActionPage{Actions: actions}
+	if len(actions) > limit {
+		page.Actions = actions[:limit]
+		last := page.Actions[limit-1]
+		page.NextCursor = repository.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
+
+func (s *moderationService) GetWarnings(userID string) ([]models.ModerationAction, error) {
+	return s.reportRepo.FindActions(repository.ActionPageQuery{
+		TargetOwnerID: userID,
+		Actions:       []string{models.ModerationWarn, models.ModerationSuspend},
+	})
+}
+
+func (s *moderationService) SetRole(adminID, userID, role string) (*models.User, error) {
+	admin, err := s.userRepo.FindByID(adminID)
+	if err != nil || admin.Role != models.UserRoleAdmin {
+		return nil, ErrNotAdmin
+	}
+	if role != models.UserRoleUser && role != models.UserRoleModerator && role != models.UserRoleAdmin {
+		return nil, ErrInvalidRole
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || user.IsGuest {
+		return nil, ErrUserNotFound
+	}
+
+	user.Role = role
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	user.Password = ""
+	return user, nil
+}
+
+func (s *moderationService) SuspendedUntil(userID string) (*time.Time, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if !user.IsSuspended(time.Now()) {
+		return nil, nil
+	}
+	return user.SuspendedUntil, nil
+}
+
+func (s *moderationService) findModerator(userID string) (*models.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || !user.IsModerator() {
+		return nil, ErrNotModerator
+	}
+	return user, nil
+}
+
+// findReport hides reports about the moderator themselves, so nobody
+// handles, or learns who filed, a report against them.
+func (s *moderationService) findReport(moderatorID, reportID string) (*models.Report, error) {
+	report, err := s.reportRepo.FindByID(reportID)
+	if err != nil || report.TargetOwnerID == moderatorID {
+		return nil, ErrReportNotFound
+	}
+	return report, nil
+}
+
+func isClosed(report *models.Report) bool {
+	return report.Status == models.ReportStatusResolved || report.Status == models.ReportStatusDismissed
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+)
+
+const (
+	PassageDifficulty = "passage"
+	// Runs faster than this are stored but flagged for review, in every
+	// game mode
+	maxPlausibleWPM = 250
+	minPassageRun   = time.Second
+	// Passage leaderboards only rank runs at least this accurate, which
+	// also means they typed at least this much of the passage
+	minRankedAccuracy = 90
+)
+
+var ErrPassageNotFound = errors.New("ไม่พบข้อความ")
+
+// PassageImport is one row of an import file.
+type PassageImport struct {
+	Text     string `json:"text"`
+	Source   string `json:"source"`
+	Language string `json:"language"`
+}
+
+type PassageImportResult struct {
+	Imported int
+	Skipped  int
+}
+
+// PassageRun is what a client sends after typing a passage.
+type PassageRun struct {
+	TypedText string
+	Elapsed   time.Duration
+}
+
+type PassageService interface {
+	Import(r io.Reader, format string) (*PassageImportResult, error)
+	GetRandomPassage(language, lengthBucket string) (*models.Passage, error)
+	GetPassage(passageID string) (*models.Passage, error)
+	SubmitRun(userID, passageID string, run PassageRun) (*models.GameScore, error)
+	GetPassageLeaderboard(passageID string, limit int) ([]models.GameScore, error)
+}
+
+type passageService struct {
+	passageRepo repository.PassageRepository
+	scoreRepo   repository.GameScoreRepository
+	userRepo    repository.UserRepository
+	listener    ScoreListener
+}
+
+func NewPassageService(passageRepo repository.PassageRepository, scoreRepo repository.GameScoreRepository, userRepo repository.UserRepository, listener ScoreListener) PassageService {
+	return &passageService{
+		passageRepo: passageRepo,
+		scoreRepo:   scoreRepo,
+		userRepo:    userRepo,
+		listener:    listener,
+	}
+}
+
+// Import reads passages from a JSON array or a CSV file with a header row
+// naming the text, source and language columns. Passages already in the
+// corpus are skipped, so the same file can be imported twice.
+func (s *passageService) Import(r io.Reader, format string) (*PassageImportResult, error) {
+	var rows []PassageImport
+	var err error
+
+	switch strings.ToLower(format) {
+	case "json":
+		err = json.NewDecoder(r).Decode(&rows)
+	case "csv":
+		rows, err = readPassageCSV(r)
+	default:
+		return nil, errors.New("format must be json or csv")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PassageImportResult{}
+	for _, row := range rows {
+		text := strings.TrimSpace(row.Text)
+		if text == "" {
+			result.Skipped++
+			continue
+		}
+		language := strings.ToLower(strings.TrimSpace(row.Language))
+		if language == "" {
+			language = "en"
+		}
+
+		passage := models.NewPassage(text, strings.TrimSpace(row.Source), language)
+		exists, err := s.passageRepo.ExistsByHash(passage.ContentHash)
+		if err != nil {
+			return result, err
+		}
+		if exists {
+			result.Skipped++
+			continue
+		}
+		if err := s.passageRepo.Create(passage); err != nil {
+			return result, err
+		}
+		result.Imported++
+	}
+	return result, nil
+}
+
+func readPassageCSV(r io.Reader) ([]PassageImport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	textCol, ok := columns["text"]
+	if !ok {
+		return nil, errors.New("csv header must contain a text column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var rows []PassageImport
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if textCol >= len(record) {
+			continue
+		}
+		rows = append(rows, PassageImport{
+			Text:     record[textCol],
+			Source:   field(record, "source"),
+			Language: field(record, "language"),
+		})
+	}
+	return rows, nil
+}
+
+func (s *passageService) GetRandomPassage(language, lengthBucket string) (*models.Passage, error) {
+	passage, err := s.passageRepo.FindRandom(language, lengthBucket)
+	if err != nil {
+		return nil, ErrPassageNotFound
+	}
+	return passage, nil
+}
+
+func (s *passageService) GetPassage(passageID string) (*models.Passage, error) {
+	passage, err := s.passageRepo.FindByID(passageID)
+	if err != nil {
+		return nil, ErrPassageNotFound
+	}
+	return passage, nil
+}
+
+// SubmitRun scores a passage run on the server from the typed text, so
+// clients can't report their own WPM or accuracy.
+func (s *passageService) SubmitRun(userID, passageID string, run PassageRun) (*models.GameScore, error) {
+	passage, err := s.passageRepo.FindByID(passageID)
+	if err != nil {
+		return nil, ErrPassageNotFound
+	}
+	if run.Elapsed < minPassageRun {
+		return nil, errors.New("elapsed time is too short")
+	}
+
+	player, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	correct, accuracy := scorePassage(passage.Text, run.TypedText)
+	wpm := float64(correct) / 5 / run.Elapsed.Minutes()
+
+	gameScore := models.NewGameScore(userID, correct, correct/5, PassageDifficulty)
+	gameScore.PassageID = &passage.ID
+	gameScore.WPM = wpm
+	gameScore.Accuracy = accuracy
+	gameScore.IsGuest = player.IsGuest
+	gameScore.Flagged = wpm > maxPlausibleWPM
+
+	if err := s.scoreRepo.Create(gameScore); err != nil {
+		return nil, err
+	}
+	if s.listener != nil && !gameScore.IsGuest {
+		s.listener.ScoreSaved(gameScore)
+	}
+	return gameScore, nil
+}
+
+// scorePassage compares the typed text to the passage character by character.
+// Accuracy is measured against the longer of the two so that both missing and
+// extra characters cost points.
+func scorePassage(passage, typed string) (correct int, accuracy float64) {
+	want := []rune(passage)
+	got := []rune(typed)
+
+	for i := 0; i < len(want) && i < len(got); i++ {
+		if want[i] == got[i] {
+			correct++
+		}
+	}
+
+	total := len(want)
+	if len(got) > total {
+		total = len(got)
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	return correct, float64(correct) * 100 / float64(total)
+}
+
+func (s *passageService) GetPassageLeaderboard(passageID string, limit int) ([]models.GameScore, error) {
+	if _, err := s.passageRepo.FindByID(passageID); err != nil {
+		return nil, ErrPassageNotFound
+	}
+	return s.scoreRepo.GetPassageLeaderboard(passageID, minRankedAccuracy, limit)
+}
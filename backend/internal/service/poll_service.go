@@ -0,0 +1,221 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 6
+	maxPollOptionLength = 100
+)
+
+var (
+	ErrPollNotFound         = errors.New("ไม่พบโพล")
+	ErrPollClosed           = errors.New("โพลนี้ปิดแล้ว")
+	ErrAlreadyVoted         = errors.New("คุณโหวตโพลนี้ไปแล้ว")
+	ErrInvalidPollVote      = errors.New("ตัวเลือกที่โหวตไม่ถูกต้อง")
+	ErrInvalidPollOptions   = errors.New("โพลต้องมี 2-6 ตัวเลือกที่ไม่ซ้ำกัน และแต่ละตัวเลือกยาว 1-100 ตัวอักษร")
+	ErrInvalidPollCloseTime = errors.New("เวลาปิดโพลต้องอยู่หลังเวลาเผยแพร่โพสต์")
+)
+
+// PollInput attaches a poll to a new post. A nil ClosesAt keeps the poll open
+// for as long as the post exists.
+type PollInput struct {
+	Options               []string
+	MultipleChoice        bool
+	HideResultsUntilVoted bool
+	ClosesAt              *time.Time
+}
+
+// PollView is a poll as one viewer sees it. When ResultsVisible is false the
+// tallies must not be shown.
+type PollView struct {
+	Poll           *models.Poll
+	Closed         bool
+	UserVotes      []string // option IDs the viewer chose; empty until they vote
+	ResultsVisible bool
+}
+
+type PollService interface {
+	// Vote casts the user's vote on a post's poll. Single-choice polls take
+	// exactly one option; votes can't be changed afterwards.
+	Vote(postID, userID string, optionIDs []string) (*PollView, error)
+	// GetPolls returns the polls of posts, keyed by post ID, as viewerID
+	// sees them. Posts without a poll are left out.
+	GetPolls(viewerID string, posts []models.Post) (map[string]*PollView, error)
+	CloseDue(now time.Time) (int, error)
+}
+
+type pollService struct {
+	pollRepo repository.PollRepository
+	postRepo repository.PostRepository
+	policy   ContentPolicy
+}
+
+func NewPollService(pollRepo repository.PollRepository, postRepo repository.PostRepository, policy ContentPolicy) PollService {
+	return &pollService{
+		pollRepo: pollRepo,
+		postRepo: postRepo,
+		policy:   policy,
+	}
+}
+
+// newPoll checks a poll for a post that goes live at opensAt and builds it
+// with fresh IDs, ready to be created together with the post.
+func newPoll(input *PollInput, opensAt time.Time) (*models.Poll, error) {
+	if len(input.Options) < minPollOptions || len(input.Options) > maxPollOptions {
+		return nil, ErrInvalidPollOptions
+	}
+	if input.ClosesAt != nil && !input.ClosesAt.After(opensAt) {
+		return nil, ErrInvalidPollCloseTime
+	}
+
+	poll := &models.Poll{
+		ID:                    uuid.New().String(),
+		MultipleChoice:        input.MultipleChoice,
+		HideResultsUntilVoted: input.HideResultsUntilVoted,
+		ClosesAt:              input.ClosesAt,
+	}
+	seen := make(map[string]bool, len(input.Options))
+	for i, text := range input.Options {
+		text = strings.TrimSpace(text)
+		if text == "" || utf8.RuneCountInString(text) > maxPollOptionLength || seen[text] {
+			return nil, ErrInvalidPollOptions
+		}
+		seen[text] = true
+		poll.Options = append(poll.Options, models.PollOption{
+			ID:       uuid.New().String(),
+			PollID:   poll.ID,
+			Position: i,
+			Text:     text,
+		})
+	}
+	return poll, nil
+}
+
+func (s *pollService) Vote(postID, userID string, optionIDs []string) (*PollView, error) {
+	post, err := s.postRepo.FindByID(postID)
+	if err != nil {
+		return nil, ErrPostNotFound
+	}
+	visible, err := s.policy.CanViewPost(userID, post)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, ErrPostNotFound
+	}
+
+	poll, err := s.pollRepo.FindByPostID(postID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPollNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if poll.IsClosed(time.Now()) {
+		return nil, ErrPollClosed
+	}
+
+	chosen, err := checkVote(poll, optionIDs)
+	if err != nil {
+		return nil, err
+	}
+	voted, err := s.pollRepo.Vote(poll.ID, userID, chosen)
+	if err != nil {
+		return nil, err
+	}
+	if !voted {
+		return nil, ErrAlreadyVoted
+	}
+
+	views, err := s.GetPolls(userID, []models.Post{*post})
+	if err != nil {
+		return nil, err
+	}
+	return views[postID], nil
+}
+
+// checkVote drops repeated option IDs and makes sure the rest belong to the
+// poll and fit its choice mode.
+func checkVote(poll *models.Poll, optionIDs []string) ([]string, error) {
+	options := make(map[string]bool, len(poll.Options))
+	for _, option := range poll.Options {
+		options[option.ID] = true
+	}
+
+	chosen := make([]string, 0, len(optionIDs))
+	seen := make(map[string]bool, len(optionIDs))
+	for _, id := range optionIDs {
+		if !options[id] {
+			return nil, ErrInvalidPollVote
+		}
+		if !seen[id] {
+			seen[id] = true
+			chosen = append(chosen, id)
+		}
+	}
+	if len(chosen) == 0 || (!poll.MultipleChoice && len(chosen) > 1) {
+		return nil, ErrInvalidPollVote
+	}
+	return chosen, nil
+}
+
+func (s *pollService) GetPolls(viewerID string, posts []models.Post) (map[string]*PollView, error) {
+	authors := make(map[string]string, len(posts))
+	postIDs := make([]string, 0, len(posts))
+	for _, post := range posts {
+		authors[post.ID] = post.AuthorID
+		postIDs = append(postIDs, post.ID)
+	}
+
+	polls, err := s.pollRepo.FindByPostIDs(postIDs)
+	if err != nil {
+		return nil, err
+	}
+	pollIDs := make([]string, 0, len(polls))
+	for _, poll := range polls {
+		pollIDs = append(pollIDs, poll.ID)
+	}
+	votes, err := s.pollRepo.FindUserVotes(viewerID, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+	userVotes := make(map[string][]string, len(votes))
+	for _, vote := range votes {
+		userVotes[vote.PollID] = append(userVotes[vote.PollID], vote.OptionID)
+	}
+
+	now := time.Now()
+	views := make(map[string]*PollView, len(polls))
+	for i := range polls {
+		poll := &polls[i]
+		view := &PollView{
+			Poll:      poll,
+			Closed:    poll.IsClosed(now),
+			UserVotes: userVotes[poll.ID],
+		}
+		if view.UserVotes == nil {
+			view.UserVotes = []string{}
+		}
+		view.ResultsVisible = !poll.HideResultsUntilVoted || view.Closed ||
+			len(view.UserVotes) > 0 || authors[poll.PostID] == viewerID
+		views[poll.PostID] = view
+	}
+	return views, nil
+}
+
+// CloseDue marks the polls whose closing time has passed as closed and
+// returns how many it closed.
+func (s *pollService) CloseDue(now time.Time) (int, error) {
+	closed, err := s.pollRepo.CloseDue(now)
+	return int(closed), err
+}
@@ -1,72 +1,455 @@
 package service
 
 import (
+	"errors"
+	"strings"
+	"time"
+
+	"typinggame-api/internal/filter"
 	"typinggame-api/internal/models"
 	"typinggame-api/internal/repository"
+	"typinggame-api/internal/search"
+	"typinggame-api/internal/textseg"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrPostNotFound is returned both for missing posts and for posts the caller
+// isn't allowed to see, so restricted posts can't be probed for.
+var ErrPostNotFound = errors.New("ไม่พบโพสต์")
+
+var ErrEmptyPost = errors.New("โพสต์ต้องมีข้อความหรือไฟล์แนบ")
+
+var ErrHistoryNotFound = errors.New("ไม่พบประวัติการแก้ไข")
+
+// ErrStaleVersion is returned for an edit of a post or comment made against
+// a version that has since been replaced by another edit.
+var ErrStaleVersion = errors.New("เนื้อหาถูกแก้ไขไปแล้ว กรุณาโหลดใหม่ก่อนแก้ไข")
+
+// publishBatchSize caps how many scheduled posts one PublishDue call handles.
+const publishBatchSize = 100
+
+var (
+	ErrInvalidPostStatus = errors.New("สถานะโพสต์ไม่ถูกต้อง")
+	ErrInvalidPublishAt  = errors.New("โพสต์ที่ตั้งเวลาต้องมีเวลาเผยแพร่ในอนาคต")
+	ErrAlreadyPublished  = errors.New("โพสต์นี้เผยแพร่แล้ว ไม่สามารถกลับเป็นฉบับร่างได้")
 )
 
+var (
+	ErrInvalidSort   = errors.New("รูปแบบการเรียงลำดับไม่ถูกต้อง")
+	ErrInvalidWindow = errors.New("ช่วงเวลาไม่ถูกต้อง")
+)
+
+// rankingWindows are the time spans top and hot listings can cover.
+var rankingWindows = map[string]time.Duration{
+	"day":  24 * time.Hour,
+	"week": 7 * 24 * time.Hour,
+}
+
+// maxRankingWindow is the longest of rankingWindows; hot scores of older
+// posts are no longer refreshed.
+const maxRankingWindow = 7 * 24 * time.Hour
+
+var (
+	ErrAlreadyReposted = errors.New("คุณแชร์โพสต์นี้ไปแล้ว")
+	ErrCannotRepost    = errors.New("โพสต์นี้ไม่สามารถแชร์ได้")
+)
+
+// CreatePostInput publishes right away unless Status is draft, or scheduled
+// with a PublishAt in the future. Poll is optional.
+type CreatePostInput struct {
+	Content     string
+	Visibility  string
+	Status      string
+	PublishAt   *time.Time
+	Attachments []AttachmentUpload
+	Poll        *PollInput
+}
+
+// UpdatePostInput replaces the content and visibility, appends Attachments
+// and drops the attachments listed in RemoveAttachmentIDs. A non-empty Status
+// moves a draft or scheduled post to another state; published posts stay
+// published.
+type UpdatePostInput struct {
+	Content             string
+	Visibility          string
+	Status              string
+	PublishAt           *time.Time
+	Attachments         []AttachmentUpload
+	RemoveAttachmentIDs []string
+	// Version is the version the edit was made against; zero skips the
+	// check. A post edited since then gives ErrStaleVersion.
+	Version int
+}
+
+// RepostInput shares a post. An empty Content makes a plain repost, anything
+// else a quote post.
+type RepostInput struct {
+	Content    string
+	Visibility string
+}
+
+// PostPage is one page of a feed. NextCursor is empty on the last page.
+type PostPage struct {
+	Posts      []models.Post
+	NextCursor string
+}
+
 type PostService interface {
-	CreatePost(content, authorID string) (*models.Post, error)
-	GetAllPosts() ([]models.Post, error)
-	GetPostByID(postID string) (*models.Post, error)
-	GetPostsByUserID(userID string) ([]models.Post, error)
-	UpdatePost(postID, userID, content string) (*models.Post, error)
-	GetEditHistory(postID string) ([]models.EditHistory, error)
+	CreatePost(authorID string, input CreatePostInput) (*models.Post, error)
+	GetAllPosts(viewerID, cursor string, limit int) (*PostPage, error)
+	GetRankedPosts(viewerID, sort, window, cursor string, limit int) (*PostPage, error)
+	RefreshHotScores(now time.Time) (int, error)
+	GetHomeFeed(viewerID, cursor string, limit int) (*PostPage, error)
+	GetPostByID(postID, viewerID string) (*models.Post, error)
+	GetPostsByUserID(userID, viewerID, cursor string, limit int) (*PostPage, error)
+	GetHashtagFeed(tag, viewerID, cursor string, limit int) (*PostPage, error)
+	GetDrafts(userID, cursor string, limit int) (*PostPage, error)
+	PublishDue(now time.Time) (int, error)
+	UpdatePost(postID, userID string, input UpdatePostInput) (*models.Post, error)
+	// RevertPost restores the content from before an edit in the post's
+	// history.
+	RevertPost(postID, historyID, userID string) (*models.Post, error)
+	// GetEditHistory follows the post's own visibility and block rules and
+	// the author's edit history setting.
+	GetEditHistory(postID, viewerID string) (*EditHistory, error)
 	DeletePost(postID, userID string) error
+	// RestorePost brings one of the user's posts back from the trash.
+	RestorePost(postID, userID string) (*models.Post, error)
+	// HidePost and RemovePost are moderator actions and skip the author check.
+	HidePost(postID string) error
+	UnhidePost(postID string) error
+	RemovePost(postID string) error
+	Repost(postID, userID string, input RepostInput) (*models.Post, error)
+	GetRepostOriginals(posts []models.Post, viewerID string) (map[string]*models.Post, error)
 	ReactToPost(postID, userID, reaction string) error
-	GetUserReaction(postID, userID string) (string, error)
-	GetReactionsCount(postID string) (map[string]int64, error)
+	GetReactors(postID, viewerID, reaction, cursor string, limit int) (*ReactorPage, error)
+	GetReactionSummary(postID, viewerID string) (*repository.ReactionSummary, error)
+	GetReactionSummaries(posts []models.Post, viewerID string) (map[string]*repository.ReactionSummary, error)
 }
 
 type postService struct {
-	postRepo      repository.PostRepository
-	userRepo      repository.UserRepository
-	historyRepo   repository.EditHistoryRepository
+	postRepo    repository.PostRepository
+	userRepo    repository.UserRepository
+	historyRepo repository.EditHistoryRepository
+	policy      ContentPolicy
+	attachments AttachmentService
+	entities    EntityService
+	search      SearchService
+	filter      filter.ContentFilter
+	review      ReviewQueue
+	reactions   ReactionService
+	logger      *zap.Logger
 }
 
-func NewPostService(postRepo repository.PostRepository, userRepo repository.UserRepository, historyRepo repository.EditHistoryRepository) PostService {
+func NewPostService(postRepo repository.PostRepository, userRepo repository.UserRepository, historyRepo repository.EditHistoryRepository, policy ContentPolicy, attachments AttachmentService, entities EntityService, search SearchService, contentFilter filter.ContentFilter, review ReviewQueue, reactions ReactionService, logger *zap.Logger) PostService {
 	return &postService{
 		postRepo:    postRepo,
 		userRepo:    userRepo,
 		historyRepo: historyRepo,
+		policy:      policy,
+		attachments: attachments,
+		entities:    entities,
+		search:      search,
+		filter:      contentFilter,
+		review:      review,
+		reactions:   reactions,
+		logger:      logger,
 	}
 }
 
-func (s *postService) CreatePost(content, authorID string) (*models.Post, error) {
+func (s *postService) CreatePost(authorID string, input CreatePostInput) (*models.Post, error) {
+	if strings.TrimSpace(input.Content) == "" && len(input.Attachments) == 0 && input.Poll == nil {
+		return nil, ErrEmptyPost
+	}
+	if len(input.Attachments) > s.attachments.Limits().MaxPerPost {
+		return nil, ErrTooManyAttachments
+	}
+	screened, err := screen(s.filter, filter.Post, authorID, input.Content)
+	if err != nil {
+		return nil, err
+	}
+
 	// Verify user exists
-	_, err := s.userRepo.FindByID(authorID)
+	_, err = s.userRepo.FindByID(authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	visibility := input.Visibility
+	if visibility == "" {
+		visibility = models.PostVisibilityPublic
+	}
+
+	now := time.Now()
+	status, publishAt, err := resolveStatus(input.Status, input.PublishAt, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var poll *models.Poll
+	if input.Poll != nil {
+		opensAt := now
+		if publishAt != nil {
+			opensAt = *publishAt
+		}
+		if poll, err = newPoll(input.Poll, opensAt); err != nil {
+			return nil, err
+		}
+	}
+
+	entities, err := s.entities.Extract(screened.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := s.attachments.Store(input.Attachments, 0)
 	if err != nil {
 		return nil, err
 	}
 
 	post := &models.Post{
-		Content:  content,
-		AuthorID: authorID,
-		Likes:    0,
-		Comments:  0,
+		Content:     screened.Text,
+		AuthorID:    authorID,
+		Visibility:  visibility,
+		Status:      status,
+		PublishAt:   publishAt,
+		Likes:       0,
+		Comments:    0,
+		Attachments: attachments,
+		Poll:        poll,
+		Entities:    entities,
+		Hidden:      screened.Verdict == filter.Hold,
 	}
 
 	if err := s.postRepo.Create(post); err != nil {
+		s.attachments.Remove(attachments)
+		return nil, err
+	}
+	if err := hold(s.review, screened, models.ReportTargetPost, post.ID, authorID); err != nil {
 		return nil, err
 	}
 
+	if post.IsPublished() {
+		s.indexPublished(post)
+	}
+
 	// Load author
 	return s.postRepo.FindByID(post.ID)
 }
 
-func (s *postService) GetAllPosts() ([]models.Post, error) {
-	return s.postRepo.FindAll()
+func (s *postService) GetAllPosts(viewerID, cursor string, limit int) (*PostPage, error) {
+	return s.findPage(repository.PostPageQuery{ViewerID: viewerID, Limit: limit}, cursor)
 }
 
-func (s *postService) GetPostByID(postID string) (*models.Post, error) {
-	return s.postRepo.FindByID(postID)
+// GetRankedPosts lists visible posts from the last day or week (window,
+// default day), ranked by sort: top for the most engagement, hot for the
+// precomputed time-decayed score. Rankings shift as people interact, so the
+// cursor is an offset into the ranking.
+func (s *postService) GetRankedPosts(viewerID, sort, window, cursor string, limit int) (*PostPage, error) {
+	if sort != repository.PostSortTop && sort != repository.PostSortHot {
+		return nil, ErrInvalidSort
+	}
+	if window == "" {
+		window = "day"
+	}
+	span, ok := rankingWindows[window]
+	if !ok {
+		return nil, ErrInvalidWindow
+	}
+	offset, err := decodeOffsetCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := s.policy.HiddenUserIDs(viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	since := time.Now().Add(-span)
+	posts, err := s.postRepo.FindPage(repository.PostPageQuery{
+		ViewerID:         viewerID,
+		ExcludeAuthorIDs: hidden,
+		Since:            &since,
+		Sort:             sort,
+		Offset:           offset,
+		Limit:            limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &PostPage{Posts: posts}
+	if len(posts) > limit {
+		page.Posts = posts[:limit]
+		page.NextCursor = encodeOffsetCursor(offset + limit)
+	}
+	return page, nil
+}
+
+// RefreshHotScores recomputes the hot scores of posts young enough to
+// appear in a hot listing and returns how many it updated.
+func (s *postService) RefreshHotScores(now time.Time) (int, error) {
+	updated, err := s.postRepo.RefreshHotScores(now.Add(-maxRankingWindow), now)
+	return int(updated), err
+}
+
+// GetHomeFeed lists posts by the viewer and their accepted friends.
+func (s *postService) GetHomeFeed(viewerID, cursor string, limit int) (*PostPage, error) {
+	return s.findPage(repository.PostPageQuery{ViewerID: viewerID, FriendsOf: viewerID, Limit: limit}, cursor)
+}
+
+func (s *postService) GetPostByID(postID, viewerID string) (*models.Post, error) {
+	return s.findVisiblePost(postID, viewerID)
+}
+
+func (s *postService) GetPostsByUserID(userID, viewerID, cursor string, limit int) (*PostPage, error) {
+	visible, err := s.policy.CanViewUser(viewerID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, ErrUserNotFound
+	}
+	return s.findPage(repository.PostPageQuery{ViewerID: viewerID, AuthorID: userID, Limit: limit}, cursor)
+}
+
+// GetHashtagFeed lists visible posts tagged with tag, newest first.
+func (s *postService) GetHashtagFeed(tag, viewerID, cursor string, limit int) (*PostPage, error) {
+	return s.findPage(repository.PostPageQuery{ViewerID: viewerID, Hashtag: textseg.Normalize(tag), Limit: limit}, cursor)
+}
+
+// GetDrafts lists the user's own drafts and scheduled posts.
+func (s *postService) GetDrafts(userID, cursor string, limit int) (*PostPage, error) {
+	return s.findPage(repository.PostPageQuery{
+		ViewerID: userID,
+		AuthorID: userID,
+		Statuses: []string{models.PostStatusDraft, models.PostStatusScheduled},
+		Limit:    limit,
+	}, cursor)
+}
+
+// PublishDue publishes the scheduled posts whose time has come and returns
+// how many this call published. It is safe to run on several servers at
+// once: each post is claimed with a conditional update, and only the server
+// that wins the claim indexes it.
+func (s *postService) PublishDue(now time.Time) (int, error) {
+	due, err := s.postRepo.FindDueScheduled(now, publishBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for i := range due {
+		claimed, err := s.postRepo.ClaimScheduled(due[i].ID, now)
+		if err != nil {
+			return published, err
+		}
+		if !claimed {
+			continue
+		}
+		published++
+
+		post := &due[i]
+		post.Status = models.PostStatusPublished
+		post.CreatedAt = now
+		s.indexPublished(post)
+	}
+	return published, nil
+}
+
+// resolveStatus checks a requested publishing state. Only scheduled posts
+// keep a publish time, and it has to be in the future.
+func resolveStatus(status string, publishAt *time.Time, now time.Time) (string, *time.Time, error) {
+	switch status {
+	case "", models.PostStatusPublished:
+		return models.PostStatusPublished, nil, nil
+	case models.PostStatusDraft:
+		return models.PostStatusDraft, nil, nil
+	case models.PostStatusScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return "", nil, ErrInvalidPublishAt
+		}
+		return models.PostStatusScheduled, publishAt, nil
+	}
+	return "", nil, ErrInvalidPostStatus
+}
+
+// indexPublished adds a live post to the hashtag, mention and search
+// indexes. Drafts and scheduled posts stay out of them until they go live,
+// and posts hidden by a moderator stay out for good.
+func (s *postService) indexPublished(post *models.Post) {
+	if post.Hidden {
+		return
+	}
+	if err := s.entities.Index(models.EntitySourcePost, post.ID, post.ID, post.AuthorID, post.Entities, post.CreatedAt); err != nil {
+		s.logger.Error("Failed to index post entities", zap.String("post_id", post.ID), zap.Error(err))
+	}
+	if err := s.search.IndexPost(post); err != nil {
+		s.logger.Error("Failed to index post for search", zap.String("post_id", post.ID), zap.Error(err))
+	}
 }
 
-func (s *postService) GetPostsByUserID(userID string) ([]models.Post, error) {
-	return s.postRepo.FindByAuthorID(userID)
+func (s *postService) findVisiblePost(postID, viewerID string) (*models.Post, error) {
+	post, err := s.postRepo.FindByID(postID)
+	if err != nil {
+		return nil, ErrPostNotFound
+	}
+	visible, err := s.policy.CanViewPost(viewerID, post)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, ErrPostNotFound
+	}
+	return post, nil
 }
 
-func (s *postService) UpdatePost(postID, userID, content string) (*models.Post, error) {
+// findPage loads one extra row to learn whether another page exists.
+func (s *postService) findPage(query repository.PostPageQuery, cursor string) (*PostPage, error) {
+	after, err := repository.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	query.After = after
+	query.ExcludeAuthorIDs, err = s.policy.HiddenUserIDs(query.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	limit := query.Limit
+	query.Limit = limit + 1
+
+	posts, err := s.postRepo.FindPage(query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &PostPage{Posts: posts}
+	if len(posts) > limit {
+		page.Posts = posts[:limit]
+		last := page.Posts[limit-1]
+		page.NextCursor = repository.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
+
+func (s *postService) UpdatePost(postID, userID string, input UpdatePostInput) (*models.Post, error) {
+	return s.update(postID, userID, input, nil)
+}
+
+// RevertPost puts back the content the post had before the given edit. The
+// revert is an edit of its own and is recorded in the history.
+func (s *postService) RevertPost(postID, historyID, userID string) (*models.Post, error) {
+	history, err := s.historyRepo.FindByID(historyID)
+	if err != nil || history.EntityType != "post" || history.EntityID != postID {
+		return nil, ErrHistoryNotFound
+	}
+	return s.update(postID, userID, UpdatePostInput{Content: history.OldContent}, &history.ID)
+}
+
+// update edits a post for UpdatePost and RevertPost; revertOf is the
+// history entry a revert restores.
+func (s *postService) update(postID, userID string, input UpdatePostInput, revertOf *string) (*models.Post, error) {
 	// Verify post exists and belongs to user
 	post, err := s.postRepo.FindByID(postID)
 	if err != nil {
@@ -76,28 +459,132 @@ func (s *postService) UpdatePost(postID, userID, content string) (*models.Post,
 	if post.AuthorID != userID {
 		return nil, nil // Not authorized
 	}
+	if input.Version != 0 && input.Version != post.Version {
+		return nil, ErrStaleVersion
+	}
+
+	remove := make(map[string]bool, len(input.RemoveAttachmentIDs))
+	for _, id := range input.RemoveAttachmentIDs {
+		remove[id] = true
+	}
+	var removed []models.PostAttachment
+	nextPosition := 0
+	for _, attachment := range post.Attachments {
+		if remove[attachment.ID] {
+			removed = append(removed, attachment)
+		}
+		if attachment.Position >= nextPosition {
+			nextPosition = attachment.Position + 1
+		}
+	}
+
+	remaining := len(post.Attachments) - len(removed) + len(input.Attachments)
+	if remaining > s.attachments.Limits().MaxPerPost {
+		return nil, ErrTooManyAttachments
+	}
+	if strings.TrimSpace(input.Content) == "" && remaining == 0 && post.RepostOfID == nil {
+		return nil, ErrEmptyPost
+	}
+	screened, err := screen(s.filter, filter.Post, userID, input.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	wasPublished := post.IsPublished()
+	if input.Status != "" {
+		if wasPublished && input.Status != models.PostStatusPublished {
+			return nil, ErrAlreadyPublished
+		}
+		post.Status, post.PublishAt, err = resolveStatus(input.Status, input.PublishAt, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+	// A post goes to the top of feeds the moment it is published
+	if !wasPublished && post.IsPublished() {
+		post.CreatedAt = time.Now()
+	}
+
+	entities, err := s.entities.Extract(screened.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := s.attachments.Store(input.Attachments, nextPosition)
+	if err != nil {
+		return nil, err
+	}
+	for i := range added {
+		added[i].PostID = postID
+	}
 	
 	// Save old content for history
 	oldContent := post.Content
 	
 	// Update content
-	post.Content = content
-	if err := s.postRepo.Update(post); err != nil {
+	post.Content = screened.Text
+	post.Entities = entities
+	if input.Visibility != "" {
+		post.Visibility = input.Visibility
+	}
+	saved, err := s.postRepo.Update(post)
+	if err != nil || !saved {
+		s.attachments.Remove(added)
+		if err == nil {
+			err = ErrStaleVersion
+		} else if post.RepostOfID != nil && post.Content == "" {
+			// Emptying a quote turns it into a plain repost
+			err = s.plainRepostConflict(err, userID, *post.RepostOfID)
+		}
+		return nil, err
+	}
+	if err := s.postRepo.AddAttachments(added); err != nil {
+		s.attachments.Remove(added)
 		return nil, err
 	}
+	if len(removed) > 0 {
+		ids := make([]string, 0, len(removed))
+		for _, attachment := range removed {
+			ids = append(ids, attachment.ID)
+		}
+		if err := s.postRepo.DeleteAttachments(postID, ids); err != nil {
+			return nil, err
+		}
+		s.attachments.Remove(removed)
+	}
 	
-	// Save edit history
-	history := models.NewEditHistory("post", postID, oldContent, content)
-	if err := s.historyRepo.Create(history); err != nil {
-		// Log error but don't fail the update
+	// A held edit takes the post out of listings until a moderator
+	// approves it
+	if screened.Verdict == filter.Hold {
+		if err := s.HidePost(postID); err != nil {
+			return nil, err
+		}
+		if err := hold(s.review, screened, models.ReportTargetPost, postID, userID); err != nil {
+			return nil, err
+		}
+	} else if post.IsPublished() {
+		s.indexPublished(post)
+	}
+
+	// Save edit history; drafts are edited freely until they are published
+	if wasPublished && oldContent != post.Content {
+		history := models.NewEditHistory("post", postID, userID, oldContent, post.Content)
+		history.RevertOf = revertOf
+		if err := s.historyRepo.Create(history); err != nil {
+			s.logger.Error("Failed to save post edit history", zap.String("post_id", postID), zap.Error(err))
+		}
 	}
 	
 	// Reload with author
 	return s.postRepo.FindByID(postID)
 }
 
-func (s *postService) GetEditHistory(postID string) ([]models.EditHistory, error) {
-	return s.historyRepo.FindByEntity("post", postID)
+func (s *postService) GetEditHistory(postID, viewerID string) (*EditHistory, error) {
+	post, err := s.findVisiblePost(postID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return findEditHistory(s.historyRepo, s.userRepo, "post", postID, post.AuthorID, viewerID)
 }
 
 func (s *postService) DeletePost(postID, userID string) error {
@@ -111,31 +598,245 @@ func (s *postService) DeletePost(postID, userID string) error {
 		return nil // Not authorized, but don't reveal this
 	}
 	
-	return s.postRepo.Delete(postID)
+	return s.remove(post)
 }
 
-func (s *postService) ReactToPost(postID, userID, reaction string) error {
-	// Validate reaction type
-	validReactions := map[string]bool{
-		"like":  true,
-		"love":  true,
-		"haha":  true,
-		"wow":   true,
-		"sad":   true,
-		"angry": true,
+// RemovePost hides the post before deleting it, which keeps it out of its
+// author's trash.
+func (s *postService) RemovePost(postID string) error {
+	post, err := s.postRepo.FindByID(postID)
+	if err != nil {
+		return ErrPostNotFound
 	}
-	if !validReactions[reaction] {
-		return nil // Invalid reaction, ignore
+	if err := s.postRepo.SetHidden(postID, true); err != nil {
+		return err
 	}
+	return s.remove(post)
+}
 
-	return s.postRepo.ReactToPost(postID, userID, reaction)
+func (s *postService) remove(post *models.Post) error {
+	if err := s.postRepo.Delete(post.ID); err != nil {
+		return err
+	}
+	if post.RepostOfID != nil {
+		if err := s.postRepo.UpdateRepostsCount(*post.RepostOfID); err != nil {
+			return err
+		}
+	}
+	if err := s.entities.Remove(models.EntitySourcePost, post.ID); err != nil {
+		return err
+	}
+	return s.search.Remove(search.Post, post.ID)
 }
 
-func (s *postService) GetUserReaction(postID, userID string) (string, error) {
-	return s.postRepo.GetUserReaction(postID, userID)
+// RestorePost undoes DeletePost. A plain repost can't come back while the
+// user has shared the same post again since.
+func (s *postService) RestorePost(postID, userID string) (*models.Post, error) {
+	post, err := s.postRepo.FindDeleted(postID)
+	if err != nil || post.AuthorID != userID || post.Hidden {
+		return nil, ErrTrashItemNotFound
+	}
+	if post.IsPlainRepost() {
+		_, err := s.postRepo.FindPlainRepost(userID, *post.RepostOfID)
+		if err == nil {
+			return nil, ErrAlreadyReposted
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	if err := s.postRepo.Restore(postID); err != nil {
+		if post.IsPlainRepost() {
+			return nil, s.plainRepostConflict(err, userID, *post.RepostOfID)
+		}
+		return nil, err
+	}
+	if post.RepostOfID != nil {
+		if err := s.postRepo.UpdateRepostsCount(*post.RepostOfID); err != nil {
+			s.logger.Error("Failed to update repost count", zap.String("post_id", *post.RepostOfID), zap.Error(err))
+		}
+	}
+	if post.IsPublished() && !post.IsPlainRepost() {
+		s.indexPublished(post)
+	}
+
+	return s.postRepo.FindByID(postID)
 }
 
-func (s *postService) GetReactionsCount(postID string) (map[string]int64, error) {
-	return s.postRepo.GetReactionsCount(postID)
+// HidePost takes a post out of feeds, tag listings and search. Unlike
+// deleting, the author can still see it and its reposts keep pointing at it.
+func (s *postService) HidePost(postID string) error {
+	if err := s.postRepo.SetHidden(postID, true); err != nil {
+		return err
+	}
+	if err := s.entities.Remove(models.EntitySourcePost, postID); err != nil {
+		return err
+	}
+	return s.search.Remove(search.Post, postID)
 }
 
+// UnhidePost undoes HidePost, putting a published post back in listings.
+func (s *postService) UnhidePost(postID string) error {
+	post, err := s.postRepo.FindByID(postID)
+	if err != nil {
+		return ErrPostNotFound
+	}
+	if err := s.postRepo.SetHidden(postID, false); err != nil {
+		return err
+	}
+	post.Hidden = false
+	if post.IsPublished() && !post.IsPlainRepost() {
+		s.indexPublished(post)
+	}
+	return nil
+}
+
+// Repost shares a post the user can see. Only public posts can be shared,
+// apart from the user's own. Reposting a plain repost shares the post it
+// points to, and each post can be plainly reposted once per user.
+func (s *postService) Repost(postID, userID string, input RepostInput) (*models.Post, error) {
+	original, err := s.findVisiblePost(postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if original.IsPlainRepost() {
+		original, err = s.findVisiblePost(*original.RepostOfID, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !original.IsPublished() || (original.Visibility != models.PostVisibilityPublic && original.AuthorID != userID) {
+		return nil, ErrCannotRepost
+	}
+
+	content := input.Content
+	if strings.TrimSpace(content) == "" {
+		content = ""
+		_, err := s.postRepo.FindPlainRepost(userID, original.ID)
+		if err == nil {
+			return nil, ErrAlreadyReposted
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	screened, err := screen(s.filter, filter.Post, userID, content)
+	if err != nil {
+		return nil, err
+	}
+	content = screened.Text
+
+	entities, err := s.entities.Extract(content)
+	if err != nil {
+		return nil, err
+	}
+
+	visibility := input.Visibility
+	if visibility == "" {
+		visibility = models.PostVisibilityPublic
+	}
+
+	post := &models.Post{
+		Content:    content,
+		AuthorID:   userID,
+		Visibility: visibility,
+		RepostOfID: &original.ID,
+		Entities:   entities,
+		Hidden:     screened.Verdict == filter.Hold,
+	}
+	if err := s.postRepo.Create(post); err != nil {
+		if content == "" {
+			return nil, s.plainRepostConflict(err, userID, original.ID)
+		}
+		return nil, err
+	}
+	if err := hold(s.review, screened, models.ReportTargetPost, post.ID, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.postRepo.UpdateRepostsCount(original.ID); err != nil {
+		s.logger.Error("Failed to update repost count", zap.String("post_id", original.ID), zap.Error(err))
+	}
+	if content != "" {
+		s.indexPublished(post)
+	}
+
+	return s.postRepo.FindByID(post.ID)
+}
+
+// plainRepostConflict turns the unique index error of a plain repost that
+// lost a race with another one into ErrAlreadyReposted.
+func (s *postService) plainRepostConflict(err error, userID, originalID string) error {
+	if _, findErr := s.postRepo.FindPlainRepost(userID, originalID); findErr == nil {
+		return ErrAlreadyReposted
+	}
+	return err
+}
+
+// GetRepostOriginals loads the posts that posts share, keyed by ID. Originals
+// that are deleted or that viewerID may not see are left out, so callers can
+// show a placeholder instead.
+func (s *postService) GetRepostOriginals(posts []models.Post, viewerID string) (map[string]*models.Post, error) {
+	var ids []string
+	for _, post := range posts {
+		if post.RepostOfID != nil {
+			ids = append(ids, *post.RepostOfID)
+		}
+	}
+	originals := make(map[string]*models.Post, len(ids))
+	if len(ids) == 0 {
+		return originals, nil
+	}
+
+	found, err := s.postRepo.FindWithDetailsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range found {
+		visible, err := s.policy.CanViewPost(viewerID, &found[i])
+		if err != nil {
+			return nil, err
+		}
+		if visible {
+			originals[found[i].ID] = &found[i]
+		}
+	}
+	return originals, nil
+}
+
+func (s *postService) ReactToPost(postID, userID, reaction string) error {
+	// Posts by users on either side of a block aren't visible, so this also
+	// stops blocked users from reacting
+	if _, err := s.findVisiblePost(postID, userID); err != nil {
+		return err
+	}
+
+	if err := s.reactions.React(models.ReactionTargetPost, postID, userID, reaction); err != nil {
+		return err
+	}
+	return s.postRepo.UpdateLikesCount(postID)
+}
+
+func (s *postService) GetReactors(postID, viewerID, reaction, cursor string, limit int) (*ReactorPage, error) {
+	if _, err := s.findVisiblePost(postID, viewerID); err != nil {
+		return nil, err
+	}
+	return s.reactions.GetReactors(models.ReactionTargetPost, postID, viewerID, reaction, cursor, limit)
+}
+
+func (s *postService) GetReactionSummary(postID, viewerID string) (*repository.ReactionSummary, error) {
+	summaries, err := s.reactions.GetSummaries(models.ReactionTargetPost, []string{postID}, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return summaries[postID], nil
+}
+
+// GetReactionSummaries loads the reactions for a whole page of posts at once.
+func (s *postService) GetReactionSummaries(posts []models.Post, viewerID string) (map[string]*repository.ReactionSummary, error) {
+	postIDs := make([]string, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+	return s.reactions.GetSummaries(models.ReactionTargetPost, postIDs, viewerID)
+}
@@ -0,0 +1,86 @@
+package service
+
+import "unicode"
+
+// ReactionRegistry is the set of reactions clients may use: the named
+// reactions from configuration and, when allowed, any single emoji.
+type ReactionRegistry struct {
+	names      []string
+	known      map[string]bool
+	allowEmoji bool
+}
+
+func NewReactionRegistry(names []string, allowEmoji bool) *ReactionRegistry {
+	known := make(map[string]bool, len(names))
+	for _, name := range names {
+		known[name] = true
+	}
+	return &ReactionRegistry{names: names, known: known, allowEmoji: allowEmoji}
+}
+
+// Names lists the named reactions in the order clients should offer them.
+func (r *ReactionRegistry) Names() []string {
+	return r.names
+}
+
+func (r *ReactionRegistry) AllowsEmoji() bool {
+	return r.allowEmoji
+}
+
+func (r *ReactionRegistry) Valid(reaction string) bool {
+	return r.known[reaction] || (r.allowEmoji && isEmoji(reaction))
+}
+
+// maxEmojiBytes fits the longest emoji sequences, such as family emoji and
+// subdivision flags, and matches the reactions column.
+const maxEmojiBytes = 64
+
+// isEmoji reports whether s is one emoji: a pictograph with optional
+// variation selectors and skin tones, several of them joined with zero
+// width joiners, a flag, or a keycap.
+func isEmoji(s string) bool {
+	runes := []rune(s)
+	if len(runes) == 0 || len(s) > maxEmojiBytes {
+		return false
+	}
+	// Keycaps: a digit, # or *, an optional variation selector, then the
+	// combining keycap
+	if last := runes[len(runes)-1]; last == 0x20E3 && len(runes) <= 3 {
+		return (unicode.IsDigit(runes[0]) && runes[0] < 0x80) || runes[0] == '#' || runes[0] == '*'
+	}
+
+	expectBase := true
+	regional := 0
+	for _, r := range runes {
+		switch {
+		case r == 0x200D: // zero width joiner
+			if expectBase {
+				return false
+			}
+			expectBase = true
+		case r == 0xFE0E, r == 0xFE0F, r >= 0x1F3FB && r <= 0x1F3FF, r >= 0xE0020 && r <= 0xE007F:
+			// Variation selectors, skin tones and the tags of subdivision flags
+			if expectBase {
+				return false
+			}
+		case r >= 0x1F1E6 && r <= 0x1F1FF: // regional indicators, in pairs for flags
+			regional++
+			if regional > 2 || (!expectBase && regional == 1) {
+				return false
+			}
+			expectBase = false
+		case pictograph(r):
+			if !expectBase {
+				return false
+			}
+			expectBase = false
+		default:
+			return false
+		}
+	}
+	return !expectBase && regional != 1
+}
+
+func pictograph(r rune) bool {
+	return r == 0xA9 || r == 0xAE || (r >= 0x2100 && unicode.Is(unicode.So, r))
+}
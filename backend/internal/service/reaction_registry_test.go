@@ -0,0 +1,44 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsEmoji(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want bool
+	}{
+		{"pictograph", "👍", true},
+		{"skin tone", "👍🏽", true},
+		{"variation selector", "❤️", true},
+		{"zwj family", "👨‍👩‍👧", true},
+		{"zwj with skin tone", "🧑🏽‍💻", true},
+		{"country flag", "🇹🇭", true},
+		{"subdivision flag", "🏴\U000E0067\U000E0062\U000E0065\U000E006E\U000E0067\U000E007F", true},
+		{"keycap", "1️⃣", true},
+		{"keycap without selector", "#⃣", true},
+		{"copyright sign", "©", true},
+		{"empty", "", false},
+		{"letter", "a", false},
+		{"word", "like", false},
+		{"thai letter", "ก", false},
+		{"two emoji", "👍👍", false},
+		{"lone regional indicator", "🇹", false},
+		{"three regional indicators", "🇹🇭🇯", false},
+		{"leading joiner", "‍👍", false},
+		{"trailing joiner", "👍‍", false},
+		{"lone skin tone", "🏽", false},
+		{"letter keycap", "a⃣", false},
+		{"too long", strings.Repeat("👍‍", 9) + "👍", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isEmoji(tt.in); got != tt.want {
+				t.Errorf("isEmoji(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"errors"
+
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+)
+
+var ErrInvalidReaction = errors.New("reaction ไม่ถูกต้อง")
+
+// ReactorPage is one page of the users who reacted to something, most
+// recent first. NextCursor is empty on the last page.
+type ReactorPage struct {
+	Reactions  []models.Reaction
+	NextCursor string
+}
+
+// ReactionService keeps reactions for every kind of target. It doesn't know
+// who may see a target: PostService, CommentService and MessageService check
+// that before calling it.
+type ReactionService interface {
+	Registry() *ReactionRegistry
+	// React sets the user's reaction, or removes it when it is the one
+	// they already gave.
+	React(targetType, targetID, userID, reaction string) error
+	GetSummaries(targetType string, targetIDs []string, viewerID string) (map[string]*repository.ReactionSummary, error)
+	// GetReactors lists who reacted, optionally with one reaction only,
+	// leaving out users hidden from the viewer.
+	GetReactors(targetType, targetID, viewerID, reaction, cursor string, limit int) (*ReactorPage, error)
+}
+
+type reactionService struct {
+	reactionRepo repository.ReactionRepository
+	policy       ContentPolicy
+	registry     *ReactionRegistry
+}
+
+func NewReactionService(reactionRepo repository.ReactionRepository, policy ContentPolicy, registry *ReactionRegistry) ReactionService {
+	return &reactionService{
+		reactionRepo: reactionRepo,
+		policy:       policy,
+		registry:     registry,
+	}
+}
+
+func (s *reactionService) Registry() *ReactionRegistry {
+	return s.registry
+}
+
+func (s *reactionService) React(targetType, targetID, userID, reaction string) error {
+	if !s.registry.Valid(reaction) {
+		return ErrInvalidReaction
+	}
+	return s.reactionRepo.Toggle(targetType, targetID, userID, reaction)
+}
+
+func (s *reactionService) GetSummaries(targetType string, targetIDs []string, viewerID string) (map[string]*repository.ReactionSummary, error) {
+	return s.reactionRepo.GetSummaries(targetType, targetIDs, viewerID)
+}
+
+func (s *reactionService) GetReactors(targetType, targetID, viewerID, reaction, cursor string, limit int) (*ReactorPage, error) {
+	after, err := repository.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := s.policy.HiddenUserIDs(viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	reactions, err := s.reactionRepo.FindReactors(repository.ReactorPageQuery{
+		TargetType:     targetType,
+		TargetID:       targetID,
+		Reaction:       reaction,
+		ExcludeUserIDs: hidden,
+		After:          after,
+		Limit:          limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ReactorPage{Reactions: reactions}
+	if len(reactions) > limit {
+		page.Reactions = reactions[:limit]
+		last := page.Reactions[limit-1]
+		page.NextCursor = repository.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
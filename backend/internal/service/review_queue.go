@@ -0,0 +1,61 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"typinggame-api/internal/filter"
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+)
+
+// ErrContentRejected is returned, wrapped with the filter's reasons, for
+// text the content filter refuses.
+var ErrContentRejected = errors.New("เนื้อหาไม่ผ่านการตรวจสอบ")
+
+// ReviewQueue files content the content filter held back, so moderators can
+// approve or remove it from the moderation queue.
+type ReviewQueue interface {
+	Hold(targetType, targetID, ownerID, reason string) error
+}
+
+type reviewQueue struct {
+	reportRepo repository.ReportRepository
+}
+
+func NewReviewQueue(reportRepo repository.ReportRepository) ReviewQueue {
+	return &reviewQueue{reportRepo: reportRepo}
+}
+
+// Hold opens a report filed by the system rather than by a user.
+func (q *reviewQueue) Hold(targetType, targetID, ownerID, reason string) error {
+	return q.reportRepo.Create(&models.Report{
+		ReporterID:    models.SystemReporterID,
+		TargetType:    targetType,
+		TargetID:      targetID,
+		TargetOwnerID: ownerID,
+		Reason:        "ระบบกรองเนื้อหา: " + reason,
+		Status:        models.ReportStatusOpen,
+	})
+}
+
+// screen runs text through the content filter. Rejected text comes back as
+// an error; otherwise the result carries the text to store, masked where
+// needed, and a Hold verdict means it must be stored hidden and filed with
+// the review queue.
+func screen(contentFilter filter.ContentFilter, kind filter.Kind, authorID, text string) (filter.Result, error) {
+	result := contentFilter.Check(filter.Input{Kind: kind, AuthorID: authorID, Text: text})
+	if result.Verdict == filter.Reject {
+		return result, fmt.Errorf("%w: %s", ErrContentRejected, result.Reason())
+	}
+	return result, nil
+}
+
+// hold files content that screening held back with the review queue, and
+// does nothing for content that passed.
+func hold(queue ReviewQueue, result filter.Result, targetType, targetID, ownerID string) error {
+	if result.Verdict != filter.Hold {
+		return nil
+	}
+	return queue.Hold(targetType, targetID, ownerID, result.Reason())
+}
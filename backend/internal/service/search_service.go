@@ -0,0 +1,335 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+	"typinggame-api/internal/search"
+)
+
+const (
+	// maxSearchRounds caps how many batches of hits one page may go through
+	// when most of them are filtered out, so a page can come back short
+	// rather than scanning the whole index
+	maxSearchRounds = 5
+	minSearchBatch  = 20
+	reindexBatch    = 500
+)
+
+var (
+	ErrInvalidSearchType   = errors.New("ประเภทการค้นหาไม่ถูกต้อง")
+	ErrSearchQueryTooShort = errors.New("คำค้นหาต้องมีอย่างน้อย 2 ตัวอักษร")
+)
+
+// SearchResult is one match. Exactly one of Post, Comment and User is set,
+// depending on the type searched. Highlights point into the post or comment
+// content, or the user's name.
+type SearchResult struct {
+	Score      float64
+	Highlights []search.Range
+	Post       *models.Post
+	Comment    *models.Comment
+	User       *models.User
+}
+
+// SearchPage is one page of results, best match first. NextCursor is empty
+// on the last page.
+type SearchPage struct {
+	Results    []SearchResult
+	NextCursor string
+}
+
+type SearchService interface {
+	Search(viewerID, query, docType, cursor string, limit int) (*SearchPage, error)
+	IndexPost(post *models.Post) error
+	IndexComment(comment *models.Comment) error
+	IndexUser(user *models.User) error
+	Remove(docType search.DocType, id string) error
+	// Reindex puts every post, comment and registered user back into the
+	// index and returns how many documents it wrote.
+	Reindex() (int, error)
+}
+
+type searchService struct {
+	index       search.Index
+	postRepo    repository.PostRepository
+	commentRepo repository.CommentRepository
+	userRepo    repository.UserRepository
+	policy      ContentPolicy
+}
+
+func NewSearchService(index search.Index, postRepo repository.PostRepository, commentRepo repository.CommentRepository, userRepo repository.UserRepository, policy ContentPolicy) SearchService {
+	return &searchService{
+		index:       index,
+		postRepo:    postRepo,
+		commentRepo: commentRepo,
+		userRepo:    userRepo,
+		policy:      policy,
+	}
+}
+
+// IndexPost indexes a live post. Drafts, scheduled posts and posts hidden by
+// a moderator are kept out of the index.
+func (s *searchService) IndexPost(post *models.Post) error {
+	if !post.IsPublished() || post.Hidden {
+		return s.index.Delete(search.Post, post.ID)
+	}
+	return s.index.Put(search.Document{
+		Type:      search.Post,
+		ID:        post.ID,
+		AuthorID:  post.AuthorID,
+		Body:      post.Content,
+		CreatedAt: post.CreatedAt,
+	})
+}
+
+// IndexComment indexes a comment unless a moderator has hidden it.
+func (s *searchService) IndexComment(comment *models.Comment) error {
+	if comment.Hidden {
+		return s.index.Delete(search.Comment, comment.ID)
+	}
+	return s.index.Put(search.Document{
+		Type:      search.Comment,
+		ID:        comment.ID,
+		AuthorID:  comment.AuthorID,
+		Body:      comment.Content,
+		CreatedAt: comment.CreatedAt,
+	})
+}
+
+// IndexUser indexes a user by name. Guests can't be found.
+func (s *searchService) IndexUser(user *models.User) error {
+	if user.IsGuest {
+		return nil
+	}
+	return s.index.Put(search.Document{
+		Type:      search.User,
+		ID:        user.ID,
+		AuthorID:  user.ID,
+		Body:      user.Name,
+		CreatedAt: user.CreatedAt,
+	})
+}
+
+func (s *searchService) Remove(docType search.DocType, id string) error {
+	return s.index.Delete(docType, id)
+}
+
+// Search pages through the index hits, dropping the ones viewerID may not
+// see. Blocked users are filtered by the index itself; post visibility is
+// checked here, so a page can come back shorter than limit.
+func (s *searchService) Search(viewerID, query, docType, cursor string, limit int) (*SearchPage, error) {
+	kind := search.DocType(docType)
+	if kind != search.Post && kind != search.Comment && kind != search.User {
+		return nil, ErrInvalidSearchType
+	}
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return nil, ErrSearchQueryTooShort
+	}
+	offset, err := decodeOffsetCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := s.policy.HiddenUserIDs(viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := max(limit*2, minSearchBatch)
+	page := &SearchPage{}
+	var positions []int
+	exhausted := false
+
+	// Collect one result past the limit to learn whether another page exists
+	for round := 0; round < maxSearchRounds && len(page.Results) <= limit; round++ {
+		hits, err := s.index.Search(search.Query{
+			Type:             kind,
+			Text:             query,
+			ExcludeAuthorIDs: hidden,
+			Offset:           offset,
+			Limit:            batch,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		visible, err := s.load(kind, viewerID, hits, terms)
+		if err != nil {
+			return nil, err
+		}
+		for i, hit := range hits {
+			if result, ok := visible[hit.ID]; ok {
+				result.Score = hit.Score
+				page.Results = append(page.Results, result)
+				positions = append(positions, offset+i)
+			}
+		}
+
+		offset += len(hits)
+		if len(hits) < batch {
+			exhausted = true
+			break
+		}
+	}
+
+	if len(page.Results) > limit {
+		page.NextCursor = encodeOffsetCursor(positions[limit])
+		page.Results = page.Results[:limit]
+	} else if !exhausted {
+		page.NextCursor = encodeOffsetCursor(offset)
+	}
+	return page, nil
+}
+
+// load fetches the documents behind hits and returns the ones viewerID may
+// see, keyed by ID.
+func (s *searchService) load(kind search.DocType, viewerID string, hits []search.Hit, terms []string) (map[string]SearchResult, error) {
+	ids := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	results := make(map[string]SearchResult, len(ids))
+
+	switch kind {
+	case search.Post:
+		posts, err := s.postRepo.FindWithDetailsByIDs(ids)
+		if err != nil {
+			return nil, err
+		}
+		for i := range posts {
+			post := &posts[i]
+			visible, err := s.policy.CanViewPost(viewerID, post)
+			if err != nil {
+				return nil, err
+			}
+			if visible {
+				results[post.ID] = SearchResult{Post: post, Highlights: search.Highlight(post.Content, terms)}
+			}
+		}
+
+	case search.Comment:
+		comments, err := s.commentRepo.FindByIDs(ids)
+		if err != nil {
+			return nil, err
+		}
+		postIDs := make([]string, 0, len(comments))
+		for _, comment := range comments {
+			postIDs = append(postIDs, comment.PostID)
+		}
+		posts, err := s.postRepo.FindByIDs(postIDs)
+		if err != nil {
+			return nil, err
+		}
+		// Comments are as visible as the post they are on
+		visiblePosts := make(map[string]bool, len(posts))
+		for i := range posts {
+			visible, err := s.policy.CanViewPost(viewerID, &posts[i])
+			if err != nil {
+				return nil, err
+			}
+			visiblePosts[posts[i].ID] = visible
+		}
+		for i := range comments {
+			comment := &comments[i]
+			if visiblePosts[comment.PostID] {
+				results[comment.ID] = SearchResult{Comment: comment, Highlights: search.Highlight(comment.Content, terms)}
+			}
+		}
+
+	case search.User:
+		users, err := s.userRepo.FindByIDs(ids)
+		if err != nil {
+			return nil, err
+		}
+		for i := range users {
+			user := &users[i]
+			if !user.IsGuest {
+				results[user.ID] = SearchResult{User: user, Highlights: search.Highlight(user.Name, terms)}
+			}
+		}
+	}
+	return results, nil
+}
+
+func (s *searchService) Reindex() (int, error) {
+	count := 0
+
+	for after := ""; ; {
+		posts, err := s.postRepo.FindBatch(after, reindexBatch)
+		if err != nil {
+			return count, err
+		}
+		for i := range posts {
+			if err := s.IndexPost(&posts[i]); err != nil {
+				return count, err
+			}
+		}
+		count += len(posts)
+		if len(posts) < reindexBatch {
+			break
+		}
+		after = posts[len(posts)-1].ID
+	}
+
+	for after := ""; ; {
+		comments, err := s.commentRepo.FindBatch(after, reindexBatch)
+		if err != nil {
+			return count, err
+		}
+		for i := range comments {
+			if err := s.IndexComment(&comments[i]); err != nil {
+				return count, err
+			}
+		}
+		count += len(comments)
+		if len(comments) < reindexBatch {
+			break
+		}
+		after = comments[len(comments)-1].ID
+	}
+
+	for after := ""; ; {
+		users, err := s.userRepo.FindBatch(after, reindexBatch)
+		if err != nil {
+			return count, err
+		}
+		for i := range users {
+			if err := s.IndexUser(&users[i]); err != nil {
+				return count, err
+			}
+		}
+		count += len(users)
+		if len(users) < reindexBatch {
+			break
+		}
+		after = users[len(users)-1].ID
+	}
+
+	return count, nil
+}
+
+// Search results and top or hot posts are ranked rather than ordered by time,
+// so their cursor is a position in the ranking instead of a (created_at, id)
+// pair.
+func encodeOffsetCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeOffsetCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, repository.ErrInvalidCursor
+	}
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset < 0 {
+		return 0, repository.ErrInvalidCursor
+	}
+	return offset, nil
+}
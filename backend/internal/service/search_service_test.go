@@ -0,0 +1,286 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+	"typinggame-api/internal/search"
+)
+
+// The stubs embed their interface so only the methods search uses need an
+// implementation; anything else panics.
+type stubPostRepo struct {
+	repository.PostRepository
+	posts map[string]models.Post
+}
+
+func (r *stubPostRepo) FindByIDs(ids []string) ([]models.Post, error) {
+	var posts []models.Post
+	for _, id := range ids {
+		if post, ok := r.posts[id]; ok {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nil
+}
+
+func (r *stubPostRepo) FindWithDetailsByIDs(ids []string) ([]models.Post, error) {
+	return r.FindByIDs(ids)
+}
+
+type stubCommentRepo struct {
+	repository.CommentRepository
+	comments map[string]models.Comment
+}
+
+func (r *stubCommentRepo) FindByIDs(ids []string) ([]models.Comment, error) {
+	var comments []models.Comment
+	for _, id := range ids {
+		if comment, ok := r.comments[id]; ok {
+			comments = append(comments, comment)
+		}
+	}
+	return comments, nil
+}
+
+type stubUserRepo struct {
+	repository.UserRepository
+	users map[string]models.User
+}
+
+func (r *stubUserRepo) FindByIDs(ids []string) ([]models.User, error) {
+	var users []models.User
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// stubPolicy hides blocked authors and posts that aren't public, unless the
+// viewer wrote them.
+type stubPolicy struct {
+	ContentPolicy
+	blocked []string
+}
+
+func (p *stubPolicy) HiddenUserIDs(viewerID string) ([]string, error) {
+	return p.blocked, nil
+}
+
+func (p *stubPolicy) CanViewPost(viewerID string, post *models.Post) (bool, error) {
+	if post.AuthorID == viewerID {
+		return true, nil
+	}
+	return post.IsPublished() && !post.Hidden && post.Visibility == models.PostVisibilityPublic, nil
+}
+
+type searchFixture struct {
+	service  SearchService
+	posts    *stubPostRepo
+	comments *stubCommentRepo
+	users    *stubUserRepo
+	policy   *stubPolicy
+}
+
+func newSearchFixture() *searchFixture {
+	f := &searchFixture{
+		posts:    &stubPostRepo{posts: map[string]models.Post{}},
+		comments: &stubCommentRepo{comments: map[string]models.Comment{}},
+		users:    &stubUserRepo{users: map[string]models.User{}},
+		policy:   &stubPolicy{},
+	}
+	f.service = NewSearchService(search.NewMemoryIndex(), f.posts, f.comments, f.users, f.policy)
+	return f
+}
+
+func (f *searchFixture) addPost(t *testing.T, post models.Post) {
+	t.Helper()
+	f.posts.posts[post.ID] = post
+	if err := f.service.IndexPost(&post); err != nil {
+		t.Fatalf("IndexPost(%s): %v", post.ID, err)
+	}
+}
+
+// searchIDs follows every page of a search and returns the IDs found on
+// each page.
+func searchIDs(t *testing.T, s SearchService, viewerID, query, docType string, limit int) [][]string {
+	t.Helper()
+	var pages [][]string
+	cursor := ""
+	for {
+		page, err := s.Search(viewerID, query, docType, cursor, limit)
+		if err != nil {
+			t.Fatalf("Search(cursor %q): %v", cursor, err)
+		}
+		var ids []string
+		for _, result := range page.Results {
+			switch {
+			case result.Post != nil:
+				ids = append(ids, result.Post.ID)
+			case result.Comment != nil:
+				ids = append(ids, result.Comment.ID)
+			case result.User != nil:
+				ids = append(ids, result.User.ID)
+			}
+		}
+		pages = append(pages, ids)
+		if page.NextCursor == "" {
+			return pages
+		}
+		if len(pages) > 100 {
+			t.Fatal("search never reached the last page")
+		}
+		cursor = page.NextCursor
+	}
+}
+
+func TestSearchPagesSkipHiddenResults(t *testing.T) {
+	f := newSearchFixture()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Newer posts rank first on equal scores; every third one is private
+	var want []string
+	for i := 0; i < 30; i++ {
+		post := models.Post{
+			ID:         fmt.Sprintf("p%02d", i),
+			AuthorID:   "author",
+			Content:    "แข่งพิมพ์ดีด",
+			Visibility: models.PostVisibilityPublic,
+			Status:     models.PostStatusPublished,
+			CreatedAt:  base.Add(time.Duration(i) * time.Minute),
+		}
+		if i%3 == 0 {
+			post.Visibility = models.PostVisibilityOnlyMe
+		}
+		f.addPost(t, post)
+	}
+	for i := 29; i >= 0; i-- {
+		if i%3 != 0 {
+			want = append(want, fmt.Sprintf("p%02d", i))
+		}
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+	}{
+		{"small pages", 3},
+		{"page size dividing the results", 5},
+		{"one page", 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, page := range searchIDs(t, f.service, "viewer", "พิมพ์", "post", tt.limit) {
+				if len(page) > tt.limit {
+					t.Errorf("page of %d results, limit %d", len(page), tt.limit)
+				}
+				got = append(got, page...)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("results = %q, want %q", got, want)
+			}
+		})
+	}
+
+	// The author sees their own private posts
+	var all []string
+	for _, page := range searchIDs(t, f.service, "author", "พิมพ์", "post", 10) {
+		all = append(all, page...)
+	}
+	if len(all) != 30 {
+		t.Errorf("author found %d posts, want 30", len(all))
+	}
+}
+
+func TestSearchLeavesOutUnpublishedAndBlocked(t *testing.T) {
+	f := newSearchFixture()
+	f.policy.blocked = []string{"blocked"}
+	posts := []models.Post{
+		{ID: "live", AuthorID: "a", Content: "タイピング練習", Visibility: models.PostVisibilityPublic},
+		{ID: "draft", AuthorID: "a", Content: "タイピング練習", Status: models.PostStatusDraft},
+		{ID: "hidden", AuthorID: "a", Content: "タイピング練習", Hidden: true},
+		{ID: "blocked", AuthorID: "blocked", Content: "タイピング練習", Visibility: models.PostVisibilityPublic},
+	}
+	for _, post := range posts {
+		f.addPost(t, post)
+	}
+
+	page, err := f.service.Search("viewer", "タイピング", "post", "", 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(page.Results) != 1 || page.Results[0].Post.ID != "live" {
+		t.Fatalf("results = %+v, want only the live post", page.Results)
+	}
+	if want := []search.Range{{Start: 0, End: 5}}; !reflect.DeepEqual(page.Results[0].Highlights, want) {
+		t.Errorf("highlights = %v, want %v", page.Results[0].Highlights, want)
+	}
+}
+
+func TestSearchCommentsFollowTheirPost(t *testing.T) {
+	f := newSearchFixture()
+	f.addPost(t, models.Post{ID: "public", AuthorID: "a", Content: "x", Visibility: models.PostVisibilityPublic})
+	f.addPost(t, models.Post{ID: "private", AuthorID: "a", Content: "x", Visibility: models.PostVisibilityFriends})
+	for _, comment := range []models.Comment{
+		{ID: "c1", PostID: "public", AuthorID: "b", Content: "nice score"},
+		{ID: "c2", PostID: "private", AuthorID: "b", Content: "nice score"},
+	} {
+		f.comments.comments[comment.ID] = comment
+		if err := f.service.IndexComment(&comment); err != nil {
+			t.Fatalf("IndexComment: %v", err)
+		}
+	}
+
+	pages := searchIDs(t, f.service, "viewer", "score", "comment", 10)
+	if want := [][]string{{"c1"}}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("pages = %q, want %q", pages, want)
+	}
+}
+
+func TestSearchUsersSkipsGuests(t *testing.T) {
+	f := newSearchFixture()
+	for _, user := range []models.User{
+		{ID: "u1", Name: "Somchai"},
+		{ID: "u2", Name: "somchai_guest", IsGuest: true},
+	} {
+		f.users.users[user.ID] = user
+		if err := f.service.IndexUser(&user); err != nil {
+			t.Fatalf("IndexUser: %v", err)
+		}
+	}
+
+	pages := searchIDs(t, f.service, "viewer", "SOMCHAI", "user", 10)
+	if want := [][]string{{"u1"}}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("pages = %q, want %q", pages, want)
+	}
+}
+
+func TestSearchRejectsBadInput(t *testing.T) {
+	f := newSearchFixture()
+	tests := []struct {
+		name    string
+		query   string
+		docType string
+		cursor  string
+		want    error
+	}{
+		{"unknown type", "typing", "video", "", ErrInvalidSearchType},
+		{"query too short", "a !", "post", "", ErrSearchQueryTooShort},
+		{"bad cursor", "typing", "post", "%%%", repository.ErrInvalidCursor},
+		{"negative offset", "typing", "post", encodeOffsetCursor(-1), repository.ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := f.service.Search("viewer", tt.query, tt.docType, tt.cursor, 10); !errors.Is(err, tt.want) {
+				t.Errorf("Search error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
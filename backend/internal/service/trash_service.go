@@ -0,0 +1,164 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"typinggame-api/internal/models"
+	"typinggame-api/internal/repository"
+	"typinggame-api/internal/search"
+	"go.uber.org/zap"
+)
+
+var ErrTrashItemNotFound = errors.New("ไม่พบรายการในถังขยะ")
+
+// purgeBatchSize caps how many posts, and how many comments, one Purge call
+// deletes.
+const purgeBatchSize = 100
+
+// TrashItem is one deleted post or comment; exactly one of Post and Comment
+// is set. PurgeAt is when it will be deleted for good.
+type TrashItem struct {
+	Post      *models.Post
+	Comment   *models.Comment
+	DeletedAt time.Time
+	PurgeAt   time.Time
+}
+
+func (item TrashItem) id() string {
+	if item.Post != nil {
+		return item.Post.ID
+	}
+	return item.Comment.ID
+}
+
+// TrashPage is one page of the trash. NextCursor is empty on the last page.
+type TrashPage struct {
+	Items      []TrashItem
+	NextCursor string
+}
+
+// TrashService lists and purges deleted posts and comments. Restoring is up
+// to PostService and CommentService, which know how to put content back in
+// the derived indexes.
+type TrashService interface {
+	// GetTrash lists the user's deleted posts and comments, most recently
+	// deleted first.
+	GetTrash(userID, cursor string, limit int) (*TrashPage, error)
+	// Purge permanently deletes what has been in the trash longer than the
+	// retention period and returns how many posts and comments it deleted.
+	Purge(now time.Time) (int, error)
+}
+
+type trashService struct {
+	postRepo    repository.PostRepository
+	commentRepo repository.CommentRepository
+	attachments AttachmentService
+	search      SearchService
+	retention   time.Duration
+	logger      *zap.Logger
+}
+
+func NewTrashService(postRepo repository.PostRepository, commentRepo repository.CommentRepository, attachments AttachmentService, search SearchService, retention time.Duration, logger *zap.Logger) TrashService {
+	return &trashService{
+		postRepo:    postRepo,
+		commentRepo: commentRepo,
+		attachments: attachments,
+		search:      search,
+		retention:   retention,
+		logger:      logger,
+	}
+}
+
+// GetTrash reads a page from both tables and merges them. Each table
+// returns up to limit+1 rows past the cursor, which is enough to fill the
+// merged page and learn whether another one exists.
+func (s *trashService) GetTrash(userID, cursor string, limit int) (*TrashPage, error) {
+	after, err := repository.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	query := repository.TrashPageQuery{AuthorID: userID, After: after, Limit: limit + 1}
+
+	posts, err := s.postRepo.FindTrashPage(query)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.commentRepo.FindTrashPage(query)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]TrashItem, 0, len(posts)+len(comments))
+	for len(items) < limit+1 && (len(posts) > 0 || len(comments) > 0) {
+		var item TrashItem
+		if len(comments) == 0 || (len(posts) > 0 && deletedBefore(comments[0].DeletedAt.Time, comments[0].ID, posts[0].DeletedAt.Time, posts[0].ID)) {
+			item = TrashItem{Post: &posts[0], DeletedAt: posts[0].DeletedAt.Time}
+			posts = posts[1:]
+		} else {
+			item = TrashItem{Comment: &comments[0], DeletedAt: comments[0].DeletedAt.Time}
+			comments = comments[1:]
+		}
+		item.PurgeAt = item.DeletedAt.Add(s.retention)
+		items = append(items, item)
+	}
+
+	page := &TrashPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = repository.EncodeCursor(last.DeletedAt, last.id())
+	}
+	return page, nil
+}
+
+// deletedBefore reports whether a comes after b in trash order, which is
+// deletion time then ID, both descending.
+func deletedBefore(aAt time.Time, aID string, bAt time.Time, bID string) bool {
+	if !aAt.Equal(bAt) {
+		return aAt.Before(bAt)
+	}
+	return aID < bID
+}
+
+// Purge deletes posts first: purging a post also deletes its comments, so
+// they don't have to wait for their own turn.
+func (s *trashService) Purge(now time.Time) (int, error) {
+	before := now.Add(-s.retention)
+
+	posts, err := s.postRepo.FindPurgeable(before, purgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	postIDs := make([]string, 0, len(posts))
+	var attachments []models.PostAttachment
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+		attachments = append(attachments, post.Attachments...)
+	}
+	commentIDs, err := s.postRepo.Purge(postIDs)
+	if err != nil {
+		return 0, err
+	}
+	s.attachments.Remove(attachments)
+	// Comments on a deleted post stay searchable until the post is purged;
+	// search results leave them out because the post is gone
+	for _, id := range commentIDs {
+		if err := s.search.Remove(search.Comment, id); err != nil {
+			s.logger.Error("Failed to remove purged comment from search", zap.String("comment_id", id), zap.Error(err))
+		}
+	}
+
+	comments, err := s.commentRepo.FindPurgeable(before, purgeBatchSize)
+	if err != nil {
+		return len(posts), err
+	}
+	ids := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
+	}
+	if err := s.commentRepo.Purge(ids); err != nil {
+		return len(posts), err
+	}
+	return len(posts) + len(comments), nil
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+type localStorage struct {
+	dir       string
+	publicURL string
+	signer    *URLSigner
+}
+
+// NewLocalStorage stores files under dir. The API serves dir itself,
+// checking links with the same signer, so publicURL should point at that
+// route.
+func NewLocalStorage(dir, publicURL string, signer *URLSigner) (Storage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &localStorage{dir: dir, publicURL: publicURL, signer: signer}, nil
+}
+
+func (s *localStorage) Put(key string, data []byte, contentType string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see half a file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *localStorage) Delete(key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *localStorage) URL(key string) string {
+	return s.signer.Sign(s.publicURL, key)
+}
@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config describes an S3 compatible bucket. Requests use path-style URLs
+// (endpoint/bucket/key), which both AWS and MinIO accept. The bucket can be
+// private: links to files are presigned.
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	URLTTL    time.Duration // how long presigned links last at least
+}
+
+type s3Storage struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+func NewS3Storage(cfg S3Config) (Storage, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("s3 endpoint must be an absolute URL: %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("s3 storage needs a bucket, access key and secret key")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	return &s3Storage{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		now:      time.Now,
+	}, nil
+}
+
+func (s *s3Storage) Put(key string, data []byte, contentType string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	req, err := s.newRequest(http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return s.do(req)
+}
+
+func (s *s3Storage) Delete(key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	req, err := s.newRequest(http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	// S3 answers 204 for missing keys too, so deletes are idempotent
+	return s.do(req)
+}
+
+// URL presigns a GET for key (AWS Signature Version 4, query string
+// variant).
+func (s *s3Storage) URL(key string) string {
+	start, expires := urlWindow(s.now(), s.cfg.URLTTL)
+	amzDate := start.UTC().Format("20060102T150405Z")
+	date := start.UTC().Format("20060102")
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	path := s.path(key)
+
+	// Parameters in the canonical query string must be sorted by name
+	query := strings.Join([]string{
+		"X-Amz-Algorithm=AWS4-HMAC-SHA256",
+		"X-Amz-Credential=" + uriEncode(s.cfg.AccessKey+"/"+scope, false),
+		"X-Amz-Date=" + amzDate,
+		"X-Amz-Expires=" + strconv.Itoa(int(expires.Sub(start).Seconds())),
+		"X-Amz-SignedHeaders=host",
+	}, "&")
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		path,
+		query,
+		"host:" + s.endpoint.Host,
+		"",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	signature := s.sign(date, amzDate, scope, canonicalRequest)
+	return s.endpoint.Scheme + "://" + s.endpoint.Host + path + "?" + query + "&X-Amz-Signature=" + signature
+}
+
+func (s *s3Storage) path(key string) string {
+	return s.endpoint.Path + "/" + uriEncode(s.cfg.Bucket, false) + "/" + uriEncode(key, true)
+}
+
+// sign signs a canonical request made at amzDate with the secret key.
+func (s *s3Storage) sign(date, amzDate, scope, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), date)
+	signingKey = hmacSHA256(signingKey, s.cfg.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+}
+
+func (s *s3Storage) do(req *http.Request) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// newRequest builds a request signed with AWS Signature Version 4.
+func (s *s3Storage) newRequest(method, key string, body []byte) (*http.Request, error) {
+	path := s.path(key)
+
+	req, err := http.NewRequest(method, s.endpoint.Scheme+"://"+s.endpoint.Host+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	// Keep Go from re-escaping the already encoded path
+	req.URL.RawPath = path
+
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		method,
+		path,
+		"", // no query string
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	signature := s.sign(date, amzDate, scope, canonicalRequest)
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature))
+	return req, nil
+}
+
+// uriEncode escapes everything except the RFC 3986 unreserved characters, as
+// SigV4 requires. Slashes are kept when encoding an object key.
+func uriEncode(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
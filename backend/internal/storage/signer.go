@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// urlWindow picks the validity of a link issued at now. Links are valid
+// from the start of the current ttl window until two windows later, so the
+// link to a key is the same throughout a window, which keeps browser caches
+// and response ETags stable, and always lasts at least ttl.
+func urlWindow(now time.Time, ttl time.Duration) (start, expires time.Time) {
+	start = now.Truncate(ttl)
+	return start, start.Add(2 * ttl)
+}
+
+// URLSigner issues and checks the short-lived links local storage hands
+// out, so files can only be fetched by someone who was shown them recently.
+type URLSigner struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func NewURLSigner(secret []byte, ttl time.Duration) *URLSigner {
+	return &URLSigner{secret: secret, ttl: ttl, now: time.Now}
+}
+
+// Sign returns the link to key under baseURL.
+func (s *URLSigner) Sign(baseURL, key string) string {
+	_, expires := urlWindow(s.now(), s.ttl)
+	unix := strconv.FormatInt(expires.Unix(), 10)
+	query := url.Values{"expires": {unix}, "signature": {s.signature(key, unix)}}
+	return joinURL(baseURL, key) + "?" + query.Encode()
+}
+
+// Verify reports whether expires and signature came from Sign for key and
+// the link hasn't expired.
+func (s *URLSigner) Verify(key, expires, signature string) bool {
+	if !validKey(key) {
+		return false
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || s.now().Unix() >= unix {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.signature(key, expires)))
+}
+
+func (s *URLSigner) signature(key, expires string) string {
+	return hex.EncodeToString(hmacSHA256(s.secret, key+"\n"+expires))
+}
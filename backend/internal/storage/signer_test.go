@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestURLWindow(t *testing.T) {
+	ttl := 15 * time.Minute
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		now         time.Time
+		wantStart   time.Time
+		wantExpires time.Time
+	}{
+		{"start of a window", base, base, base.Add(30 * time.Minute)},
+		{"inside a window", base.Add(14 * time.Minute), base, base.Add(30 * time.Minute)},
+		{"next window", base.Add(15 * time.Minute), base.Add(15 * time.Minute), base.Add(45 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, expires := urlWindow(tt.now, ttl)
+			if !start.Equal(tt.wantStart) || !expires.Equal(tt.wantExpires) {
+				t.Errorf("urlWindow = (%v, %v), want (%v, %v)", start, expires, tt.wantStart, tt.wantExpires)
+			}
+			if expires.Sub(tt.now) < ttl {
+				t.Errorf("link lasts %v, want at least %v", expires.Sub(tt.now), ttl)
+			}
+		})
+	}
+}
+
+// signedQuery signs key at signedAt and returns the expires and signature
+// parameters of the link.
+func signedQuery(t *testing.T, signer *URLSigner, signedAt time.Time, key string) (string, string) {
+	t.Helper()
+	signer.now = func() time.Time { return signedAt }
+	link, err := url.Parse(signer.Sign("http://localhost/media", key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/media/" + key; link.Path != want {
+		t.Errorf("link path = %q, want %q", link.Path, want)
+	}
+	return link.Query().Get("expires"), link.Query().Get("signature")
+}
+
+func TestURLSignerVerify(t *testing.T) {
+	signedAt := time.Date(2024, 1, 1, 10, 5, 0, 0, time.UTC)
+	signer := NewURLSigner([]byte("secret"), 15*time.Minute)
+	expires, signature := signedQuery(t, signer, signedAt, "posts/a.jpg")
+
+	tests := []struct {
+		name      string
+		checkedAt time.Time
+		signer    *URLSigner
+		key       string
+		expires   string
+		signature string
+		want      bool
+	}{
+		{"fresh link", signedAt, signer, "posts/a.jpg", expires, signature, true},
+		{"just before expiry", signedAt.Add(24*time.Minute + 59*time.Second), signer, "posts/a.jpg", expires, signature, true},
+		{"expired", signedAt.Add(25 * time.Minute), signer, "posts/a.jpg", expires, signature, false},
+		{"other key", signedAt, signer, "posts/b.jpg", expires, signature, false},
+		{"extended expiry", signedAt, signer, "posts/a.jpg", "99999999999", signature, false},
+		{"bad expiry", signedAt, signer, "posts/a.jpg", "soon", signature, false},
+		{"tampered signature", signedAt, signer, "posts/a.jpg", expires, strings.Repeat("0", len(signature)), false},
+		{"other secret", signedAt, NewURLSigner([]byte("other"), 15*time.Minute), "posts/a.jpg", expires, signature, false},
+		{"path traversal", signedAt, signer, "../config.env", expires, signature, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.signer.now = func() time.Time { return tt.checkedAt }
+			if got := tt.signer.Verify(tt.key, tt.expires, tt.signature); got != tt.want {
+				t.Errorf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestURLSignerStableWithinWindow(t *testing.T) {
+	signer := NewURLSigner([]byte("secret"), 15*time.Minute)
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	firstExpires, first := signedQuery(t, signer, base.Add(time.Minute), "posts/a.jpg")
+	laterExpires, later := signedQuery(t, signer, base.Add(14*time.Minute), "posts/a.jpg")
+	if firstExpires != laterExpires || first != later {
+		t.Error("links to the same key changed within one window")
+	}
+	nextExpires, _ := signedQuery(t, signer, base.Add(15*time.Minute), "posts/a.jpg")
+	if nextExpires == firstExpires {
+		t.Error("links did not change in the next window")
+	}
+}
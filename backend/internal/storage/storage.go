@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage keeps uploaded files. Keys are slash separated paths such as
+// "posts/<id>.jpg"; URL turns a key into a short-lived link clients can
+// fetch. Links are only issued to viewers allowed to see the file, and stop
+// working soon after, so files never stay reachable by URL alone.
+type Storage interface {
+	Put(key string, data []byte, contentType string) error
+	Delete(key string) error
+	URL(key string) string
+}
+
+// validKey rejects keys that could escape the storage root.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+func joinURL(base, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + key
+}
@@ -0,0 +1,167 @@
+package textdiff
+
+import "strings"
+
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Change is a run of text that is unchanged, added or removed.
+type Change struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxEditDistance bounds the work spent on one diff. Versions further apart
+// than this are shown as the old text removed and the new one added.
+const maxEditDistance = 1000
+
+// Diff returns the changes that turn old into new, as a minimal edit script
+// over Tokens computed with Myers' algorithm. Adjacent tokens with the same
+// operation are merged, and within a replaced stretch deletions come before
+// insertions.
+func Diff(old, new string) []Change {
+	a, b := Tokens(old), Tokens(new)
+
+	// Common ends never need the full algorithm
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var d differ
+	d.add(Equal, a[:prefix])
+	if script, ok := myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]); ok {
+		for _, step := range script {
+			d.add(step.op, step.tokens)
+		}
+	} else {
+		d.add(Delete, a[prefix:len(a)-suffix])
+		d.add(Insert, b[prefix:len(b)-suffix])
+	}
+	d.add(Equal, a[len(a)-suffix:])
+	return d.changes
+}
+
+// differ collects changes, merging runs of the same operation and keeping
+// deletions ahead of insertions when the two alternate.
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(op Op, tokens []string) {
+	if len(tokens) == 0 {
+		return
+	}
+	text := strings.Join(tokens, "")
+	n := len(d.changes)
+	switch {
+	case n > 0 && d.changes[n-1].Op == op:
+		d.changes[n-1].Text += text
+	case op == Delete && n > 0 && d.changes[n-1].Op == Insert:
+		if n > 1 && d.changes[n-2].Op == Delete {
+			d.changes[n-2].Text += text
+		} else {
+			d.changes = append(d.changes[:n-1], Change{Op: Delete, Text: text}, d.changes[n-1])
+		}
+	default:
+		d.changes = append(d.changes, Change{Op: op, Text: text})
+	}
+}
+
+type step struct {
+	op     Op
+	tokens []string
+}
+
+// myers finds a shortest edit script from a to b. It gives up once the
+// script would need more than maxEditDistance insertions and deletions.
+func myers(a, b []string) ([]step, bool) {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return []step{{Delete, a}, {Insert, b}}, true
+	}
+
+	// v[k] is the furthest x reached on diagonal k = x - y; trace keeps a
+	// copy per edit distance to walk the path back
+	max := n + m
+	if max > maxEditDistance {
+		max = maxEditDistance
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+	for dist := 0; dist <= max; dist++ {
+		trace = append(trace, append([]int(nil), v[offset-dist-1:offset+dist+2]...))
+		for k := -dist; k <= dist; k += 2 {
+			var x int
+			if k == -dist || (k != dist && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, dist), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// backtrack walks the saved frontiers from the end of both sequences back
+// to the start. Each round added one insertion or deletion followed by a
+// run of equal tokens; the steps are collected in reverse.
+func backtrack(a, b []string, trace [][]int, dist int) []step {
+	var steps []step
+	x, y := len(a), len(b)
+	for d := dist; d > 0; d-- {
+		// trace[d] is the frontier round d started from, for diagonals
+		// -d-1 .. d+1
+		frontier := func(k int) int { return trace[d][k+d+1] }
+		k := x - y
+		inserted := k == -d || (k != d && frontier(k-1) < frontier(k+1))
+
+		var prevX, startX int
+		if inserted {
+			prevX = frontier(k + 1)
+			startX = prevX
+		} else {
+			prevX = frontier(k - 1)
+			startX = prevX + 1
+		}
+		prevY := prevX - (x - y)
+		if inserted {
+			prevY--
+		} else {
+			prevY++
+		}
+
+		steps = append(steps, step{Equal, a[startX:x]})
+		if inserted {
+			steps = append(steps, step{Insert, b[prevY : prevY+1]})
+		} else {
+			steps = append(steps, step{Delete, a[prevX : prevX+1]})
+		}
+		x, y = prevX, prevY
+	}
+	steps = append(steps, step{Equal, a[:x]})
+
+	for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+		steps[i], steps[j] = steps[j], steps[i]
+	}
+	return steps
+}
@@ -0,0 +1,74 @@
+package textdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []Change
+	}{
+		{"unchanged", "same text", "same text", []Change{{Equal, "same text"}}},
+		{"both empty", "", "", nil},
+		{"all inserted", "", "hello", []Change{{Insert, "hello"}}},
+		{"all deleted", "hello", "", []Change{{Delete, "hello"}}},
+		{"word replaced", "the quick fox", "the slow fox", []Change{
+			{Equal, "the "}, {Delete, "quick"}, {Insert, "slow"}, {Equal, " fox"},
+		}},
+		{"word added", "the fox", "the quick fox", []Change{
+			{Equal, "the "}, {Insert, "quick "}, {Equal, "fox"},
+		}},
+		{"deletions before insertions", "a b c", "x b y", []Change{
+			{Delete, "a"}, {Insert, "x"}, {Equal, " b "}, {Delete, "c"}, {Insert, "y"},
+		}},
+		{"thai cluster", "ฉันกินข้าว", "ฉันกินน้ำ", []Change{
+			{Equal, "ฉันกิน"}, {Delete, "ข้าว"}, {Insert, "น้ำ"},
+		}},
+		{"japanese particle", "東京に行く", "東京へ行く", []Change{
+			{Equal, "東京"}, {Delete, "に"}, {Insert, "へ"}, {Equal, "行く"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.old, tt.new); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff(%q, %q) = %v, want %v", tt.old, tt.new, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDiffRebuildsBothVersions checks that every diff, including ones past
+// maxEditDistance, gives back the old text from its equal and deleted runs
+// and the new text from its equal and inserted runs.
+func TestDiffRebuildsBothVersions(t *testing.T) {
+	long := strings.Repeat("a b ", maxEditDistance)
+	other := strings.Repeat("c d ", maxEditDistance)
+	pairs := []struct{ old, new string }{
+		{"the quick brown fox", "a quick red fox jumps"},
+		{"ฉันชอบกาแฟมาก", "เขาชอบชามาก"},
+		{"今日はいい天気です", "明日もいい天気でしょう"},
+		{"👍 nice", "👎 nice!"},
+		{long, other},
+	}
+	for _, pair := range pairs {
+		var old, new strings.Builder
+		for _, change := range Diff(pair.old, pair.new) {
+			switch change.Op {
+			case Equal:
+				old.WriteString(change.Text)
+				new.WriteString(change.Text)
+			case Delete:
+				old.WriteString(change.Text)
+			case Insert:
+				new.WriteString(change.Text)
+			}
+		}
+		if old.String() != pair.old || new.String() != pair.new {
+			t.Errorf("Diff(%.20q, %.20q) rebuilt %.20q and %.20q", pair.old, pair.new, old.String(), new.String())
+		}
+	}
+}
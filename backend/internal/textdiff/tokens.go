@@ -0,0 +1,94 @@
+// Package textdiff compares two versions of user text. Text is split into
+// words where the script separates words with spaces; Thai and similar
+// scripts have no spaces and are split into character clusters (a consonant
+// with its vowels and tone marks). Japanese is split into runs of kanji or
+// katakana, which keep compounds and loanwords whole, and single hiragana,
+// which are mostly particles and inflections. A change never cuts a mark off
+// the letter it belongs to.
+package textdiff
+
+import "unicode"
+
+type class int
+
+const (
+	classOther class = iota // punctuation and symbols, one rune each
+	classSpace
+	classWord
+	classCluster // Thai, Lao, Khmer, Myanmar
+	classHan
+	classHiragana // one rune each
+	classKatakana
+)
+
+func classify(r rune) class {
+	switch {
+	case unicode.IsSpace(r):
+		return classSpace
+	case unicode.In(r, unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar):
+		return classCluster
+	case unicode.Is(unicode.Han, r):
+		return classHan
+	case unicode.Is(unicode.Hiragana, r):
+		return classHiragana
+	case unicode.Is(unicode.Katakana, r), r == 'ー': // the long vowel mark is shared by both kana
+		return classKatakana
+	case unicode.IsLetter(r), unicode.IsDigit(r):
+		return classWord
+	}
+	return classOther
+}
+
+// leadingVowel reports whether r is written before the consonant it
+// follows in speech, so it belongs to the next cluster.
+func leadingVowel(r rune) bool {
+	return (r >= 0x0E40 && r <= 0x0E44) || (r >= 0x0EC0 && r <= 0x0EC4)
+}
+
+// trailingVowel reports whether r is a spacing vowel that can't start a
+// cluster, such as Thai sara aa.
+func trailingVowel(r rune) bool {
+	switch r {
+	case 0x0E30, 0x0E32, 0x0E33, 0x0E45, 0x0EB0, 0x0EB2, 0x0EB3:
+		return true
+	}
+	return false
+}
+
+// Tokens splits text into the units a diff works on. Joining the tokens
+// gives back the text.
+func Tokens(text string) []string {
+	var tokens []string
+	var current []rune
+	currentClass := classOther
+	flush := func() {
+		if len(current) > 0 {
+			tokens = append(tokens, string(current))
+			current = current[:0]
+		}
+	}
+
+	for _, r := range text {
+		c := classify(r)
+		switch {
+		case len(current) == 0:
+		case unicode.In(r, unicode.Mn, unicode.Mc, unicode.Me):
+			// Marks stay with the letter before them
+			current = append(current, r)
+			continue
+		case c != currentClass:
+			flush()
+		case c == classCluster:
+			last := current[len(current)-1]
+			if !trailingVowel(r) && !(len(current) == 1 && leadingVowel(last)) {
+				flush()
+			}
+		case c == classOther, c == classHiragana:
+			flush()
+		}
+		current = append(current, r)
+		currentClass = c
+	}
+	flush()
+	return tokens
+}
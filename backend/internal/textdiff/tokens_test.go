@@ -0,0 +1,35 @@
+package textdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"words and spaces", "the quick fox", []string{"the", " ", "quick", " ", "fox"}},
+		{"punctuation one rune each", "hi!?", []string{"hi", "!", "?"}},
+		{"thai marks stay on their consonant", "กิน", []string{"กิ", "น"}},
+		{"thai leading vowel joins the next consonant", "เกม", []string{"เก", "ม"}},
+		{"thai trailing vowel", "ทำงาน", []string{"ทำ", "งา", "น"}},
+		{"japanese", "東京に行きます", []string{"東京", "に", "行", "き", "ま", "す"}},
+		{"katakana with long vowel mark", "コーヒーを", []string{"コーヒー", "を"}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Tokens(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokens(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if joined := strings.Join(got, ""); joined != tt.text {
+				t.Errorf("joined tokens = %q, want %q", joined, tt.text)
+			}
+		})
+	}
+}
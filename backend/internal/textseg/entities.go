@@ -0,0 +1,125 @@
+// Package textseg finds hashtags and @mentions in user text. It works on
+// Unicode letters rather than ASCII, so Thai (whose vowels and tone marks are
+// combining characters) and Japanese tags are picked up whole.
+package textseg
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+type Kind string
+
+const (
+	Hashtag Kind = "hashtag"
+	Mention Kind = "mention"
+
+	maxTagLength    = 100
+	maxHandleLength = 50
+)
+
+// Entity is one hashtag or mention. Start and End are offsets in code points
+// (not bytes) into the original text, End exclusive, and include the # or @.
+// Value is the normalized tag or handle without the prefix.
+type Entity struct {
+	Kind  Kind
+	Value string
+	Start int
+	End   int
+}
+
+// Parse returns the entities in text in order of appearance. A # or @ only
+// starts an entity at the beginning of the text or after a character that
+// can't be part of one, so "a@b.com" and "C#" are left alone. Hashtags made
+// only of digits are ignored.
+func Parse(text string) []Entity {
+	runes := []rune(text)
+	var entities []Entity
+
+	for i := 0; i < len(runes); i++ {
+		kind, ok := prefixKind(runes[i])
+		if !ok || (i > 0 && (isWordRune(runes[i-1]) || isPrefix(runes[i-1]))) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		// A word glued to another prefix ("#a#b") is not a tag
+		if end < len(runes) && isPrefix(runes[end]) {
+			i = end
+			continue
+		}
+
+		body := string(runes[i+1 : end])
+		if body == "" || (kind == Hashtag && allDigits(body)) {
+			continue
+		}
+		limit := maxTagLength
+		if kind == Mention {
+			limit = maxHandleLength
+		}
+		if end-i-1 > limit {
+			i = end
+			continue
+		}
+
+		entities = append(entities, Entity{Kind: kind, Value: Normalize(body), Start: i, End: end})
+		i = end - 1
+	}
+	return entities
+}
+
+// Normalize folds full-width and compatibility forms and case, so #ＴＥＳＴ,
+// #Test and #test are the same tag.
+func Normalize(s string) string {
+	s = strings.TrimLeft(s, "#＃@＠")
+	return strings.ToLower(norm.NFKC.String(s))
+}
+
+// Values returns the distinct values of entities of the given kind.
+func Values(entities []Entity, kind Kind) []string {
+	seen := map[string]bool{}
+	var values []string
+	for _, entity := range entities {
+		if entity.Kind == kind && !seen[entity.Value] {
+			seen[entity.Value] = true
+			values = append(values, entity.Value)
+		}
+	}
+	return values
+}
+
+func prefixKind(r rune) (Kind, bool) {
+	switch r {
+	case '#', '＃':
+		return Hashtag, true
+	case '@', '＠':
+		return Mention, true
+	}
+	return "", false
+}
+
+func isPrefix(r rune) bool {
+	_, ok := prefixKind(r)
+	return ok
+}
+
+// isWordRune accepts letters (including Japanese kana, kanji and the ー
+// prolonged sound mark), combining marks such as Thai vowels, digits and
+// underscores.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,29 @@
+// Package worker runs periodic background jobs inside the API process.
+package worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Every runs job right away and then once per interval until ctx is
+// cancelled. A failed run is logged and tried again on the next tick; a run
+// in progress is allowed to finish when ctx is cancelled.
+func Every(ctx context.Context, interval time.Duration, logger *zap.Logger, name string, job func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(); err != nil {
+			logger.Error("Background job failed", zap.String("job", name), zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
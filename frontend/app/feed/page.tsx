@@ -1,6 +1,6 @@
 "use client";
 
-import { useState, useEffect } from "react";
+import { useState, useEffect, ReactNode } from "react";
 import TypingGame from "./components/TypingGame";
 import UserSearch from "./components/UserSearch";
 import Chat from "../components/Chat";
@@ -38,6 +38,9 @@ import {
   MoreVert as MoreVertIcon,
   Delete as DeleteIcon,
   History as HistoryIcon,
+  Repeat as RepeatIcon,
+  Bookmark as BookmarkIcon,
+  BookmarkBorder as BookmarkBorderIcon,
 } from "@mui/icons-material";
 import { useRouter } from "next/navigation";
 import axios from "axios";
@@ -53,6 +56,29 @@ interface Post {
   comments: number;
   reactions?: Record<string, number>;
   userReaction?: string;
+  attachments?: Attachment[];
+  entities?: TextEntity[];
+  reposts?: number;
+  isBookmarked?: boolean;
+  repostOfId?: string | null;
+  // A deleted or hidden original comes back as { id, unavailable: true }
+  repostOf?: (Post & { unavailable?: boolean }) | null;
+}
+
+interface TextEntity {
+  type: "hashtag" | "mention";
+  value: string;
+  start: number;
+  end: number;
+  userId?: string;
+}
+
+interface Attachment {
+  id: string;
+  url: string;
+  thumbnailUrl: string;
+  width: number;
+  height: number;
 }
 
 interface Comment {
@@ -67,10 +93,49 @@ interface Comment {
 
 const API_URL = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080";
 
+// Entity offsets count code points, so split with Array.from rather than
+// indexing the UTF-16 string
+function renderContent(content: string, entities: TextEntity[] = [], onOpen: (href: string) => void) {
+  const chars = Array.from(content);
+  const parts: ReactNode[] = [];
+  let pos = 0;
+  entities.forEach((entity, i) => {
+    if (entity.start < pos) return;
+    parts.push(chars.slice(pos, entity.start).join(""));
+    const text = chars.slice(entity.start, entity.end).join("");
+    const href =
+      entity.type === "hashtag"
+        ? `/hashtag/${encodeURIComponent(entity.value)}`
+        : entity.userId
+        ? `/profile/${entity.userId}`
+        : null;
+    parts.push(
+      href ? (
+        <a
+          key={i}
+          href={href}
+          onClick={(e) => {
+            e.preventDefault();
+            onOpen(href);
+          }}
+        >
+          {text}
+        </a>
+      ) : (
+        text
+      )
+    );
+    pos = entity.end;
+  });
+  parts.push(chars.slice(pos).join(""));
+  return parts;
+}
+
 export default function FeedPage() {
   const router = useRouter();
   const [posts, setPosts] = useState<Post[]>([]);
   const [newPost, setNewPost] = useState("");
+  const [newPostFiles, setNewPostFiles] = useState<File[]>([]);
   const [loading, setLoading] = useState(true);
   const [user, setUser] = useState<any>(null);
   const [expandedPost, setExpandedPost] = useState<string | null>(null);
@@ -128,11 +193,11 @@ export default function FeedPage() {
         headers: { Authorization: `Bearer ${token}` },
         timeout: 10000, // 10 seconds timeout
       });
-      if (Array.isArray(response.data)) {
-        setPosts(response.data);
-        response.data.forEach((post: Post) => {
-          fetchReactions(post.id);
-        });
+      // Feed is paginated: { items, nextCursor, hasMore }
+      const items = response.data?.items;
+      if (Array.isArray(items)) {
+        // Each post already carries its reaction counts and userReaction
+        setPosts(items);
       } else {
         console.warn("API response is not an array:", response.data);
         setPosts([]);
@@ -159,15 +224,13 @@ export default function FeedPage() {
         headers: { Authorization: `Bearer ${token}` },
         timeout: 10000, // 10 seconds timeout
       });
-      if (Array.isArray(response.data)) {
-        const newPostIds = response.data.map((p: Post) => p.id).join(",");
+      const items = response.data?.items;
+      if (Array.isArray(items)) {
+        const newPostIds = items.map((p: Post) => p.id).join(",");
         const currentPostIds = posts.map((p) => p.id).join(",");
         
         if (newPostIds !== currentPostIds) {
-          setPosts(response.data);
-          response.data.forEach((post: Post) => {
-            fetchReactions(post.id);
-          });
+          setPosts(items);
         }
       }
     } catch (error) {
@@ -178,22 +241,63 @@ export default function FeedPage() {
   };
 
   const handleCreatePost = async () => {
-    if (!newPost.trim()) return;
+    if (!newPost.trim() && newPostFiles.length === 0) return;
 
     try {
       const token = localStorage.getItem("token");
-      const response = await axios.post(
-        `${API_URL}/api/posts`,
-        { content: newPost },
-        {
-          headers: { Authorization: `Bearer ${token}` },
-        }
-      );
+      // Images go up as multipart; text-only posts stay JSON
+      let body: any = { content: newPost };
+      if (newPostFiles.length > 0) {
+        body = new FormData();
+        body.append("content", newPost);
+        newPostFiles.forEach((file) => body.append("attachments", file));
+      }
+      const response = await axios.post(`${API_URL}/api/posts`, body, {
+        headers: { Authorization: `Bearer ${token}` },
+      });
       setPosts([response.data, ...(posts || [])]);
       setNewPost("");
-    } catch (error) {
+      setNewPostFiles([]);
+    } catch (error: any) {
       console.error("Error creating post:", error);
-      alert("เกิดข้อผิดพลาดในการโพสต์");
+      alert(error.response?.data?.message || "เกิดข้อผิดพลาดในการโพสต์");
+    }
+  };
+
+  const handleRepost = async (postId: string) => {
+    const quote = window.prompt("เพิ่มข้อความ (เว้นว่างเพื่อแชร์อย่างเดียว)");
+    if (quote === null) return;
+
+    try {
+      const token = localStorage.getItem("token");
+      const response = await axios.post(
+        `${API_URL}/api/posts/${postId}/repost`,
+        { content: quote },
+        { headers: { Authorization: `Bearer ${token}` } }
+      );
+      setPosts([response.data, ...(posts || [])]);
+    } catch (error: any) {
+      console.error("Error reposting:", error);
+      alert(error.response?.data?.message || "เกิดข้อผิดพลาดในการแชร์โพสต์");
+    }
+  };
+
+  const handleToggleBookmark = async (post: Post) => {
+    try {
+      const token = localStorage.getItem("token");
+      const url = `${API_URL}/api/posts/${post.id}/bookmark`;
+      const headers = { Authorization: `Bearer ${token}` };
+      if (post.isBookmarked) {
+        await axios.delete(url, { headers });
+      } else {
+        await axios.post(url, {}, { headers });
+      }
+      setPosts((prev) =>
+        prev.map((p) => (p.id === post.id ? { ...p, isBookmarked: !post.isBookmarked } : p))
+      );
+    } catch (error: any) {
+      console.error("Error updating bookmark:", error);
+      alert(error.response?.data?.message || "เกิดข้อผิดพลาดในการบันทึกโพสต์");
     }
   };
 
@@ -243,34 +347,6 @@ export default function FeedPage() {
     }
   };
 
-  const fetchReactions = async (postId: string) => {
-    try {
-      const token = localStorage.getItem("token");
-      const response = await axios.get(`${API_URL}/api/posts/${postId}/reactions`, {
-        headers: { Authorization: `Bearer ${token}` },
-      });
-      
-      setUserReactions((prev) => ({
-        ...prev,
-        [postId]: response.data.userReaction || "",
-      }));
-      
-      setPosts((prev) =>
-        prev.map((post) =>
-          post.id === postId
-            ? {
-                ...post,
-                reactions: response.data.reactions,
-                userReaction: response.data.userReaction || undefined,
-              }
-            : post
-        )
-      );
-    } catch (error) {
-      console.error("Error fetching reactions:", error);
-    }
-  };
-
   const handleToggleComments = async (postId: string) => {
     if (expandedPost === postId) {
       setExpandedPost(null);
@@ -293,7 +369,7 @@ export default function FeedPage() {
       const response = await axios.get(`${API_URL}/api/posts/${postId}/comments`, {
         headers: { Authorization: `Bearer ${token}` },
       });
-      setComments((prev) => ({ ...prev, [postId]: response.data || [] }));
+      setComments((prev) => ({ ...prev, [postId]: response.data?.items || [] }));
     } catch (error: any) {
       console.error("Error fetching comments:", error);
       if (error.response?.status === 404) {
@@ -478,11 +554,21 @@ export default function FeedPage() {
             onChange={(e) => setNewPost(e.target.value)}
             sx={{ mb: 2 }}
           />
+          <Button variant="outlined" component="label" sx={{ mr: 1 }}>
+            แนบรูปภาพ{newPostFiles.length > 0 ? ` (${newPostFiles.length})` : ""}
+            <input
+              hidden
+              multiple
+              type="file"
+              accept="image/jpeg,image/png,image/gif"
+              onChange={(e) => setNewPostFiles(Array.from(e.target.files || []))}
+            />
+          </Button>
           <Button
             variant="contained"
             endIcon={<SendIcon />}
             onClick={handleCreatePost}
-            disabled={!newPost.trim()}
+            disabled={!newPost.trim() && newPostFiles.length === 0}
           >
             โพสต์
           </Button>
@@ -556,7 +642,53 @@ export default function FeedPage() {
                     </IconButton>
                   )}
                 </Box>
-                <Typography variant="body1">{post.content}</Typography>
+                <Typography variant="body1">
+                  {renderContent(post.content, post.entities, (href) => router.push(href))}
+                </Typography>
+                {post.repostOfId && (
+                  <Paper variant="outlined" sx={{ p: 1.5, mt: 1 }}>
+                    {!post.repostOf || post.repostOf.unavailable ? (
+                      <Typography variant="body2" color="text.secondary">
+                        โพสต์นี้ไม่พร้อมใช้งานแล้ว
+                      </Typography>
+                    ) : (
+                      <Box
+                        sx={{ cursor: "pointer" }}
+                        onClick={() => router.push(`/post/${post.repostOf!.id}`)}
+                      >
+                        <Typography variant="subtitle2">{post.repostOf.authorName}</Typography>
+                        <Typography variant="body2">
+                          {renderContent(post.repostOf.content, post.repostOf.entities, (href) => router.push(href))}
+                        </Typography>
+                        {post.repostOf.attachments && post.repostOf.attachments.length > 0 && (
+                          <Box sx={{ display: "flex", flexWrap: "wrap", gap: 1, mt: 1 }}>
+                            {post.repostOf.attachments.map((attachment) => (
+                              <img
+                                key={attachment.id}
+                                src={attachment.thumbnailUrl}
+                                alt=""
+                                style={{ maxWidth: 120, maxHeight: 120, borderRadius: 4 }}
+                              />
+                            ))}
+                          </Box>
+                        )}
+                      </Box>
+                    )}
+                  </Paper>
+                )}
+                {post.attachments && post.attachments.length > 0 && (
+                  <Box sx={{ display: "flex", flexWrap: "wrap", gap: 1, mt: 2 }}>
+                    {post.attachments.map((attachment) => (
+                      <a key={attachment.id} href={attachment.url} target="_blank" rel="noreferrer">
+                        <img
+                          src={attachment.thumbnailUrl}
+                          alt=""
+                          style={{ maxWidth: 200, maxHeight: 200, borderRadius: 4 }}
+                        />
+                      </a>
+                    ))}
+                  </Box>
+                )}
               </CardContent>
               <CardActions>
                 <Box
@@ -729,6 +861,25 @@ export default function FeedPage() {
                   <CommentIcon />
                 </IconButton>
                 <Typography variant="body2">{post.comments}</Typography>
+                <IconButton
+                  size="small"
+                  color="primary"
+                  onClick={() => handleRepost(post.id)}
+                  title="แชร์โพสต์"
+                  sx={{ ml: 1 }}
+                >
+                  <RepeatIcon />
+                </IconButton>
+                <Typography variant="body2">{post.reposts || 0}</Typography>
+                <IconButton
+                  size="small"
+                  color="primary"
+                  onClick={() => handleToggleBookmark(post)}
+                  title={post.isBookmarked ? "เลิกบันทึก" : "บันทึกโพสต์"}
+                  sx={{ ml: "auto" }}
+                >
+                  {post.isBookmarked ? <BookmarkIcon /> : <BookmarkBorderIcon />}
+                </IconButton>
               </CardActions>
 
               {expandedPost === post.id && (
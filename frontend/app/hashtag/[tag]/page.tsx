@@ -0,0 +1,114 @@
+"use client";
+
+import { useState, useEffect } from "react";
+import { useRouter, useParams } from "next/navigation";
+import {
+  Container,
+  Box,
+  Paper,
+  Typography,
+  Card,
+  CardContent,
+  IconButton,
+  AppBar,
+  Toolbar,
+  CircularProgress,
+  Button,
+} from "@mui/material";
+import { ArrowBack as ArrowBackIcon } from "@mui/icons-material";
+import axios from "axios";
+
+const API_URL = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080";
+
+interface Post {
+  id: string;
+  content: string;
+  authorName: string;
+  createdAt: string;
+}
+
+export default function HashtagPage() {
+  const router = useRouter();
+  const params = useParams();
+  const tag = decodeURIComponent(params.tag as string);
+
+  const [posts, setPosts] = useState<Post[]>([]);
+  const [nextCursor, setNextCursor] = useState<string | null>(null);
+  const [loading, setLoading] = useState(true);
+
+  useEffect(() => {
+    if (tag) {
+      fetchPosts(null);
+    }
+  }, [tag]);
+
+  const fetchPosts = async (cursor: string | null) => {
+    try {
+      const token = localStorage.getItem("token");
+      if (!token) {
+        router.push("/login");
+        return;
+      }
+      const response = await axios.get(
+        `${API_URL}/api/hashtags/${encodeURIComponent(tag)}/posts`,
+        {
+          headers: { Authorization: `Bearer ${token}` },
+          params: cursor ? { cursor } : {},
+        }
+      );
+      const items = response.data?.items || [];
+      setPosts((prev) => (cursor ? [...prev, ...items] : items));
+      setNextCursor(response.data?.nextCursor || null);
+    } catch (error) {
+      console.error("Error fetching hashtag posts:", error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <>
+      <AppBar position="static">
+        <Toolbar>
+          <IconButton edge="start" color="inherit" onClick={() => router.push("/feed")} sx={{ mr: 2 }}>
+            <ArrowBackIcon />
+          </IconButton>
+          <Typography variant="h6" component="div" sx={{ flexGrow: 1 }}>
+            #{tag}
+          </Typography>
+        </Toolbar>
+      </AppBar>
+
+      <Container maxWidth="md" sx={{ mt: 4, mb: 4 }}>
+        {loading ? (
+          <Box sx={{ display: "flex", justifyContent: "center", p: 3 }}>
+            <CircularProgress />
+          </Box>
+        ) : posts.length > 0 ? (
+          <Box sx={{ display: "flex", flexDirection: "column", gap: 2 }}>
+            {posts.map((post) => (
+              <Card key={post.id} sx={{ cursor: "pointer" }} onClick={() => router.push(`/post/${post.id}`)}>
+                <CardContent>
+                  <Typography variant="subtitle2">{post.authorName}</Typography>
+                  <Typography variant="caption" color="text.secondary">
+                    {new Date(post.createdAt).toLocaleString("th-TH")}
+                  </Typography>
+                  <Typography variant="body1" sx={{ mt: 1 }}>
+                    {post.content}
+                  </Typography>
+                </CardContent>
+              </Card>
+            ))}
+            {nextCursor && <Button onClick={() => fetchPosts(nextCursor)}>โหลดเพิ่ม</Button>}
+          </Box>
+        ) : (
+          <Paper sx={{ p: 3, textAlign: "center" }}>
+            <Typography variant="body1" color="text.secondary">
+              ยังไม่มีโพสต์ที่ใช้แฮชแท็กนี้
+            </Typography>
+          </Paper>
+        )}
+      </Container>
+    </>
+  );
+}
@@ -141,7 +141,7 @@ export default function PostDetailPage() {
       const response = await axios.get(`${API_URL}/api/posts/${postId}/comments`, {
         headers: { Authorization: `Bearer ${token}` },
       });
-      setComments(response.data || []);
+      setComments(response.data?.items || []);
     } catch (error) {
       console.error("Error fetching comments:", error);
     } finally {
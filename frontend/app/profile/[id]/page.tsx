@@ -92,7 +92,7 @@ export default function UserProfilePage() {
       const response = await axios.get(`${API_URL}/api/posts/user/${userId}`, {
         headers: { Authorization: `Bearer ${token}` },
       });
-      setMyPosts(response.data || []);
+      setMyPosts(response.data?.items || []);
     } catch (error) {
       console.error("Error fetching user posts:", error);
     } finally {
@@ -135,7 +135,7 @@ export default function UserProfilePage() {
       const response = await axios.get(`${API_URL}/api/posts/${postId}/comments`, {
         headers: { Authorization: `Bearer ${token}` },
       });
-      setComments((prev) => ({ ...prev, [postId]: response.data || [] }));
+      setComments((prev) => ({ ...prev, [postId]: response.data?.items || [] }));
     } catch (error) {
       console.error("Error fetching comments:", error);
     }
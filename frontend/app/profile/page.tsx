@@ -136,7 +136,7 @@ export default function ProfilePage() {
       const response = await axios.get(`${API_URL}/api/posts/user/${userId}`, {
         headers: { Authorization: `Bearer ${token}` },
       });
-      setMyPosts(response.data || []);
+      setMyPosts(response.data?.items || []);
     } catch (error) {
       console.error("Error fetching user posts:", error);
     } finally {
@@ -178,7 +178,7 @@ export default function ProfilePage() {
       const response = await axios.get(`${API_URL}/api/posts/my`, {
         headers: { Authorization: `Bearer ${token}` },
       });
-      setMyPosts(response.data || []);
+      setMyPosts(response.data?.items || []);
     } catch (error) {
       console.error("Error fetching my posts:", error);
     } finally {
@@ -221,7 +221,7 @@ export default function ProfilePage() {
       const response = await axios.get(`${API_URL}/api/posts/${postId}/comments`, {
         headers: { Authorization: `Bearer ${token}` },
       });
-      setComments((prev) => ({ ...prev, [postId]: response.data || [] }));
+      setComments((prev) => ({ ...prev, [postId]: response.data?.items || [] }));
     } catch (error) {
       console.error("Error fetching comments:", error);
     }